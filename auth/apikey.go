@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"template_project/config"
+	"template_project/logger"
+	"template_project/model"
+	"template_project/task"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultAPIKeyHeader = "X-API-Key"
+	// touchInterval limit the writes of api_key.last_used_at for a busy key
+	touchInterval = time.Minute
+)
+
+// APIKeyAuthenticator check the keys of the api key header against the hashes stored in mysql.
+// A key is "<prefix>.<secret>", the prefix finds the row and the secret is never stored.
+type APIKeyAuthenticator struct {
+	header  string
+	touched sync.Map // key id -> time.Time of the last recorded use
+}
+
+func NewAPIKeyAuthenticator(cfg config.APIKeyConfig) *APIKeyAuthenticator {
+	header := cfg.Header
+	if header == "" {
+		header = defaultAPIKeyHeader
+	}
+	return &APIKeyAuthenticator{header: header}
+}
+
+func (a *APIKeyAuthenticator) Method() string {
+	return MethodAPIKey
+}
+
+func (a *APIKeyAuthenticator) Authenticate(req Credentials) (*Principal, error) {
+	raw := strings.TrimSpace(req.GetHeader(a.header))
+	if raw == "" {
+		return nil, ErrNoCredentials
+	}
+	i := strings.IndexByte(raw, '.')
+	if i <= 0 || i == len(raw)-1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	key, err := (&model.APIKey{}).QueryByPrefix(raw[:i])
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(HashAPIKey(raw)), []byte(key.KeyHash)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, ErrInvalidCredentials
+	}
+	a.touch(key.Id, now)
+
+	p := &Principal{
+		Method:  MethodAPIKey,
+		Subject: strconv.FormatInt(key.UserId, 10),
+		UserID:  key.UserId,
+		KeyID:   key.Id,
+	}
+	if key.Scopes != "" {
+		for _, s := range strings.Split(key.Scopes, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				p.Scopes = append(p.Scopes, s)
+			}
+		}
+	}
+	return p, nil
+}
+
+// touch record the use of the key in the background, at most once per touchInterval
+func (a *APIKeyAuthenticator) touch(id int64, now time.Time) {
+	if last, ok := a.touched.Load(id); ok && now.Sub(last.(time.Time)) < touchInterval {
+		return
+	}
+	a.touched.Store(id, now)
+	task.Go("touch_api_key", func() error {
+		return (&model.APIKey{}).Touch(id, now)
+	}, func(err error) {
+		logger.Named(logger.ModuleMySQL).Warn("touch api key %d: %v", id, err)
+	})
+}
+
+// GenerateAPIKey return a new key, the prefix to store with it and the hash of the key
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 28)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(b[:6])
+	key = prefix + "." + hex.EncodeToString(b[6:])
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey return the sha256 hex of key as stored in api_key.key_hash
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
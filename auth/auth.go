@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"template_project/config"
+	"template_project/db/redis"
+	"errors"
+	"sync"
+)
+
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request carries none of its credentials
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned when the credentials are wrong, expired or revoked
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Method  string                 `json:"method"`  // MethodJWT or MethodAPIKey
+	Subject string                 `json:"subject"` // jwt sub, or the user id of an api key
+	UserID  int64                  `json:"user_id"` // 0 when the subject is not a user id
+	KeyID   int64                  `json:"key_id"`  // id of the api key, 0 for other methods
+	Scopes  []string               `json:"scopes"`
+	Claims  map[string]interface{} `json:"-"` // jwt claims, nil for api keys
+}
+
+// HasScope report whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials is the part of a request an Authenticator reads
+type Credentials interface {
+	GetHeader(key string) string
+}
+
+// Authenticator turn the credentials of a request into a Principal
+type Authenticator interface {
+	Method() string
+	// Authenticate return ErrNoCredentials when the request has no credentials for this method,
+	// so that the next authenticator is tried
+	Authenticate(req Credentials) (*Principal, error)
+}
+
+var (
+	authenticators []Authenticator
+	lock           sync.RWMutex
+)
+
+// Init build the authenticators enabled in cfg, replacing the previous ones
+func Init(cfg config.AuthConfig) error {
+	var list []Authenticator
+	if cfg.JWT.Enable {
+		a, err := NewJWTAuthenticator(cfg.JWT)
+		if err != nil {
+			return err
+		}
+		list = append(list, a)
+	}
+	if cfg.APIKey.Enable {
+		list = append(list, NewAPIKeyAuthenticator(cfg.APIKey))
+	}
+	var w *Wallet
+	if cfg.Wallet.Enable {
+		if redis.DB == nil {
+			return errors.New("auth.wallet requires redis")
+		}
+		w = NewWallet(cfg.Wallet, redis.DB)
+	}
+
+	lock.Lock()
+	authenticators, wallet = list, w
+	lock.Unlock()
+	return nil
+}
+
+// Register add a custom authenticator after the configured ones
+func Register(a Authenticator) {
+	lock.Lock()
+	defer lock.Unlock()
+	authenticators = append(authenticators, a)
+}
+
+// Authenticators return the registered authenticators of methods, or all of them when methods is empty
+func Authenticators(methods ...string) []Authenticator {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if len(methods) == 0 {
+		return append([]Authenticator{}, authenticators...)
+	}
+	var list []Authenticator
+	for _, a := range authenticators {
+		for _, m := range methods {
+			if a.Method() == m {
+				list = append(list, a)
+				break
+			}
+		}
+	}
+	return list
+}
+
+// Authenticate try the authenticators in turn and return the first principal.
+// It fails with ErrNoCredentials when the request has credentials for none of them.
+func Authenticate(req Credentials, list []Authenticator) (*Principal, error) {
+	for _, a := range list {
+		p, err := a.Authenticate(req)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return p, err
+	}
+	return nil, ErrNoCredentials
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksCheckInterval is how often the JWKS file is checked for a rotation
+	jwksCheckInterval = 10 * time.Second
+	// jwksMissInterval limit the checks triggered by tokens with an unknown kid
+	jwksMissInterval = time.Second
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// verificationKey is a key of the set: []byte for HS256, *rsa.PublicKey or *ecdsa.PublicKey
+type verificationKey struct {
+	key interface{}
+	alg string // alg of the jwk, empty when unrestricted
+}
+
+// keySet hold the keys of a JWKS file and reload them when the file changes,
+// so rotating keys only means rewriting the file
+type keySet struct {
+	file string
+
+	mu      sync.Mutex
+	keys    map[string]verificationKey
+	modTime time.Time
+	checked time.Time
+}
+
+func newKeySet(file string) (*keySet, error) {
+	s := &keySet{file: file}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// get return the key kid, reloading the file when it is due or when kid is unknown
+func (s *keySet) get(kid string) (verificationKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[kid]
+	since := time.Since(s.checked)
+	if since > jwksCheckInterval || (!ok && since > jwksMissInterval) {
+		// keep serving the previous keys if the new file is broken
+		s.reload()
+		k, ok = s.keys[kid]
+	}
+	return k, ok
+}
+
+// only return the single key usable with alg, for tokens without kid
+func (s *keySet) only(alg string) (verificationKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		found verificationKey
+		n     int
+	)
+	for _, k := range s.keys {
+		if keyFits(k, alg) {
+			found = k
+			n++
+		}
+	}
+	return found, n == 1
+}
+
+func (s *keySet) reload() error {
+	s.checked = time.Now()
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	return s.loadLocked()
+}
+
+func (s *keySet) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checked = time.Now()
+	return s.loadLocked()
+}
+
+func (s *keySet) loadLocked() error {
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return fmt.Errorf("jwks file: %v", err)
+	}
+	data, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		return fmt.Errorf("jwks file: %v", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("jwks file %s: %v", s.file, err)
+	}
+	s.keys, s.modTime = keys, info.ModTime()
+	return nil
+}
+
+func parseJWKS(data []byte) (map[string]verificationKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d %q: %v", i, k.Kid, err)
+		}
+		keys[k.Kid] = verificationKey{key: key, alg: k.Alg}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		return decodeSegment(k.K)
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// keyFits report whether k may verify a token signed with alg, which also stops
+// a public RSA key from being used as an HMAC secret
+func keyFits(k verificationKey, alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+	switch k.key.(type) {
+	case []byte:
+		return alg == "HS256"
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case *ecdsa.PublicKey:
+		return alg == "ES256"
+	}
+	return false
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := decodeSegment(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"template_project/config"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// JWTAuthenticator verify the bearer tokens of the Authorization header
+type JWTAuthenticator struct {
+	cfg    config.JWTConfig
+	parser *jwt.Parser
+	keys   *keySet // nil without jwks_file
+}
+
+func NewJWTAuthenticator(cfg config.JWTConfig) (*JWTAuthenticator, error) {
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"HS256", "RS256", "ES256"}
+	}
+	a := &JWTAuthenticator{
+		cfg: cfg,
+		// exp, nbf and iat are checked by validate with the clock skew
+		parser: &jwt.Parser{ValidMethods: algorithms, SkipClaimsValidation: true},
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := newKeySet(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+	return a, nil
+}
+
+func (a *JWTAuthenticator) Method() string {
+	return MethodJWT
+}
+
+func (a *JWTAuthenticator) Authenticate(req Credentials) (*Principal, error) {
+	header := req.GetHeader("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := a.parser.ParseWithClaims(strings.TrimSpace(header[7:]), claims, a.key)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidCredentials
+	}
+	if err := a.validate(claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	p := &Principal{Method: MethodJWT, Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+	if id, err := strconv.ParseInt(p.Subject, 10, 64); err == nil {
+		p.UserID = id
+	}
+	p.Scopes = scopes(claims)
+	return p, nil
+}
+
+// key find the verification key of token: the jwk named by its kid, else the secret
+// for HS256, else the only key of the set matching its algorithm
+func (a *JWTAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		if a.keys == nil {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		k, ok := a.keys.get(kid)
+		if !ok || !keyFits(k, alg) {
+			return nil, fmt.Errorf("unknown kid %q for %s", kid, alg)
+		}
+		return k.key, nil
+	}
+	if alg == "HS256" && a.cfg.Secret != "" {
+		return []byte(a.cfg.Secret), nil
+	}
+	if a.keys != nil {
+		if k, ok := a.keys.only(alg); ok {
+			return k.key, nil
+		}
+	}
+	return nil, fmt.Errorf("no key for %s", alg)
+}
+
+func (a *JWTAuthenticator) validate(claims jwt.MapClaims) error {
+	now := time.Now()
+	skew := a.cfg.ClockSkew.Duration
+
+	exp, ok := numericDate(claims["exp"])
+	switch {
+	case !ok && !a.cfg.AllowMissingExp:
+		return fmt.Errorf("token has no exp")
+	case ok && now.After(exp.Add(skew)):
+		return fmt.Errorf("token is expired")
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Before(nbf.Add(-skew)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if iat, ok := numericDate(claims["iat"]); ok && now.Before(iat.Add(-skew)) {
+		return fmt.Errorf("token is issued in the future")
+	}
+	if a.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.cfg.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+	if a.cfg.Audience != "" && !hasAudience(claims["aud"], a.cfg.Audience) {
+		return fmt.Errorf("unexpected audience")
+	}
+	return nil
+}
+
+func numericDate(v interface{}) (time.Time, bool) {
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// aud is either a string or an array of strings
+func hasAudience(v interface{}, audience string) bool {
+	switch aud := v.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scopes read the space separated scope claim, or the scp array
+func scopes(claims jwt.MapClaims) []string {
+	if s, ok := claims["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+	var list []string
+	if scp, ok := claims["scp"].([]interface{}); ok {
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// Issue sign an HS256 token for subject with the secret, issuer and audience of the config,
+// extra claims are added as is
+func (a *JWTAuthenticator) Issue(subject string, ttl time.Duration, extra map[string]interface{}) (string, time.Time, error) {
+	if a.cfg.Secret == "" {
+		return "", time.Time{}, fmt.Errorf("auth: issuing tokens requires auth.jwt.secret")
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := jwt.MapClaims{}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["sub"] = subject
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+	if a.cfg.Issuer != "" {
+		claims["iss"] = a.cfg.Issuer
+	}
+	if a.cfg.Audience != "" {
+		claims["aud"] = a.cfg.Audience
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(a.cfg.Secret))
+	return token, expiresAt, err
+}
+
+// IssueToken sign a token with the configured jwt authenticator
+func IssueToken(subject string, ttl time.Duration, extra map[string]interface{}) (string, time.Time, error) {
+	for _, a := range Authenticators(MethodJWT) {
+		if j, ok := a.(*JWTAuthenticator); ok {
+			return j.Issue(subject, ttl, extra)
+		}
+	}
+	return "", time.Time{}, fmt.Errorf("auth: issuing tokens requires auth.jwt.enable")
+}
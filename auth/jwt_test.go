@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"template_project/config"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type testRequest map[string]string
+
+func (r testRequest) GetHeader(key string) string {
+	return r[key]
+}
+
+func sign(t *testing.T, claims jwt.MapClaims) testRequest {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testRequest{"Authorization": "Bearer " + token}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		claims       jwt.MapClaims
+		allowMissing bool
+		err          error
+	}{
+		{"valid", jwt.MapClaims{"sub": "1", "exp": now.Add(time.Minute).Unix()}, false, nil},
+		{"expired", jwt.MapClaims{"sub": "1", "exp": now.Add(-time.Minute).Unix()}, false, ErrInvalidCredentials},
+		{"within the skew", jwt.MapClaims{"sub": "1", "exp": now.Add(-5 * time.Second).Unix()}, false, nil},
+		{"missing exp", jwt.MapClaims{"sub": "1"}, false, ErrInvalidCredentials},
+		{"missing exp allowed", jwt.MapClaims{"sub": "1"}, true, nil},
+		{"expired with missing allowed", jwt.MapClaims{"sub": "1", "exp": now.Add(-time.Minute).Unix()}, true, ErrInvalidCredentials},
+		{"exp is not a number", jwt.MapClaims{"sub": "1", "exp": "tomorrow"}, false, ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		a, err := NewJWTAuthenticator(config.JWTConfig{
+			Enable:          true,
+			Algorithms:      []string{"HS256"},
+			Secret:          "test secret",
+			ClockSkew:       config.Duration{Duration: 30 * time.Second},
+			AllowMissingExp: tt.allowMissing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := a.Authenticate(sign(t, tt.claims))
+		if err != tt.err {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && p.UserID != 1 {
+			t.Errorf("%s: user id = %d, want 1", tt.name, p.UserID)
+		}
+	}
+}
+
+func TestJWTIssue(t *testing.T) {
+	a, err := NewJWTAuthenticator(config.JWTConfig{Enable: true, Secret: "test secret", Issuer: "api", Audience: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, expiresAt, err := a.Issue("42", time.Minute, map[string]interface{}{"scope": "users:read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expiresAt); d <= 0 || d > time.Minute {
+		t.Errorf("expires in %v, want about a minute", d)
+	}
+	p, err := a.Authenticate(testRequest{"Authorization": "Bearer " + token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.UserID != 42 || !p.HasScope("users:read") {
+		t.Errorf("principal = %+v", p)
+	}
+
+	other, _ := NewJWTAuthenticator(config.JWTConfig{Enable: true, Secret: "test secret", Audience: "mobile"})
+	if _, err := other.Authenticate(testRequest{"Authorization": "Bearer " + token}); err != ErrInvalidCredentials {
+		t.Errorf("other audience error = %v, want ErrInvalidCredentials", err)
+	}
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"template_project/config"
+	"template_project/db/redis"
+	"template_project/utils"
+	"template_project/utils/eth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+const (
+	// SignPersonal is an EIP-191 personal_sign of Challenge.Message
+	SignPersonal = "personal_sign"
+	// SignTypedData is an EIP-712 eth_signTypedData_v4 of Challenge.TypedData
+	SignTypedData = "eip712"
+
+	walletChallengePrefix = "wallet:challenge:"
+	walletNonceLength     = 24
+	defaultChallengeTTL   = 5 * time.Minute
+	defaultTokenTTL       = 24 * time.Hour
+)
+
+var (
+	ErrInvalidAddress = errors.New("invalid address")
+	// ErrChallengeNotFound is returned for unknown, expired or foreign nonces
+	ErrChallengeNotFound = errors.New("challenge not found or expired")
+	ErrNonceUsed         = errors.New("nonce already used")
+	ErrSignature         = errors.New("signature does not match the address")
+	ErrSignMethod        = errors.New("signature method should be personal_sign or eip712")
+)
+
+// Challenge is what a wallet signs to log in, either Message or TypedData
+type Challenge struct {
+	Address   string         `json:"address"`
+	Nonce     string         `json:"nonce"`
+	IssuedAt  time.Time      `json:"issued_at"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	Message   string         `json:"message"`
+	TypedData *eth.TypedData `json:"typed_data"`
+}
+
+// challengeRecord is the part of a challenge kept in redis, the rest is derived from the config
+type challengeRecord struct {
+	Address   string    `json:"address"`
+	Nonce     string    `json:"nonce"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Session is the result of a wallet login
+type Session struct {
+	Address   string    `json:"address"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Wallet log users in with a signature of their Ethereum account.
+// Challenges are single use: the nonce is deleted from the store by the login that consumes it.
+type Wallet struct {
+	cfg   config.WalletConfig
+	store redis.ServiceI
+}
+
+var wallet *Wallet
+
+func NewWallet(cfg config.WalletConfig, store redis.ServiceI) *Wallet {
+	if cfg.ChallengeTTL.Duration <= 0 {
+		cfg.ChallengeTTL.Duration = defaultChallengeTTL
+	}
+	if cfg.TokenTTL.Duration <= 0 {
+		cfg.TokenTTL.Duration = defaultTokenTTL
+	}
+	return &Wallet{cfg: cfg, store: store}
+}
+
+// GetWallet return the wallet login set up by Init, nil when auth.wallet is disabled
+func GetWallet() *Wallet {
+	lock.RLock()
+	defer lock.RUnlock()
+	return wallet
+}
+
+// Challenge create a challenge for address, valid for the challenge ttl
+func (w *Wallet) Challenge(address string) (*Challenge, error) {
+	addr, err := eth.HexToAddress(address)
+	if err != nil {
+		return nil, ErrInvalidAddress
+	}
+	nonce, err := utils.GenCharacter(walletNonceLength)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	rec := challengeRecord{
+		Address:   addr.Hex(),
+		Nonce:     nonce,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(w.cfg.ChallengeTTL.Duration),
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.store.Set(walletChallengePrefix+nonce, data, int64(w.cfg.ChallengeTTL.Seconds())); err != nil {
+		return nil, err
+	}
+	return w.challenge(rec), nil
+}
+
+// Login verify the signature of the challenge nonce by address, consume the nonce and issue a jwt
+func (w *Wallet) Login(address, nonce, method, signature string) (*Session, error) {
+	addr, err := eth.HexToAddress(address)
+	if err != nil {
+		return nil, ErrInvalidAddress
+	}
+	if len(nonce) != walletNonceLength {
+		return nil, ErrChallengeNotFound
+	}
+	key := walletChallengePrefix + nonce
+	data, err := w.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrChallengeNotFound
+	}
+	var rec challengeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if rec.Address != addr.Hex() || time.Now().After(rec.ExpiresAt) {
+		return nil, ErrChallengeNotFound
+	}
+
+	sig, err := eth.DecodeHex(signature)
+	if err != nil {
+		return nil, ErrSignature
+	}
+	if method == "" {
+		method = SignPersonal
+	}
+	c := w.challenge(rec)
+	var signer eth.Address
+	switch method {
+	case SignPersonal:
+		signer, err = eth.RecoverPersonal([]byte(c.Message), sig)
+	case SignTypedData:
+		signer, err = eth.RecoverTypedData(c.TypedData, sig)
+	default:
+		return nil, ErrSignMethod
+	}
+	if err != nil || signer != addr {
+		return nil, ErrSignature
+	}
+
+	// only one login may consume the nonce
+	ok, err := w.store.CompareAndDelete(key, data)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNonceUsed
+	}
+
+	token, expiresAt, err := IssueToken(addr.Hex(), w.cfg.TokenTTL.Duration, map[string]interface{}{
+		"amr": method,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Address: addr.Hex(), Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// challenge build the message and typed data of rec, in the EIP-4361 sign in with Ethereum format
+func (w *Wallet) challenge(rec challengeRecord) *Challenge {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s wants you to sign in with your Ethereum account:\n%s\n\n", w.cfg.Domain, rec.Address)
+	if w.cfg.Statement != "" {
+		fmt.Fprintf(&b, "%s\n\n", w.cfg.Statement)
+	}
+	if w.cfg.URI != "" {
+		fmt.Fprintf(&b, "URI: %s\n", w.cfg.URI)
+	}
+	fmt.Fprintf(&b, "Version: 1\nChain ID: %d\nNonce: %s\nIssued At: %s\nExpiration Time: %s",
+		w.cfg.ChainID, rec.Nonce, rec.IssuedAt.Format(time.RFC3339), rec.ExpiresAt.Format(time.RFC3339))
+
+	return &Challenge{
+		Address:   rec.Address,
+		Nonce:     rec.Nonce,
+		IssuedAt:  rec.IssuedAt,
+		ExpiresAt: rec.ExpiresAt,
+		Message:   b.String(),
+		TypedData: &eth.TypedData{
+			Types: map[string][]eth.TypedField{
+				"EIP712Domain": {
+					{Name: "name", Type: "string"},
+					{Name: "version", Type: "string"},
+					{Name: "chainId", Type: "uint256"},
+				},
+				"Login": {
+					{Name: "address", Type: "address"},
+					{Name: "statement", Type: "string"},
+					{Name: "nonce", Type: "string"},
+					{Name: "issuedAt", Type: "string"},
+					{Name: "expiresAt", Type: "string"},
+				},
+			},
+			PrimaryType: "Login",
+			Domain: eth.TypedDomain{
+				Name:    w.cfg.Domain,
+				Version: "1",
+				ChainId: math.NewHexOrDecimal256(w.cfg.ChainID),
+			},
+			Message: map[string]interface{}{
+				"address":   rec.Address,
+				"statement": w.cfg.Statement,
+				"nonce":     rec.Nonce,
+				"issuedAt":  rec.IssuedAt.Format(time.RFC3339),
+				"expiresAt": rec.ExpiresAt.Format(time.RFC3339),
+			},
+		},
+	}
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"template_project/db/redis"
+	"template_project/logger"
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// log is the logger of the cache, which lives in redis
+var log = logger.Named(logger.ModuleRedis)
+
+var (
+	// ErrNotFound is returned by a loader when the value does not exist,
+	// GetOrLoad remembers it for Options.NegativeTTL and returns it to the callers
+	ErrNotFound = errors.New("cache: not found")
+	// ErrMiss is returned by Get when the key is not cached
+	ErrMiss = errors.New("cache: miss")
+)
+
+// the first byte of a stored entry tells a value from a remembered miss
+const (
+	flagValue    byte = 'v'
+	flagNotFound byte = 'n'
+)
+
+const (
+	defaultJitter   = 0.1
+	defaultLocalTTL = 30 * time.Second
+)
+
+type Options struct {
+	Prefix string // prepended to every key, e.g. "user:"
+	Codec  Codec  // default JSON
+	// Jitter spread the ttl of the entries by +-Jitter*ttl so that keys cached together
+	// do not expire together, default 0.1, negative disables
+	Jitter float64
+	// NegativeTTL is how long a loader ErrNotFound is remembered, 0 disables negative caching
+	NegativeTTL time.Duration
+	// LocalSize is the number of entries of the in-process LRU tier in front of redis, 0 disables it
+	LocalSize int
+	// LocalTTL cap the lifetime of local entries, default 30s, as other pods can not evict them
+	// unless InvalidationChannel is set and Listen is running
+	LocalTTL time.Duration
+	// InvalidationChannel is the redis channel Delete publishes the evicted keys on
+	InvalidationChannel string
+	// OnError receive the redis errors GetOrLoad hides from its callers, default a warning log
+	OnError func(key string, err error)
+}
+
+// Cache is a cache-aside layer over a redis ServiceI storing encoded values
+type Cache struct {
+	store redis.ServiceI
+	opts  Options
+	local *LRU
+	group singleflight.Group
+}
+
+func New(store redis.ServiceI, opts Options) *Cache {
+	if opts.Codec == nil {
+		opts.Codec = JSON
+	}
+	if opts.Jitter == 0 {
+		opts.Jitter = defaultJitter
+	}
+	if opts.LocalTTL <= 0 {
+		opts.LocalTTL = defaultLocalTTL
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(key string, err error) {
+			log.Warn("cache %s: %v", key, err)
+		}
+	}
+	c := &Cache{store: store, opts: opts}
+	if opts.LocalSize > 0 {
+		c.local = NewLRU(opts.LocalSize)
+	}
+	return c
+}
+
+// Get decode the cached value of key into dest.
+// It returns ErrMiss when key is not cached and ErrNotFound for a remembered miss.
+func (c *Cache) Get(key string, dest interface{}) error {
+	entry, err := c.get(c.opts.Prefix + key)
+	if err != nil {
+		return err
+	}
+	return c.decode(entry, dest)
+}
+
+// Set cache value for ttl, ttl <= 0 keeps it until deleted
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration) error {
+	entry, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	return c.set(c.opts.Prefix+key, entry, ttl)
+}
+
+// Delete evict keys from redis and from the local tier, then tell the other pods
+func (c *Cache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, 0, len(keys))
+	for _, key := range keys {
+		full = append(full, c.opts.Prefix+key)
+		if c.local != nil {
+			c.local.Delete(c.opts.Prefix + key)
+		}
+	}
+	if err := c.store.Dels(full); err != nil {
+		return err
+	}
+	if c.opts.InvalidationChannel != "" {
+		for _, key := range full {
+			if _, err := c.store.Publish(c.opts.InvalidationChannel, []byte(key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Listen evict the local entries deleted by other pods until ctx is done
+func (c *Cache) Listen(ctx context.Context) error {
+	if c.local == nil || c.opts.InvalidationChannel == "" {
+		return nil
+	}
+	messages, err := c.store.Subscribe(ctx, c.opts.InvalidationChannel)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for msg := range messages {
+			c.local.Delete(string(msg.Data))
+		}
+	}()
+	return nil
+}
+
+// GetOrLoad decode the cached value of key into dest, calling loader on a miss.
+// Concurrent misses of a key in this process share one loader call.
+// A loader returning ErrNotFound is cached as a miss for NegativeTTL and ErrNotFound is returned.
+// Redis failures are reported to OnError and do not fail the call as long as loader succeeds.
+func (c *Cache) GetOrLoad(key string, ttl time.Duration, dest interface{}, loader func() (interface{}, error)) error {
+	full := c.opts.Prefix + key
+	entry, err := c.get(full)
+	if err == nil {
+		return c.decode(entry, dest)
+	}
+	if err != ErrMiss {
+		c.opts.OnError(full, err)
+	}
+
+	v, err, _ := c.group.Do(full, func() (interface{}, error) {
+		value, err := loader()
+		if err == ErrNotFound {
+			entry := []byte{flagNotFound}
+			if c.opts.NegativeTTL > 0 {
+				if err := c.set(full, entry, c.opts.NegativeTTL); err != nil {
+					c.opts.OnError(full, err)
+				}
+			}
+			return entry, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := c.encode(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.set(full, entry, ttl); err != nil {
+			c.opts.OnError(full, err)
+		}
+		return entry, nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.decode(v.([]byte), dest)
+}
+
+func (c *Cache) get(key string) ([]byte, error) {
+	if c.local != nil {
+		if entry, ok := c.local.Get(key); ok {
+			return entry, nil
+		}
+	}
+	entry, err := c.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry) == 0 {
+		return nil, ErrMiss
+	}
+	if c.local != nil {
+		c.local.Set(key, entry, c.opts.LocalTTL)
+	}
+	return entry, nil
+}
+
+func (c *Cache) set(key string, entry []byte, ttl time.Duration) error {
+	ttl = c.jitter(ttl)
+	if c.local != nil {
+		local := c.opts.LocalTTL
+		if ttl > 0 && ttl < local {
+			local = ttl
+		}
+		c.local.Set(key, entry, local)
+	}
+
+	var seconds int64
+	if ttl > 0 {
+		// redis expires in whole seconds, round up so that a short ttl does not become 0
+		seconds = int64((ttl + time.Second - 1) / time.Second)
+	}
+	return c.store.Set(key, entry, seconds)
+}
+
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if ttl <= 0 || c.opts.Jitter <= 0 {
+		return ttl
+	}
+	spread := float64(ttl) * c.opts.Jitter
+	return ttl + time.Duration(spread*(2*rand.Float64()-1))
+}
+
+func (c *Cache) encode(value interface{}) ([]byte, error) {
+	data, err := c.opts.Codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{flagValue}, data...), nil
+}
+
+func (c *Cache) decode(entry []byte, dest interface{}) error {
+	switch entry[0] {
+	case flagNotFound:
+		return ErrNotFound
+	case flagValue:
+		return c.opts.Codec.Unmarshal(entry[1:], dest)
+	}
+	return errors.New("cache: corrupted entry")
+}
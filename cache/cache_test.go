@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"template_project/db/redis"
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingStore remember the ttl, in seconds, of the last Set of every key
+type recordingStore struct {
+	*redis.MemoryService
+	mu   sync.Mutex
+	ttls map[string]int64
+}
+
+func newStore() *recordingStore {
+	return &recordingStore{MemoryService: redis.NewMemoryService(), ttls: make(map[string]int64)}
+}
+
+func (s *recordingStore) Set(key string, value []byte, ttl int64) error {
+	s.mu.Lock()
+	s.ttls[key] = ttl
+	s.mu.Unlock()
+	return s.MemoryService.Set(key, value, ttl)
+}
+
+func (s *recordingStore) ttl(key string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ttls[key]
+}
+
+type user struct {
+	ID   int64
+	Name string
+	Tags []string
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	c := New(newStore(), Options{Prefix: "user:"})
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	loader := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return user{ID: 1, Name: "ann"}, nil
+	}
+
+	var wg sync.WaitGroup
+	got := make([]user, 10)
+	errs := make([]error, 10)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.GetOrLoad("1", time.Minute, &got[i], loader)
+		}(i)
+	}
+	<-started
+	// let the other callers reach the load in flight before it returns
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+	for i := range got {
+		if errs[i] != nil || got[i].Name != "ann" {
+			t.Errorf("caller %d: %+v, %v", i, got[i], errs[i])
+		}
+	}
+
+	var cached user
+	if err := c.Get("1", &cached); err != nil || cached.ID != 1 {
+		t.Errorf("Get after load = %+v, %v", cached, err)
+	}
+}
+
+func TestGetOrLoadNegative(t *testing.T) {
+	store := newStore()
+	c := New(store, Options{Prefix: "user:", NegativeTTL: 5 * time.Second, Jitter: -1})
+	var calls int
+	missing := func() (interface{}, error) {
+		calls++
+		return nil, ErrNotFound
+	}
+
+	var u user
+	for i := 0; i < 2; i++ {
+		if err := c.GetOrLoad("404", time.Minute, &u, missing); err != ErrNotFound {
+			t.Fatalf("GetOrLoad %d error = %v, want ErrNotFound", i, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times, want the miss remembered", calls)
+	}
+	entry, _ := store.Get("user:404")
+	if !reflect.DeepEqual(entry, []byte{flagNotFound}) {
+		t.Errorf("stored entry = %q, want the not found flag", entry)
+	}
+	if ttl := store.ttl("user:404"); ttl != 5 {
+		t.Errorf("negative ttl = %ds, want 5s", ttl)
+	}
+	if err := c.Get("404", &u); err != ErrNotFound {
+		t.Errorf("Get error = %v, want ErrNotFound", err)
+	}
+
+	// without NegativeTTL a miss is not remembered
+	c = New(newStore(), Options{})
+	calls = 0
+	c.GetOrLoad("404", time.Minute, &u, missing)
+	c.GetOrLoad("404", time.Minute, &u, missing)
+	if calls != 2 {
+		t.Errorf("loader called %d times without negative caching, want 2", calls)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	c := New(newStore(), Options{})
+	ttl := 100 * time.Second
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 1000; i++ {
+		got := c.jitter(ttl)
+		if got < 90*time.Second || got > 110*time.Second {
+			t.Fatalf("jitter(%v) = %v, out of the default 10%%", ttl, got)
+		}
+		seen[got] = true
+	}
+	if len(seen) < 2 {
+		t.Error("jitter did not spread the ttl")
+	}
+	if got := c.jitter(0); got != 0 {
+		t.Errorf("jitter(0) = %v, want no expiry kept", got)
+	}
+
+	store := newStore()
+	c = New(store, Options{Jitter: -1})
+	if got := c.jitter(ttl); got != ttl {
+		t.Errorf("disabled jitter(%v) = %v", ttl, got)
+	}
+	// redis expires in whole seconds, a short ttl is rounded up
+	c.Set("k", 1, 1500*time.Millisecond)
+	if got := store.ttl("k"); got != 2 {
+		t.Errorf("ttl of 1.5s stored as %ds, want 2s", got)
+	}
+}
+
+func TestInvalidation(t *testing.T) {
+	store := newStore()
+	opts := Options{Prefix: "user:", LocalSize: 10, LocalTTL: time.Minute, InvalidationChannel: "cache:invalidate"}
+	a, b := New(store, opts), New(store, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := b.Listen(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var u user
+	a.Set("1", user{ID: 1}, time.Minute)
+	if err := b.Get("1", &u); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.local.Get("user:1"); !ok {
+		t.Fatal("Get did not fill the local tier")
+	}
+
+	if err := a.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := b.local.Get("user:1"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the local entry of the other cache was not dropped")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := b.Get("1", &u); err != ErrMiss {
+		t.Errorf("Get after delete error = %v, want ErrMiss", err)
+	}
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCodecs(t *testing.T) {
+	in := user{ID: 7, Name: "ann", Tags: []string{"admin", "ops"}}
+	for name, codec := range map[string]Codec{"json": JSON, "gob": Gob, "msgpack": Msgpack} {
+		data, err := codec.Marshal(in)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var out user
+		if err := codec.Unmarshal(data, &out); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("%s round trip = %+v, want %+v", name, out, in)
+		}
+
+		c := New(newStore(), Options{Codec: codec})
+		out = user{}
+		if err := c.Set("u", in, time.Minute); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := c.Get("u", &out); err != nil || !reflect.DeepEqual(in, out) {
+			t.Errorf("%s cache round trip = %+v, %v", name, out, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a fixed size in-process cache of encoded values with a per-entry expiry
+type LRU struct {
+	size int
+	now  func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key      string
+	data     []byte
+	expireAt time.Time
+}
+
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  size,
+		now:   time.Now,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get return the data of a live entry and mark it as recently used
+func (l *LRU) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !l.now().Before(e.expireAt) {
+		l.remove(el)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return e.data, true
+}
+
+// Set store data for ttl, evicting the least recently used entry when full
+func (l *LRU) Set(key string, data []byte, ttl time.Duration) {
+	if ttl <= 0 || l.size <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expireAt := l.now().Add(ttl)
+	if el, ok := l.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.data, e.expireAt = data, expireAt
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, data: data, expireAt: expireAt})
+	for l.ll.Len() > l.size {
+		l.remove(l.ll.Back())
+	}
+}
+
+func (l *LRU) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		l.remove(el)
+	}
+}
+
+// Purge drop every entry
+func (l *LRU) Purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+}
+
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *LRU) remove(el *list.Element) {
+	l.ll.Remove(el)
+	delete(l.items, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	l := NewLRU(2)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	l.Set("a", []byte("1"), time.Minute)
+	l.Set("b", []byte("2"), time.Minute)
+	l.Get("a")
+	l.Set("c", []byte("3"), time.Minute)
+	if _, ok := l.Get("b"); ok {
+		t.Error("the least recently used entry was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := l.Get(key); !ok {
+			t.Errorf("%s evicted", key)
+		}
+	}
+
+	l.Set("a", []byte("updated"), time.Second)
+	if data, _ := l.Get("a"); string(data) != "updated" {
+		t.Errorf("a = %q after an update", data)
+	}
+	now = now.Add(time.Second)
+	if _, ok := l.Get("a"); ok {
+		t.Error("an expired entry is returned")
+	}
+	if l.Len() != 1 {
+		t.Errorf("len = %d, want the expired entry removed", l.Len())
+	}
+
+	l.Set("d", []byte("4"), 0)
+	if _, ok := l.Get("d"); ok {
+		t.Error("an entry without ttl is kept")
+	}
+	l.Delete("c")
+	if l.Len() != 0 {
+		t.Errorf("len = %d after delete", l.Len())
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"template_project/auth"
+	"template_project/config"
+	"template_project/db/mysql"
+	"template_project/model"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiKeyFile    *string
+	apiKeySets    *[]string
+	apiKeyUser    *int64
+	apiKeyName    *string
+	apiKeyScopes  *string
+	apiKeyExpires *string
+)
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "api(.exe) apikey",
+	Long:  "manage the api keys of the users",
+}
+
+var apiKeyCreateCmd = &cobra.Command{
+	Use:          "create",
+	Short:        "api(.exe) apikey create",
+	Long:         "api(.exe) apikey create -c ./build/app.json --user 1 --name ci --scopes read,write --expires 720h",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if *apiKeyUser <= 0 {
+			return errors.New("--user should be a user id")
+		}
+		if *apiKeyName == "" {
+			return errors.New("--name is required")
+		}
+		var expiresAt *time.Time
+		if *apiKeyExpires != "" {
+			d, err := config.ParseDuration(*apiKeyExpires)
+			if err != nil || d.Duration <= 0 {
+				return fmt.Errorf("invalid --expires %q", *apiKeyExpires)
+			}
+			t := time.Now().Add(d.Duration)
+			expiresAt = &t
+		}
+		if err := initAPIKeyDB(); err != nil {
+			return err
+		}
+
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		row := &model.APIKey{
+			UserId:    *apiKeyUser,
+			Name:      *apiKeyName,
+			Prefix:    prefix,
+			KeyHash:   hash,
+			Scopes:    *apiKeyScopes,
+			ExpiresAt: expiresAt,
+		}
+		if err := row.Create(); err != nil {
+			return err
+		}
+		fmt.Printf("created api key %d for user %d\n", row.Id, row.UserId)
+		fmt.Println("key (shown once):", key)
+		return nil
+	},
+}
+
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:          "revoke <id>",
+	Short:        "api(.exe) apikey revoke id",
+	Long:         "api(.exe) apikey revoke 3 -c ./build/app.json",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid api key id %q", args[0])
+		}
+		if err := initAPIKeyDB(); err != nil {
+			return err
+		}
+		ok, err := (&model.APIKey{}).Revoke(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no active api key %d", id)
+		}
+		fmt.Printf("revoked api key %d\n", id)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(apiKeyCmd)
+	apiKeyFile = apiKeyCmd.PersistentFlags().StringP("config", "c", "", "start config file (required)")
+	apiKeySets = apiKeyCmd.PersistentFlags().StringArray("set", nil, "override a config field, e.g. --set mysql.host=127.0.0.1 (repeatable)")
+
+	apiKeyCmd.AddCommand(apiKeyCreateCmd, apiKeyRevokeCmd)
+	apiKeyUser = apiKeyCreateCmd.Flags().Int64("user", 0, "id of the user owning the key (required)")
+	apiKeyName = apiKeyCreateCmd.Flags().String("name", "", "name of the key (required)")
+	apiKeyScopes = apiKeyCreateCmd.Flags().String("scopes", "", "comma separated scopes")
+	apiKeyExpires = apiKeyCreateCmd.Flags().String("expires", "", "lifetime of the key, e.g. 720h, never expires when empty")
+}
+
+// initAPIKeyDB load the config and connect mysql
+func initAPIKeyDB() error {
+	if *apiKeyFile == "" {
+		return errors.New(`required flag(s) "config" not set`)
+	}
+	cfg, err := config.Init(apiKeyFile, *apiKeySets...)
+	if err != nil {
+		return err
+	}
+	if !cfg.MySQL.Enable {
+		return errors.New("apikey requires mysql.enable in config")
+	}
+	mysql.Init()
+	return nil
+}
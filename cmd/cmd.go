@@ -11,11 +11,14 @@ var rootCmd = &cobra.Command{
 	Use:   "api",
 	Short: "api command for starting the service",
 	Long:  "",
+	// errors are printed once by Execute
+	SilenceErrors: true,
 }
 
+// Execute run the root command and exit non-zero when it fails
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err.Error())
-		os.Exit(0)
+		os.Exit(1)
 	}
 }
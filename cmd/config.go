@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"template_project/config"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateFile *string
+	validateSets *[]string
+	printFile    *string
+	printSets    *[]string
+	resolved     *bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "api(.exe) config",
+	Long:  "inspect the service config file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:          "validate",
+	Short:        "api(.exe) config validate",
+	Long:         "api(.exe) config validate -c ./build/app.json",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := config.Load(*validateFile, *validateSets...); err != nil {
+			return err
+		}
+		fmt.Println("config ok")
+		return nil
+	},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:          "print",
+	Short:        "api(.exe) config print",
+	Long:         "api(.exe) config print -c ./build/app.json --resolved --set mysql.host=127.0.0.1",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var (
+			cfg config.Configuration
+			err error
+		)
+		if *resolved {
+			cfg, err = config.Resolve(*printFile, *printSets...)
+		} else {
+			cfg, err = config.LoadFile(*printFile)
+		}
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(cfg.Masked(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	validateFile = configValidateCmd.Flags().StringP("config", "c", "", "config file to validate (required)")
+	validateSets = addSetFlag(configValidateCmd)
+	err := configValidateCmd.MarkFlagRequired("config")
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	configCmd.AddCommand(configPrintCmd)
+	printFile = configPrintCmd.Flags().StringP("config", "c", "", "config file to print (required)")
+	printSets = addSetFlag(configPrintCmd)
+	resolved = configPrintCmd.Flags().Bool("resolved", false, "apply APP_ env variables and --set overrides")
+	err = configPrintCmd.MarkFlagRequired("config")
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// addSetFlag register the repeatable --set key=value config override flag
+func addSetFlag(cmd *cobra.Command) *[]string {
+	return cmd.Flags().StringArray("set", nil, "override a config field, e.g. --set mysql.host=127.0.0.1 (repeatable)")
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"template_project/config"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	healthFile    *string
+	healthSets    *[]string
+	healthLive    *bool
+	healthURL     *string
+	healthTimeout *time.Duration
+)
+
+var healthcheckCmd = &cobra.Command{
+	Use:   "healthcheck",
+	Short: "api(.exe) healthcheck",
+	Long: "api(.exe) healthcheck -c ./build/app.json, query /readyz of the running service (/healthz with --live) " +
+		"and exit non-zero when it is not ready, for the docker HEALTHCHECK",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := *healthURL
+		if target == "" {
+			if *healthFile == "" {
+				return errors.New(`required flag(s) "config" or "url" not set`)
+			}
+			cfg, err := config.Load(*healthFile, *healthSets...)
+			if err != nil {
+				return err
+			}
+			path := "/readyz"
+			if *healthLive {
+				path = "/healthz"
+			}
+			target = "http://" + localAddr(cfg.Server.ListenAddr) + path
+		}
+
+		client := &http.Client{Timeout: *healthTimeout}
+		resp, err := client.Get(target)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		fmt.Printf("%s", body)
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: status %d", target, resp.StatusCode)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(healthcheckCmd)
+	healthFile = healthcheckCmd.Flags().StringP("config", "c", "", "start config file of the service")
+	healthSets = addSetFlag(healthcheckCmd)
+	healthLive = healthcheckCmd.Flags().Bool("live", false, "check the liveness instead of the readiness")
+	healthURL = healthcheckCmd.Flags().String("url", "", "probe url, e.g. http://127.0.0.1:8083/readyz, instead of the config listen_addr")
+	healthTimeout = healthcheckCmd.Flags().Duration("timeout", 5*time.Second, "request timeout")
+}
+
+// localAddr turn a listen address into one the healthcheck can dial from the same host
+func localAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
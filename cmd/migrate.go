@@ -3,36 +3,183 @@ package cmd
 import (
 	"template_project/config"
 	"template_project/db/mysql"
+	"template_project/migration"
 	"template_project/model"
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/spf13/cobra"
 )
 
-var cfgFile *string
+var (
+	cfgFile     *string
+	migrateSets *[]string
+	migrateDir  *string
+)
 
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "api(.exe) migrate",
-	Long:  "api(.exe) migrate -c ./build/app.json",
-	Run: func(cmd *cobra.Command, args []string) {
-		config.Init(cfgFile)
-		mysql.Init()
+	Long:  "api(.exe) migrate -c ./build/app.json, same as migrate up",
+	// runtime failures are not usage errors
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return migrateUp()
+	},
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:          "up",
+	Short:        "api(.exe) migrate up",
+	Long:         "api(.exe) migrate up -c ./build/app.json, apply every pending migration",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return migrateUp()
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:          "down [N]",
+	Short:        "api(.exe) migrate down N",
+	Long:         "api(.exe) migrate down 1 -c ./build/app.json, roll back the N latest migrations",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 1
+		if len(args) == 1 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				return fmt.Errorf("invalid migration count %q", args[0])
+			}
+		}
+
+		migrator, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		done, err := migrator.Down(context.Background(), n)
+		for _, m := range done {
+			fmt.Printf("rolled back %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "api(.exe) migrate status",
+	Long:         "api(.exe) migrate status -c ./build/app.json",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator()
+		if err != nil {
+			return err
+		}
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:          "create <name>",
+	Short:        "api(.exe) migrate create name",
+	Long:         "api(.exe) migrate create add_user_email, write a new migration skeleton",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := migration.Create(*migrateDir, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", path)
+		return nil
+	},
+}
+
+var migrateAutoCmd = &cobra.Command{
+	Use:          "auto",
+	Short:        "api(.exe) migrate auto",
+	Long:         "api(.exe) migrate auto -c ./build/app.json, gorm auto migrate, only allowed in debug run mode",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := initMigrateDB()
+		if err != nil {
+			return err
+		}
+		if cfg.Server.RunMode != "debug" {
+			return errors.New("migrate auto is only allowed when server.run_mode is debug")
+		}
 		migrate()
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(migrateCmd)
-	cfgFile = migrateCmd.Flags().StringP("config", "c", "", "start config file (required)")
-	err := migrateCmd.MarkFlagRequired("config")
+	cfgFile = migrateCmd.PersistentFlags().StringP("config", "c", "", "start config file (required except for create)")
+	migrateSets = migrateCmd.PersistentFlags().StringArray("set", nil, "override a config field, e.g. --set mysql.host=127.0.0.1 (repeatable)")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateCreateCmd, migrateAutoCmd)
+	migrateDir = migrateCreateCmd.Flags().String("dir", "./migration", "directory of the migration package")
+}
+
+// initMigrateDB load the config and connect mysql
+func initMigrateDB() (config.Configuration, error) {
+	if *cfgFile == "" {
+		return config.Configuration{}, errors.New(`required flag(s) "config" not set`)
+	}
+	cfg, err := config.Init(cfgFile, *migrateSets...)
 	if err != nil {
-		fmt.Println(err)
+		return cfg, err
+	}
+	if !cfg.MySQL.Enable {
+		return cfg, errors.New("migrate requires mysql.enable in config")
+	}
+	mysql.Init()
+	return cfg, nil
+}
+
+func newMigrator() (*migration.Migrator, error) {
+	if _, err := initMigrateDB(); err != nil {
+		return nil, err
+	}
+	return migration.New(mysql.DB.DB), nil
+}
+
+func migrateUp() error {
+	migrator, err := newMigrator()
+	if err != nil {
+		return err
+	}
+	done, err := migrator.Up(context.Background())
+	for _, m := range done {
+		fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Println("no pending migration")
 	}
+	return err
 }
 
-func migrate()  {
+// migrate keep the schema up to date with gorm auto migrate, for development only
+func migrate() {
 	var tables []interface{}
-	tables = append(tables, &model.User{},)
+	tables = append(tables, &model.User{}, &model.APIKey{},
+		&model.Role{}, &model.Permission{}, &model.RolePermission{}, &model.UserRole{})
 	mysql.DB.RegistTables(tables)
 }
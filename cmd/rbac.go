@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"template_project/config"
+	"template_project/db/mysql"
+	"template_project/db/redis"
+	"template_project/model"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rbacFile *string
+	rbacSets *[]string
+	rbacUser *int64
+
+	rbacAllowStaleCache *bool
+)
+
+var rbacCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "api(.exe) rbac",
+	Long:  "manage the roles of the users",
+}
+
+var rbacRolesCmd = &cobra.Command{
+	Use:          "roles",
+	Short:        "api(.exe) rbac roles",
+	Long:         "api(.exe) rbac roles -c ./build/app.json [--user 1], list the roles, or the roles of a user",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initRBACDB(); err != nil {
+			return err
+		}
+		var (
+			roles []model.Role
+			err   error
+		)
+		if *rbacUser > 0 {
+			roles, err = (&model.Role{}).QueryByUserId(*rbacUser)
+		} else {
+			roles, err = (&model.Role{}).QueryAll()
+		}
+		if err != nil {
+			return err
+		}
+		for _, r := range roles {
+			fmt.Printf("%d  %-20s %s\n", r.Id, r.Name, r.Description)
+		}
+		if *rbacUser > 0 {
+			permissions, err := model.QueryPermissionsByUserId(*rbacUser)
+			if err != nil {
+				return err
+			}
+			fmt.Println("permissions:", strings.Join(permissions, ", "))
+		}
+		return nil
+	},
+}
+
+var rbacGrantCmd = &cobra.Command{
+	Use:          "grant <role>",
+	Short:        "api(.exe) rbac grant role",
+	Long:         "api(.exe) rbac grant admin --user 1 -c ./build/app.json",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		role, err := rbacRole(args[0])
+		if err != nil {
+			return err
+		}
+		granted, err := (&model.UserRole{}).Grant(*rbacUser, role.Id)
+		if err != nil {
+			return err
+		}
+		if !granted {
+			fmt.Printf("user %d already has role %s\n", *rbacUser, role.Name)
+			return nil
+		}
+		fmt.Printf("granted role %s to user %d\n", role.Name, *rbacUser)
+		return nil
+	},
+}
+
+var rbacRevokeCmd = &cobra.Command{
+	Use:          "revoke <role>",
+	Short:        "api(.exe) rbac revoke role",
+	Long:         "api(.exe) rbac revoke admin --user 1 -c ./build/app.json [--allow-stale-cache]",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		role, err := rbacRole(args[0])
+		if err != nil {
+			return err
+		}
+		// check redis first, a revoke it can not invalidate would stay effective on the servers
+		if err := checkPermissionCache(*rbacAllowStaleCache); err != nil {
+			return err
+		}
+		revoked, err := (&model.UserRole{}).Revoke(*rbacUser, role.Id)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return fmt.Errorf("user %d does not have role %s", *rbacUser, role.Name)
+		}
+		fmt.Printf("revoked role %s from user %d\n", role.Name, *rbacUser)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rbacCmd)
+	rbacFile = rbacCmd.PersistentFlags().StringP("config", "c", "", "start config file (required)")
+	rbacSets = rbacCmd.PersistentFlags().StringArray("set", nil, "override a config field, e.g. --set mysql.host=127.0.0.1 (repeatable)")
+	rbacUser = rbacCmd.PersistentFlags().Int64("user", 0, "id of the user (required by grant and revoke)")
+
+	rbacAllowStaleCache = rbacRevokeCmd.Flags().Bool("allow-stale-cache", false,
+		"revoke even though redis is disabled in the config and the cached permissions can not be invalidated")
+
+	rbacCmd.AddCommand(rbacRolesCmd, rbacGrantCmd, rbacRevokeCmd)
+}
+
+// initRBACDB load the config, connect mysql and redis when enabled so that the cached permissions are invalidated
+func initRBACDB() error {
+	if *rbacFile == "" {
+		return errors.New(`required flag(s) "config" not set`)
+	}
+	cfg, err := config.Init(rbacFile, *rbacSets...)
+	if err != nil {
+		return err
+	}
+	if !cfg.MySQL.Enable {
+		return errors.New("rbac requires mysql.enable in config")
+	}
+	mysql.Init()
+	if cfg.Redis.Enable {
+		redis.Init()
+	} else {
+		fmt.Fprintln(os.Stderr, "warning: redis is disabled in this config, servers caching permissions in redis keep the old ones until the cache expires")
+	}
+	return nil
+}
+
+// checkPermissionCache fail when the cached permissions can not be invalidated: redis is unreachable,
+// or disabled in the config and allowStale is not set
+func checkPermissionCache(allowStale bool) error {
+	if redis.DB == nil {
+		if allowStale {
+			return nil
+		}
+		return errors.New("redis is disabled in this config, the servers would keep the revoked permissions cached " +
+			"for up to " + model.PermissionCacheTTL.String() + ", pass --allow-stale-cache to revoke anyway")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := redis.DB.Ping(ctx); err != nil {
+		return fmt.Errorf("redis is unreachable, the servers would keep the revoked permissions cached: %v", err)
+	}
+	return nil
+}
+
+// rbacRole connect the databases and find the role name for grant and revoke
+func rbacRole(name string) (*model.Role, error) {
+	if *rbacUser <= 0 {
+		return nil, errors.New("--user should be a user id")
+	}
+	if err := initRBACDB(); err != nil {
+		return nil, err
+	}
+	role, err := (&model.Role{}).QueryByName(name)
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, fmt.Errorf("no role %q", name)
+	}
+	return role, err
+}
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"template_project/auth"
 	"template_project/config"
 	"template_project/db/mysql"
 	"template_project/db/redis"
+	"template_project/health"
 	"template_project/logger"
 	"template_project/server"
 	"fmt"
@@ -11,16 +13,24 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var configFile *string
+var (
+	configFile *string
+	startSets  *[]string
+)
 
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "api(.exe) start",
 	Long:  "api(.exe) start -c ./build/app.json",
-	Run: func(cmd *cobra.Command, args []string) {
+	// runtime failures are not usage errors
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("start service")
 
-		cfg := config.Init(configFile)
+		cfg, err := config.Init(configFile, *startSets...)
+		if err != nil {
+			return err
+		}
 
 		if cfg.MySQL.Enable {
 			mysql.Init()
@@ -32,7 +42,14 @@ var startCmd = &cobra.Command{
 
 		logger.Init()
 
-		logger.Log.Info("Config:", cfg)
+		// the secrets are masked by Configuration.String
+		logger.Log.Info("config: %s", cfg)
+
+		if err := auth.Init(cfg.Auth); err != nil {
+			return err
+		}
+
+		health.Init(cfg)
 
 		api, err := server.New(&cfg)
 		if err != nil {
@@ -40,14 +57,18 @@ var startCmd = &cobra.Command{
 		}
 
 		if err := api.Start(); err != nil {
-			panic(err)
+			logger.Log.Error("server stopped with err: %v", err)
+			return err
 		}
+		logger.Log.Info("server exited")
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(startCmd)
 	configFile = startCmd.Flags().StringP("config", "c", "", "start config file (required)")
+	startSets = addSetFlag(startCmd)
 	err := startCmd.MarkFlagRequired("config")
 	if err != nil {
 		fmt.Println(err)
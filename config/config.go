@@ -2,8 +2,8 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"sync"
-	"time"
 
 	"github.com/jinzhu/configor"
 )
@@ -15,89 +15,225 @@ var (
 
 type (
 	ServerConfig struct {
-		Name             string        `json:"name"`
-		RunMode          string        `json:"run_mode"`
-		ListenAddr       string        `json:"listen_addr"`
-		LimitConnection  int           `json:"limit_connection"`
-		RootRouterPrefix string        `json:"root_router_prefix"`
-		EnableHTTPS      bool          `json:"enable_https"`
-		HTTPSAddr        string        `json:"https_addr"`
-		ReadTimeout      time.Duration `json:"read_timeout"`
-		WriteTimeout     time.Duration `json:"write_timeout"`
-		IdleTimeout      time.Duration `json:"idle_timeout"`
-		MaxHeaderBytes   int           `json:"max_header_bytes"`
+		Name              string   `json:"name"`
+		RunMode           string   `json:"run_mode"`
+		ListenAddr        string   `json:"listen_addr"`
+		LimitConnection   int      `json:"limit_connection"`
+		AllowOrigins      []string `json:"allow_origins"` // CORS origins, default *
+		RootRouterPrefix  string   `json:"root_router_prefix"`
+		EnableHTTPS       bool     `json:"enable_https"`
+		HTTPSAddr         string   `json:"https_addr"`
+		ReadTimeout       Duration `json:"read_timeout"`
+		ReadHeaderTimeout Duration `json:"read_header_timeout"`
+		WriteTimeout      Duration `json:"write_timeout"`
+		IdleTimeout       Duration `json:"idle_timeout"`
+		MaxHeaderBytes    int      `json:"max_header_bytes"`
+		ShutdownTimeout   Duration `json:"shutdown_timeout"` // drain deadline for in-flight requests
+		AdminAddr         string   `json:"admin_addr"`       // listener of /metrics, kept off the public port, empty disables it
+		DrainDelay        Duration `json:"drain_delay"`      // time /readyz fails before the listeners close, for the load balancer to notice
+		TrustedProxies    []string `json:"trusted_proxies"`  // ips or cidrs of the proxies whose X-Forwarded-For is believed, empty trusts none
 	}
 
 	TLSConfig struct {
-		CertFile string `json:"cert_file"`
-		KeyFile  string `json:"key_file"`
+		CertFile     string   `json:"cert_file"`
+		KeyFile      string   `json:"key_file"`
+		MinVersion   string   `json:"min_version"`    // 1.0, 1.1, 1.2 or 1.3, default 1.2
+		CipherSuites []string `json:"cipher_suites"`  // IANA names, empty means go defaults
+		ClientAuth   string   `json:"client_auth"`    // none, request, require, verify_if_given, require_and_verify
+		ClientCAFile string   `json:"client_ca_file"` // CA bundle used to verify client certs
 	}
 
 	MySQLConfig struct {
-		Enable             bool   `json:"enable" defualt:"false"`
-		Host               string `json:"host"`
-		Port               string `json:"port"`
-		User               string `json:"user"`
-		Password           string `json:"password"`
-		DbName             string `json:"db_name"`
-		TablePrefix        string `json:"table_prefix"`
-		MaxOpenConnections int    `json:"max_open_connections"`
-		MaxIdleConnections int    `json:"max_idle_connections"`
-		ConnMaxLifetime    int    `json:"conn_max_lifetime"`
-		Local              string `json:"local"`
-		Debug              bool   `json:"debug"`
+		Enable             bool     `json:"enable" defualt:"false"`
+		Host               string   `json:"host"`
+		Port               string   `json:"port"`
+		User               string   `json:"user"`
+		Password           string   `json:"password" secret:"true"`
+		DbName             string   `json:"db_name"`
+		TablePrefix        string   `json:"table_prefix"`
+		MaxOpenConnections int      `json:"max_open_connections"`
+		MaxIdleConnections int      `json:"max_idle_connections"`
+		ConnMaxLifetime    Duration `json:"conn_max_lifetime"`
+		Local              string   `json:"local"`
+		Debug              bool     `json:"debug"`
 	}
 
 	RedisConfig struct {
-		Enable      bool   `json:"enable" defualt:"false"`
-		Host        string `json:"host"`
-		Port        string `json:"port"`
-		Password    string `json:"password"`
-		IdleTimeout int    `json:"idle_timeout"`
-		MaxIdle     int    `json:"max_idle"`
-		MaxActive   int    `json:"max_active"`
+		Enable           bool           `json:"enable" defualt:"false"`
+		Mode             string         `json:"mode"` // standalone, sentinel or cluster, default standalone
+		Host             string         `json:"host"`
+		Port             string         `json:"port"`
+		Username         string         `json:"username"` // ACL user, redis 6+
+		Password         string         `json:"password" secret:"true"`
+		DB               int            `json:"db"`                              // logical database, must be 0 in cluster mode
+		MasterName       string         `json:"master_name"`                     // sentinel mode
+		SentinelAddrs    []string       `json:"sentinel_addrs"`                  // sentinel mode, host:port
+		SentinelPassword string         `json:"sentinel_password" secret:"true"` // sentinel mode
+		ClusterAddrs     []string       `json:"cluster_addrs"`                   // cluster mode, seed nodes host:port
+		DialTimeout      Duration       `json:"dial_timeout"`
+		ReadTimeout      Duration       `json:"read_timeout"`
+		WriteTimeout     Duration       `json:"write_timeout"`
+		IdleTimeout      Duration       `json:"idle_timeout"`
+		MaxIdle          int            `json:"max_idle"`
+		MaxActive        int            `json:"max_active"`
+		TLS              RedisTLSConfig `json:"tls"`
+	}
+
+	RedisTLSConfig struct {
+		Enable             bool   `json:"enable"`
+		CAFile             string `json:"ca_file"`   // CA bundle verifying the servers, system roots when empty
+		CertFile           string `json:"cert_file"` // client certificate, optional
+		KeyFile            string `json:"key_file"`
+		ServerName         string `json:"server_name"` // default the host dialed
+		InsecureSkipVerify bool   `json:"insecure_skip_verify"`
 	}
 
 	LoggerConfig struct {
-		Level          string        `json:"level"`
-		Formatter      string        `json:"formatter"`
-		DisableConsole bool          `json:"disable_console"`
-		Write          bool          `json:"write"`
-		Path           string        `json:"path"`
-		FileName       string        `json:"file_name"`
-		MaxAge         time.Duration `json:"max_age"`
-		RotationTime   time.Duration `json:"rotation_time"`
-		Debug          bool          `json:"debug"`
+		Level          string       `json:"level"`
+		Formatter      string       `json:"formatter"`
+		DisableConsole bool         `json:"disable_console"`
+		Write          bool         `json:"write"`
+		Path           string       `json:"path"`
+		FileName       string       `json:"file_name"`
+		MaxAge         HourDuration `json:"max_age"`       // a bare number is hours
+		RotationTime   HourDuration `json:"rotation_time"` // a bare number is hours
+		Debug          bool         `json:"debug"`
+		// Modules set the level of the named loggers, e.g. {"mysql": "warn"}, the others use level
+		Modules map[string]string `json:"modules"`
+		// LevelTTL is the default delay after which a level set through the admin api reverts, 0 keeps it
+		LevelTTL Duration `json:"level_ttl"`
+		// Redact mask the secrets in the entries before they reach any output
+		Redact RedactConfig `json:"redact"`
+	}
+
+	RedactConfig struct {
+		Disable  bool     `json:"disable"`
+		Patterns []string `json:"patterns"` // built-in patterns among private_key, jwt, password, email and hex_key, default all but hex_key
+		Custom   []string `json:"custom"`   // extra regular expressions, their matches are masked
+		Fields   []string `json:"fields"`   // names of the fields masked whole, default password, secret, authorization, token and api_key
+	}
+
+	RateLimitConfig struct {
+		Enable bool                     `json:"enable"`
+		Prefix string                   `json:"prefix"` // redis key prefix, default ratelimit:
+		Rules  map[string]RateLimitRule `json:"rules"`  // keyed by route group, a group without rule is not limited
+	}
+
+	RateLimitRule struct {
+		Algorithm string   `json:"algorithm"` // sliding_window or token_bucket
+		Limit     int64    `json:"limit"`     // requests per window, or bucket capacity
+		Window    Duration `json:"window"`    // window length, or time to refill the whole bucket
+		KeyBy     string   `json:"key_by"`    // ip, api_key or user of the authenticated request, default ip
+	}
+
+	AuthConfig struct {
+		JWT    JWTConfig    `json:"jwt"`
+		APIKey APIKeyConfig `json:"api_key"`
+		Wallet WalletConfig `json:"wallet"`
+	}
+
+	JWTConfig struct {
+		Enable          bool     `json:"enable"`
+		Algorithms      []string `json:"algorithms"`           // accepted among HS256, RS256 and ES256, default all
+		Secret          string   `json:"secret" secret:"true"` // HS256 key of the tokens without kid
+		JWKSFile        string   `json:"jwks_file"`            // local JWKS of the verification keys, reread when it changes
+		Issuer          string   `json:"issuer"`               // expected iss, empty skips the check
+		Audience        string   `json:"audience"`             // expected aud, empty skips the check
+		ClockSkew       Duration `json:"clock_skew"`           // leeway of exp, nbf and iat
+		AllowMissingExp bool     `json:"allow_missing_exp"`    // accept tokens without exp, they never expire
+	}
+
+	APIKeyConfig struct {
+		Enable bool   `json:"enable"`
+		Header string `json:"header"` // default X-API-Key
+	}
+
+	// WalletConfig is the sign in with an Ethereum wallet, the challenges are kept in redis
+	// and the sessions are HS256 jwt signed with auth.jwt.secret
+	WalletConfig struct {
+		Enable       bool     `json:"enable"`
+		Domain       string   `json:"domain"`        // shown in the message and EIP-712 domain name, e.g. example.com
+		URI          string   `json:"uri"`           // shown in the message
+		ChainID      int64    `json:"chain_id"`      // EIP-712 domain chainId
+		Statement    string   `json:"statement"`     // human readable line of the message
+		ChallengeTTL Duration `json:"challenge_ttl"` // default 5m
+		TokenTTL     Duration `json:"token_ttl"`     // lifetime of the issued jwt, default 24h
 	}
 
 	ChainConfig struct {
 		Account string `json:"account"`
-		Secret  string `json:"secret"`
+		Secret  string `json:"secret" secret:"true"`
+		RPC     string `json:"rpc"` // json-rpc endpoint checked by /readyz, empty skips the check
 	}
 
 	Configuration struct {
-		Server ServerConfig           `json:"server"`
-		TLS    TLSConfig              `json:"tls"`
-		MySQL  MySQLConfig            `json:"mysql"`
-		Redis  RedisConfig            `json:"redis"`
-		Logger LoggerConfig           `json:"logger"`
-		Chains map[string]ChainConfig `json:"chains"`
+		Server    ServerConfig           `json:"server"`
+		TLS       TLSConfig              `json:"tls"`
+		MySQL     MySQLConfig            `json:"mysql"`
+		Redis     RedisConfig            `json:"redis"`
+		Logger    LoggerConfig           `json:"logger"`
+		RateLimit RateLimitConfig        `json:"rate_limit"`
+		Auth      AuthConfig             `json:"auth"`
+		Chains    map[string]ChainConfig `json:"chains"`
 	}
 )
 
-func Init(file *string) Configuration {
-	Lock.Lock()
-	defer Lock.Unlock()
+// LoadFile read the config file only, without overrides or validation
+func LoadFile(file string) (Configuration, error) {
+	var cfg Configuration
+	if _, err := os.Stat(file); err != nil {
+		return cfg, fmt.Errorf("load config file err: %v", err)
+	}
+	if err := configor.Load(&cfg, file); err != nil {
+		return cfg, fmt.Errorf("load config file err: %v", err)
+	}
+	return cfg, nil
+}
 
-	err := configor.Load(&Cfg, *file)
+// Resolve merge the config file, then APP_ env variables, then key=value overrides
+func Resolve(file string, overrides ...string) (Configuration, error) {
+	cfg, err := LoadFile(file)
 	if err != nil {
-		fmt.Println(err)
+		return cfg, err
 	}
-	return Cfg
+	if err := cfg.ApplyEnv(os.Environ()); err != nil {
+		return cfg, err
+	}
+	if err := cfg.SetAll(overrides); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
 }
 
-func GetConfig() Configuration {
+// Load resolve and validate the config without touching the global config
+func Load(file string, overrides ...string) (Configuration, error) {
+	cfg, err := Resolve(file, overrides...)
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Init load the config file into the global config, an invalid file is rejected
+func Init(file *string, overrides ...string) (Configuration, error) {
+	cfg, err := Load(*file, overrides...)
+	if err != nil {
+		return cfg, err
+	}
+
 	Lock.Lock()
 	defer Lock.Unlock()
+	Cfg = cfg
+	source.file = *file
+	source.overrides = overrides
+	return Cfg, nil
+}
+
+func GetConfig() Configuration {
+	Lock.RLock()
+	defer Lock.RUnlock()
 	return Cfg
 }
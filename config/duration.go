@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Duration is a time.Duration loaded from config files.
+// It accepts a go duration string such as "500ms", "30s" or "2h",
+// or for backward compatibility a bare number which is read as seconds.
+type Duration struct {
+	time.Duration
+}
+
+// HourDuration is a Duration whose bare numbers are read as hours, the unit of
+// logger.max_age and logger.rotation_time before they accepted duration strings
+type HourDuration struct {
+	time.Duration
+}
+
+// ParseDuration parse a duration string, a bare number is read as seconds
+func ParseDuration(s string) (Duration, error) {
+	d, err := parseDuration(s, time.Second)
+	return Duration{d}, err
+}
+
+// parseDuration parse a duration string, a bare number is a count of unit
+func parseDuration(s string, unit time.Duration) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(unit)), nil
+	}
+	return 0, fmt.Errorf("invalid duration %q", s)
+}
+
+// unmarshalDuration decode a json string or number, a number is a count of unit
+func unmarshalDuration(b []byte, unit time.Duration) (time.Duration, error) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return 0, err
+	}
+	switch value := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return time.Duration(value * float64(unit)), nil
+	case string:
+		return parseDuration(value, unit)
+	}
+	return 0, fmt.Errorf("invalid duration %s", string(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	parsed, err := unmarshalDuration(b, time.Second)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, durations are written as strings
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(b []byte) error {
+	parsed, err := parseDuration(string(b), time.Second)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *HourDuration) UnmarshalJSON(b []byte) error {
+	parsed, err := unmarshalDuration(b, time.Hour)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, durations are written as strings
+func (d HourDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *HourDuration) UnmarshalText(b []byte) error {
+	parsed, err := parseDuration(string(b), time.Hour)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
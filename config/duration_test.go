@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30s", want: 30 * time.Second},
+		{in: "1m30s", want: 90 * time.Second},
+		{in: "500ms", want: 500 * time.Millisecond},
+		{in: "2h", want: 2 * time.Hour},
+		{in: "0", want: 0},
+		{in: "60", want: 60 * time.Second},
+		{in: "1.5", want: 1500 * time.Millisecond},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "10x", wantErr: true},
+		{in: "1m 30s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDuration(%q) err: %v", tt.in, err)
+			continue
+		}
+		if got.Duration != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got.Duration, tt.want)
+		}
+	}
+}
+
+func TestDurationUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: `"30s"`, want: 30 * time.Second},
+		{in: `"1m30s"`, want: 90 * time.Second},
+		{in: `60`, want: 60 * time.Second},
+		{in: `"60"`, want: 60 * time.Second},
+		{in: `0.25`, want: 250 * time.Millisecond},
+		{in: `null`, want: 0},
+		{in: `"soon"`, wantErr: true},
+		{in: `true`, wantErr: true},
+		{in: `[1]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var d Duration
+		err := json.Unmarshal([]byte(tt.in), &d)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("unmarshal %s = %v, want an error", tt.in, d.Duration)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unmarshal %s err: %v", tt.in, err)
+			continue
+		}
+		if d.Duration != tt.want {
+			t.Errorf("unmarshal %s = %v, want %v", tt.in, d.Duration, tt.want)
+		}
+	}
+}
+
+func TestDurationMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Duration{90 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"1m30s"` {
+		t.Errorf("marshal = %s, want \"1m30s\"", b)
+	}
+
+	var d Duration
+	if err := json.Unmarshal(b, &d); err != nil || d.Duration != 90*time.Second {
+		t.Errorf("round trip = %v, %v", d.Duration, err)
+	}
+}
+
+// the logger rotation fields read bare numbers as hours, as they did before duration strings
+func TestLoggerLegacyHours(t *testing.T) {
+	tests := []struct {
+		in           string
+		maxAge       time.Duration
+		rotationTime time.Duration
+	}{
+		{in: `{"max_age": 24, "rotation_time": 168}`, maxAge: 24 * time.Hour, rotationTime: 168 * time.Hour},
+		{in: `{"max_age": "24", "rotation_time": "0.5"}`, maxAge: 24 * time.Hour, rotationTime: 30 * time.Minute},
+		{in: `{"max_age": "24h", "rotation_time": "168h"}`, maxAge: 24 * time.Hour, rotationTime: 168 * time.Hour},
+		{in: `{"max_age": "90m", "rotation_time": "1h30m"}`, maxAge: 90 * time.Minute, rotationTime: 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		var l LoggerConfig
+		if err := json.Unmarshal([]byte(tt.in), &l); err != nil {
+			t.Errorf("unmarshal %s err: %v", tt.in, err)
+			continue
+		}
+		if l.MaxAge.Duration != tt.maxAge || l.RotationTime.Duration != tt.rotationTime {
+			t.Errorf("unmarshal %s = max_age %v rotation_time %v, want %v %v",
+				tt.in, l.MaxAge.Duration, l.RotationTime.Duration, tt.maxAge, tt.rotationTime)
+		}
+	}
+
+	var l LoggerConfig
+	if err := json.Unmarshal([]byte(`{"max_age": "a day"}`), &l); err == nil {
+		t.Errorf("unmarshal an invalid max_age = %v, want an error", l.MaxAge.Duration)
+	}
+}
+
+// the env and --set overrides parse the fields like the config file
+func TestDurationOverride(t *testing.T) {
+	var c Configuration
+	for _, kv := range []string{"server.read_timeout=60", "server.write_timeout=1m30s", "logger.max_age=24", "logger.rotation_time=30m"} {
+		if err := c.SetAll([]string{kv}); err != nil {
+			t.Fatalf("set %s: %v", kv, err)
+		}
+	}
+	if c.Server.ReadTimeout.Duration != time.Minute {
+		t.Errorf("server.read_timeout = %v, want 1m", c.Server.ReadTimeout.Duration)
+	}
+	if c.Server.WriteTimeout.Duration != 90*time.Second {
+		t.Errorf("server.write_timeout = %v, want 1m30s", c.Server.WriteTimeout.Duration)
+	}
+	if c.Logger.MaxAge.Duration != 24*time.Hour {
+		t.Errorf("logger.max_age = %v, want 24h", c.Logger.MaxAge.Duration)
+	}
+	if c.Logger.RotationTime.Duration != 30*time.Minute {
+		t.Errorf("logger.rotation_time = %v, want 30m", c.Logger.RotationTime.Duration)
+	}
+	if err := c.Set("server.idle_timeout", "later"); err == nil {
+		t.Error("set an invalid server.idle_timeout, want an error")
+	}
+}
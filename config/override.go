@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of environment variables overriding config fields,
+// e.g. APP_MYSQL_PASSWORD sets mysql.password, APP_CHAINS_ETH_SECRET sets chains.eth.secret
+// and APP_LOGGER_MODULES_MYSQL sets logger.modules.mysql
+const EnvPrefix = "APP_"
+
+// Set override a single field addressed by its json path such as mysql.host or chains.eth.secret.
+// Durations accept the same formats as the config file and string lists are comma separated.
+func (c *Configuration) Set(path, value string) error {
+	return setPath(reflect.ValueOf(c).Elem(), strings.Split(path, "."), path, value)
+}
+
+// SetAll apply key=value overrides in order
+func (c *Configuration) SetAll(overrides []string) error {
+	for _, o := range overrides {
+		kv := strings.SplitN(o, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid config override %q, should be key=value", o)
+		}
+		if err := c.Set(kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyEnv override fields from environment variables in KEY=value form, like os.Environ.
+// Variables are applied in name order and those not matching a config field are ignored.
+func (c *Configuration) ApplyEnv(environ []string) error {
+	vars := make(map[string]string)
+	var names []string
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], EnvPrefix) {
+			continue
+		}
+		vars[parts[0]] = parts[1]
+		names = append(names, parts[0])
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path, ok := envPath(reflect.TypeOf(*c), strings.TrimPrefix(name, EnvPrefix))
+		if !ok {
+			continue
+		}
+		if err := c.Set(strings.Join(path, "."), vars[name]); err != nil {
+			return fmt.Errorf("env %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// envPath resolve an upper-case, underscore separated env name to a json path of t
+func envPath(t reflect.Type, name string) ([]string, bool) {
+	switch {
+	case isLeaf(t):
+		return nil, name == ""
+	case t.Kind() == reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			tag := jsonName(t.Field(i))
+			if tag == "" {
+				continue
+			}
+			upper := strings.ToUpper(tag)
+			if name == upper {
+				if isLeaf(t.Field(i).Type) {
+					return []string{tag}, true
+				}
+				continue
+			}
+			if strings.HasPrefix(name, upper+"_") {
+				if rest, ok := envPath(t.Field(i).Type, name[len(upper)+1:]); ok {
+					return append([]string{tag}, rest...), true
+				}
+			}
+		}
+	case t.Kind() == reflect.Map:
+		// a map of values is keyed by the rest of the name, e.g. LOGGER_MODULES_MYSQL
+		if isLeaf(t.Elem()) {
+			return []string{strings.ToLower(name)}, name != ""
+		}
+		// the map key may itself contain underscores, take the shortest key that resolves
+		for i := 1; i < len(name); i++ {
+			if name[i] != '_' {
+				continue
+			}
+			if rest, ok := envPath(t.Elem(), name[i+1:]); ok {
+				return append([]string{strings.ToLower(name[:i])}, rest...), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func setPath(v reflect.Value, keys []string, path, value string) error {
+	if len(keys) == 0 {
+		return setValue(v, path, value)
+	}
+	switch {
+	case isLeaf(v.Type()):
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if jsonName(t.Field(i)) == keys[0] {
+				return setPath(v.Field(i), keys[1:], path, value)
+			}
+		}
+	case v.Kind() == reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.ValueOf(keys[0]).Convert(v.Type().Key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := setPath(elem, keys[1:], path, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+	return fmt.Errorf("unknown config key %q", path)
+}
+
+func setValue(v reflect.Value, path, value string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("config key %q: %v", path, err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config key %q should be a bool: %v", path, err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("config key %q should be an integer: %v", path, err)
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("config key %q can not be overridden", path)
+		}
+		var list []string
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("config key %q can not be overridden", path)
+	}
+	return nil
+}
+
+// isLeaf report whether t is set from a single value rather than walked into
+func isLeaf(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return true
+	}
+	return t.Kind() != reflect.Struct && t.Kind() != reflect.Map
+}
+
+func jsonName(f reflect.StructField) string {
+	tag := strings.Split(f.Tag.Get("json"), ",")[0]
+	if tag == "-" {
+		return ""
+	}
+	return tag
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvPath(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{env: "MYSQL_PASSWORD", want: []string{"mysql", "password"}},
+		{env: "SERVER_LISTEN_ADDR", want: []string{"server", "listen_addr"}},
+		{env: "REDIS_TLS_CA_FILE", want: []string{"redis", "tls", "ca_file"}},
+		{env: "CHAINS_ETH_SECRET", want: []string{"chains", "eth", "secret"}},
+		{env: "CHAINS_ETH_MAIN_RPC", want: []string{"chains", "eth_main", "rpc"}},
+		{env: "RATE_LIMIT_RULES_V1_LIMIT", want: []string{"rate_limit", "rules", "v1", "limit"}},
+		{env: "LOGGER_MODULES_MYSQL", want: []string{"logger", "modules", "mysql"}},
+		{env: "LOGGER_MODULES_MY_MODULE", want: []string{"logger", "modules", "my_module"}},
+		{env: "LOGGER_MODULES", want: nil},
+		{env: "MYSQL", want: nil},
+		{env: "UNKNOWN_KEY", want: nil},
+	}
+	for _, tt := range tests {
+		got, ok := envPath(reflect.TypeOf(Configuration{}), tt.env)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("envPath(%s) = %v, want no match", tt.env, got)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("envPath(%s) = %v, %v, want %v", tt.env, got, ok, tt.want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	var c Configuration
+	err := c.ApplyEnv([]string{
+		"APP_LOGGER_MODULES_MYSQL=warn",
+		"APP_MYSQL_PORT=3307",
+		"APP_SERVER_ALLOW_ORIGINS=https://a.example, https://b.example",
+		"APP_UNKNOWN=ignored",
+		"PATH=/usr/bin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Logger.Modules["mysql"] != "warn" {
+		t.Errorf("logger.modules.mysql = %q, want warn", c.Logger.Modules["mysql"])
+	}
+	if c.MySQL.Port != "3307" {
+		t.Errorf("mysql.port = %q, want 3307", c.MySQL.Port)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(c.Server.AllowOrigins, want) {
+		t.Errorf("server.allow_origins = %v, want %v", c.Server.AllowOrigins, want)
+	}
+
+	if err := c.ApplyEnv([]string{"APP_MYSQL_MAX_OPEN_CONNECTIONS=many"}); err == nil {
+		t.Error("apply an invalid integer, want an error")
+	}
+}
+
+// the config file is overridden by the env, which is overridden by --set
+func TestResolvePrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.json")
+	data := `{"mysql": {"host": "file", "port": "3306", "user": "file"}, "logger": {"modules": {"mysql": "info", "redis": "info"}}}`
+	if err := ioutil.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer setenv(t, "APP_MYSQL_HOST", "env")()
+	defer setenv(t, "APP_MYSQL_PORT", "3307")()
+	defer setenv(t, "APP_LOGGER_MODULES_MYSQL", "warn")()
+	defer setenv(t, "APP_LOGGER_MODULES_REDIS", "warn")()
+
+	c, err := Resolve(file, "mysql.host=flag", "logger.modules.redis=error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		key, got, want string
+	}{
+		{"mysql.host", c.MySQL.Host, "flag"},
+		{"mysql.port", c.MySQL.Port, "3307"},
+		{"mysql.user", c.MySQL.User, "file"},
+		{"logger.modules.mysql", c.Logger.Modules["mysql"], "warn"},
+		{"logger.modules.redis", c.Logger.Modules["redis"], "error"},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %q, want %q", check.key, check.got, check.want)
+		}
+	}
+}
+
+func TestSetAll(t *testing.T) {
+	var c Configuration
+	if err := c.SetAll([]string{"redis.db=2", "chains.eth.account=0xabc", "server.enable_https=true"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Redis.DB != 2 || c.Chains["eth"].Account != "0xabc" || !c.Server.EnableHTTPS {
+		t.Errorf("set = redis.db %d, chains.eth.account %q, server.enable_https %v", c.Redis.DB, c.Chains["eth"].Account, c.Server.EnableHTTPS)
+	}
+
+	for _, o := range []string{"redis.db", "=1", "redis.nope=1", "redis.db=two", "server.enable_https=maybe"} {
+		if err := c.SetAll([]string{o}); err == nil {
+			t.Errorf("set %q, want an error", o)
+		}
+	}
+}
+
+// setenv set key and return a func restoring its previous value
+func setenv(t *testing.T, key, value string) func() {
+	old, had := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// SecretMask replace the values of the secret fields
+const SecretMask = "******"
+
+// Masked return a copy of the configuration with the fields tagged secret:"true" hidden
+func (c Configuration) Masked() Configuration {
+	masked := c
+	maskSecrets(reflect.ValueOf(&masked).Elem(), false)
+	return masked
+}
+
+// String implements fmt.Stringer, the secrets are masked so that the config can be logged
+func (c Configuration) String() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// MarshalJSON implements json.Marshaler, the secrets are masked
+func (c Configuration) MarshalJSON() ([]byte, error) {
+	// plain drops the methods of Configuration, or json.Marshal would call MarshalJSON again
+	type plain Configuration
+	return json.Marshal(plain(c.Masked()))
+}
+
+// maskSecrets hide the secret strings held by v, which must be settable.
+// The maps and slices are copied before they are changed, the copy shares them with the original.
+func maskSecrets(v reflect.Value, secret bool) {
+	switch v.Kind() {
+	case reflect.String:
+		if secret && v.Len() > 0 {
+			v.SetString(SecretMask)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" {
+				maskSecrets(v.Field(i), secret || f.Tag.Get("secret") == "true")
+			}
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		elem := reflect.New(v.Type().Elem())
+		elem.Elem().Set(v.Elem())
+		maskSecrets(elem.Elem(), secret)
+		v.Set(elem)
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(copied, v)
+		for i := 0; i < copied.Len(); i++ {
+			maskSecrets(copied.Index(i), secret)
+		}
+		v.Set(copied)
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(k))
+			maskSecrets(elem, secret)
+			copied.SetMapIndex(k, elem)
+		}
+		v.Set(copied)
+	}
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce group the burst of events editors emit for a single save
+const reloadDebounce = 200 * time.Millisecond
+
+// reloadable lists the config keys applied without restart, a key also covers its children.
+// Any other change is swapped into Cfg but only takes effect after a restart.
+var reloadable = []string{
+	"logger.level",
+	"logger.level_ttl",
+	"logger.modules",
+	"server.allow_origins",
+	"server.limit_connection",
+	"server.trusted_proxies",
+	"rate_limit",
+}
+
+// ChangeFunc is called with the previous and the new config after a reload
+type ChangeFunc func(old, new Configuration)
+
+var (
+	subscribers []ChangeFunc
+	subLock     sync.Mutex
+
+	// source remember what Init loaded so that Reload read the same file and overrides
+	source struct {
+		file      string
+		overrides []string
+	}
+)
+
+// OnChange register fn to be called after a reloaded config has been swapped in
+func OnChange(fn ChangeFunc) {
+	subLock.Lock()
+	defer subLock.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// ReloadResult describe what a reload changed
+type ReloadResult struct {
+	Changed []string // every changed key
+	Restart []string // changed keys that only take effect after a restart
+}
+
+// Reload re-read the config given to Init, validate it and atomically swap it in.
+// An invalid file is rejected and the running config is kept.
+func Reload() (ReloadResult, error) {
+	var result ReloadResult
+
+	Lock.RLock()
+	file, overrides := source.file, source.overrides
+	Lock.RUnlock()
+	if file == "" {
+		return result, errors.New("config is not initialized")
+	}
+
+	cfg, err := Load(file, overrides...)
+	if err != nil {
+		return result, err
+	}
+
+	Lock.Lock()
+	old := Cfg
+	result.Changed = changedKeys(old, cfg)
+	if len(result.Changed) > 0 {
+		Cfg = cfg
+	}
+	Lock.Unlock()
+
+	if len(result.Changed) == 0 {
+		return result, nil
+	}
+	for _, key := range result.Changed {
+		if !isReloadable(key) {
+			result.Restart = append(result.Restart, key)
+		}
+	}
+
+	subLock.Lock()
+	fns := append([]ChangeFunc(nil), subscribers...)
+	subLock.Unlock()
+	for _, fn := range fns {
+		fn(old, cfg)
+	}
+	return result, nil
+}
+
+// Watch reload the config on SIGHUP and whenever the config file changes, until ctx is done.
+// report is called after every reload attempt.
+func Watch(ctx context.Context, report func(ReloadResult, error)) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	Lock.RLock()
+	file := source.file
+	Lock.RUnlock()
+
+	var events chan fsnotify.Event
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		defer watcher.Close()
+		// watch the directory, editors replace the file rather than write it and kubernetes
+		// config maps swap the ..data symlink the file resolves through
+		err = watcher.Add(filepath.Dir(file))
+	}
+	if err != nil {
+		report(ReloadResult{}, err)
+	} else {
+		events = watcher.Events
+	}
+
+	var (
+		last     = statFile(file)
+		debounce = time.NewTimer(reloadDebounce)
+		reload   = func() {
+			result, err := Reload()
+			report(result, err)
+		}
+	)
+	debounce.Stop()
+	watchTarget(watcher, file, last)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			reload()
+		case e := <-events:
+			if e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			// any event of the directories may change what the path resolves to, compare the file itself
+			stamp := statFile(file)
+			if stamp != last || (filepath.Clean(e.Name) == filepath.Clean(file) && e.Op&fsnotify.Write != 0) {
+				last = stamp
+				watchTarget(watcher, file, stamp)
+				debounce.Reset(reloadDebounce)
+			}
+		case <-debounce.C:
+			reload()
+		}
+	}
+}
+
+// fileStamp identify the content a config path resolves to
+type fileStamp struct {
+	path    string // the path after the symlinks, empty when it does not resolve
+	size    int64
+	modTime time.Time
+}
+
+func statFile(file string) fileStamp {
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return fileStamp{}
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{path: resolved, size: info.Size(), modTime: info.ModTime()}
+}
+
+// watchTarget also watch the directory of the file a symlinked config resolves to,
+// so that writes to the target are seen
+func watchTarget(watcher *fsnotify.Watcher, file string, stamp fileStamp) {
+	if watcher == nil || stamp.path == "" {
+		return
+	}
+	if dir := filepath.Dir(stamp.path); dir != filepath.Dir(filepath.Clean(file)) {
+		watcher.Add(dir)
+	}
+}
+
+func isReloadable(key string) bool {
+	for _, r := range reloadable {
+		if key == r || strings.HasPrefix(key, r+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// changedKeys list the json paths of the leaves that differ between a and b
+func changedKeys(a, b Configuration) []string {
+	var keys []string
+	diff(reflect.ValueOf(a), reflect.ValueOf(b), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func diff(a, b reflect.Value, path string, keys *[]string) {
+	switch {
+	case isLeaf(a.Type()):
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*keys = append(*keys, path)
+		}
+	case a.Kind() == reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if name := jsonName(t.Field(i)); name != "" {
+				diff(a.Field(i), b.Field(i), join(path, name), keys)
+			}
+		}
+	case a.Kind() == reflect.Map:
+		seen := make(map[string]bool)
+		for _, v := range []reflect.Value{a, b} {
+			for _, k := range v.MapKeys() {
+				name := k.String()
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				x, y := a.MapIndex(k), b.MapIndex(k)
+				if !x.IsValid() || !y.IsValid() {
+					*keys = append(*keys, join(path, name))
+					continue
+				}
+				diff(x, y, join(path, name), keys)
+			}
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const watchConfig = `{"server": {"listen_addr": ":8080"}, "logger": {"level": "%s"}}`
+
+// a kubernetes config map mounts app.json -> ..data/app.json, ..data -> ..<timestamp>,
+// an update writes a new timestamped directory and renames a new ..data symlink over the old one
+func TestWatchConfigMapSwap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeVersion(t, dir, "..v1", "info")
+	must(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	file := filepath.Join(dir, "app.json")
+	must(t, os.Symlink(filepath.Join("..data", "app.json"), file))
+
+	if _, err := Init(&file); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan ReloadResult, 1)
+	go Watch(ctx, func(result ReloadResult, err error) {
+		if err != nil {
+			t.Errorf("reload: %v", err)
+		}
+		reports <- result
+	})
+	// let the watcher start
+	time.Sleep(100 * time.Millisecond)
+
+	writeVersion(t, dir, "..v2", "debug")
+	must(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	must(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	must(t, os.RemoveAll(filepath.Join(dir, "..v1")))
+
+	select {
+	case result := <-reports:
+		if len(result.Changed) != 1 || result.Changed[0] != "logger.level" {
+			t.Errorf("changed = %v, want [logger.level]", result.Changed)
+		}
+		if level := GetConfig().Logger.Level; level != "debug" {
+			t.Errorf("logger.level = %q, want debug", level)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the config map update was not reloaded")
+	}
+}
+
+func writeVersion(t *testing.T, dir, version, level string) {
+	must(t, os.Mkdir(filepath.Join(dir, version), 0700))
+	data := []byte(fmt.Sprintf(watchConfig, level))
+	must(t, ioutil.WriteFile(filepath.Join(dir, version, "app.json"), data, 0600))
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,361 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	rateLimitAlgorithms = []string{"sliding_window", "token_bucket"}
+	rateLimitKeys       = []string{"", "ip", "api_key", "user"}
+	jwtAlgorithms       = []string{"HS256", "RS256", "ES256"}
+	runModes            = []string{"", "debug", "release", "test"}
+	logLevels           = []string{"", "panic", "fatal", "error", "warn", "info", "debug"}
+	formatters          = []string{"", "text", "json"}
+	redactPatterns      = []string{"private_key", "jwt", "password", "email", "hex_key"}
+)
+
+// ValidationError holds every problem found in a Configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config:\n  - %s", strings.Join(e.Problems, "\n  - "))
+}
+
+func (e *ValidationError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// Validate check the configuration and return all problems at once as a *ValidationError
+func (c *Configuration) Validate() error {
+	e := &ValidationError{}
+
+	c.validateServer(e)
+	c.validateMySQL(e)
+	c.validateRedis(e)
+	c.validateLogger(e)
+	c.validateRateLimit(e)
+	c.validateAuth(e)
+	c.validateChains(e)
+
+	if len(e.Problems) > 0 {
+		return e
+	}
+	return nil
+}
+
+func (c *Configuration) validateServer(e *ValidationError) {
+	s := c.Server
+	if !oneOf(s.RunMode, runModes) {
+		e.add("server.run_mode %q should be one of debug, release, test", s.RunMode)
+	}
+	checkAddr(e, "server.listen_addr", s.ListenAddr)
+	for _, origin := range s.AllowOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			e.add("server.allow_origins %q should be * or start with http:// or https://", origin)
+		}
+	}
+	if s.LimitConnection < 0 {
+		e.add("server.limit_connection should not be negative")
+	}
+	if s.MaxHeaderBytes < 0 {
+		e.add("server.max_header_bytes should not be negative")
+	}
+	checkDuration(e, "server.read_timeout", s.ReadTimeout)
+	checkDuration(e, "server.read_header_timeout", s.ReadHeaderTimeout)
+	checkDuration(e, "server.write_timeout", s.WriteTimeout)
+	checkDuration(e, "server.idle_timeout", s.IdleTimeout)
+	checkDuration(e, "server.shutdown_timeout", s.ShutdownTimeout)
+	checkDuration(e, "server.drain_delay", s.DrainDelay)
+	for _, proxy := range s.TrustedProxies {
+		if !validProxy(proxy) {
+			e.add("server.trusted_proxies %q should be an ip or a cidr", proxy)
+		}
+	}
+	if s.AdminAddr != "" {
+		checkAddr(e, "server.admin_addr", s.AdminAddr)
+		if s.AdminAddr == s.ListenAddr || (s.EnableHTTPS && s.AdminAddr == s.HTTPSAddr) {
+			e.add("server.admin_addr %q should differ from the api listeners", s.AdminAddr)
+		}
+	}
+
+	if !s.EnableHTTPS {
+		return
+	}
+	checkAddr(e, "server.https_addr", s.HTTPSAddr)
+	checkFile(e, "tls.cert_file", c.TLS.CertFile, true)
+	checkFile(e, "tls.key_file", c.TLS.KeyFile, true)
+	checkFile(e, "tls.client_ca_file", c.TLS.ClientCAFile, false)
+}
+
+func (c *Configuration) validateMySQL(e *ValidationError) {
+	m := c.MySQL
+	if !m.Enable {
+		return
+	}
+	if m.Host == "" {
+		e.add("mysql.host is required")
+	}
+	checkPort(e, "mysql.port", m.Port)
+	if m.User == "" {
+		e.add("mysql.user is required")
+	}
+	if m.DbName == "" {
+		e.add("mysql.db_name is required")
+	}
+	checkPool(e, "mysql.max_idle_connections", m.MaxIdleConnections, "mysql.max_open_connections", m.MaxOpenConnections)
+	checkDuration(e, "mysql.conn_max_lifetime", m.ConnMaxLifetime)
+}
+
+func (c *Configuration) validateRedis(e *ValidationError) {
+	r := c.Redis
+	if !r.Enable {
+		return
+	}
+	switch r.Mode {
+	case "", "standalone":
+		if r.Host == "" {
+			e.add("redis.host is required")
+		}
+		checkPort(e, "redis.port", r.Port)
+	case "sentinel":
+		if r.MasterName == "" {
+			e.add("redis.master_name is required in sentinel mode")
+		}
+		checkAddrs(e, "redis.sentinel_addrs", r.SentinelAddrs)
+	case "cluster":
+		checkAddrs(e, "redis.cluster_addrs", r.ClusterAddrs)
+		if r.DB != 0 {
+			e.add("redis.db should be 0 in cluster mode")
+		}
+	default:
+		e.add("redis.mode %q should be standalone, sentinel or cluster", r.Mode)
+	}
+	if r.DB < 0 {
+		e.add("redis.db should not be negative")
+	}
+	if r.TLS.Enable {
+		checkFile(e, "redis.tls.ca_file", r.TLS.CAFile, false)
+		if (r.TLS.CertFile == "") != (r.TLS.KeyFile == "") {
+			e.add("redis.tls.cert_file and redis.tls.key_file should be set together")
+		}
+		checkFile(e, "redis.tls.cert_file", r.TLS.CertFile, false)
+		checkFile(e, "redis.tls.key_file", r.TLS.KeyFile, false)
+	}
+	checkDuration(e, "redis.dial_timeout", r.DialTimeout)
+	checkDuration(e, "redis.read_timeout", r.ReadTimeout)
+	checkDuration(e, "redis.write_timeout", r.WriteTimeout)
+	checkPool(e, "redis.max_idle", r.MaxIdle, "redis.max_active", r.MaxActive)
+	checkDuration(e, "redis.idle_timeout", r.IdleTimeout)
+}
+
+func (c *Configuration) validateLogger(e *ValidationError) {
+	l := c.Logger
+	if !oneOf(l.Level, logLevels) {
+		e.add("logger.level %q should be one of panic, fatal, error, warn, info, debug", l.Level)
+	}
+	if !oneOf(l.Formatter, formatters) {
+		e.add("logger.formatter %q should be text or json", l.Formatter)
+	}
+	checkDuration(e, "logger.max_age", Duration(l.MaxAge))
+	checkDuration(e, "logger.rotation_time", Duration(l.RotationTime))
+	checkDuration(e, "logger.level_ttl", l.LevelTTL)
+
+	modules := make([]string, 0, len(l.Modules))
+	for module := range l.Modules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		if level := l.Modules[module]; level == "" || !oneOf(level, logLevels) {
+			e.add("logger.modules.%s %q should be one of panic, fatal, error, warn, info, debug", module, level)
+		}
+		if module == "root" {
+			e.add("logger.modules.root should be set with logger.level")
+		}
+	}
+
+	for _, p := range l.Redact.Patterns {
+		if !oneOf(p, redactPatterns) {
+			e.add("logger.redact.patterns %q should be one of %s", p, strings.Join(redactPatterns, ", "))
+		}
+	}
+	for _, expr := range l.Redact.Custom {
+		if _, err := regexp.Compile(expr); err != nil {
+			e.add("logger.redact.custom %q: %v", expr, err)
+		}
+	}
+}
+
+func (c *Configuration) validateRateLimit(e *ValidationError) {
+	r := c.RateLimit
+	if !r.Enable {
+		return
+	}
+	names := make([]string, 0, len(r.Rules))
+	for name := range r.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rule := r.Rules[name]
+		prefix := "rate_limit.rules." + name
+		if !oneOf(rule.Algorithm, rateLimitAlgorithms) {
+			e.add("%s.algorithm %q should be sliding_window or token_bucket", prefix, rule.Algorithm)
+		}
+		if rule.Limit <= 0 {
+			e.add("%s.limit should be positive", prefix)
+		}
+		if rule.Window.Duration <= 0 {
+			e.add("%s.window should be positive", prefix)
+		}
+		switch {
+		case !oneOf(rule.KeyBy, rateLimitKeys):
+			e.add("%s.key_by %q should be ip, api_key or user", prefix, rule.KeyBy)
+		case rule.KeyBy == "api_key" && !c.Auth.APIKey.Enable:
+			e.add("%s.key_by api_key requires auth.api_key.enable", prefix)
+		case rule.KeyBy == "user" && !c.Auth.JWT.Enable && !c.Auth.APIKey.Enable:
+			e.add("%s.key_by user requires auth.jwt or auth.api_key", prefix)
+		}
+	}
+}
+
+func (c *Configuration) validateAuth(e *ValidationError) {
+	j := c.Auth.JWT
+	if j.Enable {
+		for _, alg := range j.Algorithms {
+			if !oneOf(alg, jwtAlgorithms) {
+				e.add("auth.jwt.algorithms %q should be HS256, RS256 or ES256", alg)
+			}
+		}
+		if j.Secret == "" && j.JWKSFile == "" {
+			e.add("auth.jwt needs a secret or a jwks_file")
+		}
+		checkFile(e, "auth.jwt.jwks_file", j.JWKSFile, false)
+		checkDuration(e, "auth.jwt.clock_skew", j.ClockSkew)
+	}
+	if c.Auth.APIKey.Enable && !c.MySQL.Enable {
+		e.add("auth.api_key requires mysql.enable, the keys are stored in mysql")
+	}
+	if w := c.Auth.Wallet; w.Enable {
+		if w.Domain == "" {
+			e.add("auth.wallet.domain is required")
+		}
+		if w.ChainID <= 0 {
+			e.add("auth.wallet.chain_id should be positive")
+		}
+		checkDuration(e, "auth.wallet.challenge_ttl", w.ChallengeTTL)
+		checkDuration(e, "auth.wallet.token_ttl", w.TokenTTL)
+		if !c.Redis.Enable {
+			e.add("auth.wallet requires redis.enable, the challenges are stored in redis")
+		}
+		if !j.Enable || j.Secret == "" || (len(j.Algorithms) > 0 && !oneOf("HS256", j.Algorithms)) {
+			e.add("auth.wallet requires auth.jwt with a secret and HS256, the sessions are jwt")
+		}
+	}
+}
+
+func (c *Configuration) validateChains(e *ValidationError) {
+	names := make([]string, 0, len(c.Chains))
+	for name := range c.Chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rpc := c.Chains[name].RPC
+		if rpc == "" {
+			continue
+		}
+		if u, err := url.Parse(rpc); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			e.add("chains.%s.rpc %q should be an http or https url", name, rpc)
+		}
+	}
+}
+
+func checkAddr(e *ValidationError, name, addr string) {
+	if addr == "" {
+		e.add("%s is required", name)
+		return
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		e.add("%s %q should be host:port", name, addr)
+		return
+	}
+	checkPort(e, name, port)
+}
+
+func checkAddrs(e *ValidationError, name string, addrs []string) {
+	if len(addrs) == 0 {
+		e.add("%s is required", name)
+	}
+	for i, addr := range addrs {
+		checkAddr(e, fmt.Sprintf("%s[%d]", name, i), addr)
+	}
+}
+
+func checkPort(e *ValidationError, name, port string) {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		e.add("%s port %q should be a number between 1 and 65535", name, port)
+	}
+}
+
+func checkFile(e *ValidationError, name, file string, required bool) {
+	if file == "" {
+		if required {
+			e.add("%s is required when server.enable_https is set", name)
+		}
+		return
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		e.add("%s %q: %v", name, file, err)
+	} else if info.IsDir() {
+		e.add("%s %q is a directory", name, file)
+	}
+}
+
+func checkPool(e *ValidationError, idleName string, idle int, maxName string, max int) {
+	if idle < 0 {
+		e.add("%s should not be negative", idleName)
+	}
+	if max < 0 {
+		e.add("%s should not be negative", maxName)
+	}
+	if max > 0 && idle > max {
+		e.add("%s %d should not exceed %s %d", idleName, idle, maxName, max)
+	}
+}
+
+func checkDuration(e *ValidationError, name string, d Duration) {
+	if d.Duration < 0 {
+		e.add("%s should not be negative", name)
+	}
+}
+
+// validProxy accept an ip or a cidr
+func validProxy(proxy string) bool {
+	if strings.Contains(proxy, "/") {
+		_, _, err := net.ParseCIDR(proxy)
+		return err == nil
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+func oneOf(v string, values []string) bool {
+	for _, s := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// problems return the validation problems of c mentioning about
+func problems(c Configuration, about string) []string {
+	var found []string
+	if e, ok := c.Validate().(*ValidationError); ok {
+		for _, p := range e.Problems {
+			if strings.Contains(p, about) {
+				found = append(found, p)
+			}
+		}
+	}
+	return found
+}
+
+func TestValidateRateLimitKeyBy(t *testing.T) {
+	tests := []struct {
+		keyBy  string
+		auth   AuthConfig
+		errors int
+	}{
+		{"ip", AuthConfig{}, 0},
+		{"", AuthConfig{}, 0},
+		{"api_key", AuthConfig{}, 1},
+		{"api_key", AuthConfig{JWT: JWTConfig{Enable: true, Secret: "s"}}, 1},
+		{"api_key", AuthConfig{APIKey: APIKeyConfig{Enable: true}}, 0},
+		{"user", AuthConfig{}, 1},
+		{"user", AuthConfig{JWT: JWTConfig{Enable: true, Secret: "s"}}, 0},
+		{"user", AuthConfig{APIKey: APIKeyConfig{Enable: true}}, 0},
+		{"session", AuthConfig{}, 1},
+	}
+	for _, tt := range tests {
+		var c Configuration
+		c.Auth = tt.auth
+		c.RateLimit = RateLimitConfig{Enable: true, Rules: map[string]RateLimitRule{
+			"v1": {Algorithm: "sliding_window", Limit: 1, Window: Duration{time.Second}, KeyBy: tt.keyBy},
+		}}
+		if got := problems(c, "key_by"); len(got) != tt.errors {
+			t.Errorf("key_by %q with %+v: problems %q, want %d", tt.keyBy, tt.auth, got, tt.errors)
+		}
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	var c Configuration
+	c.Server.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32", "10.0.0.0/33", "lb.local"}
+	got := problems(c, "trusted_proxies")
+	if len(got) != 2 {
+		t.Errorf("problems %q, want the bad cidr and the host name", got)
+	}
+}
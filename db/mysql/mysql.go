@@ -2,8 +2,10 @@ package mysql
 
 import (
 	"template_project/config"
+	"template_project/metrics"
 
 	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 var DB *Service
@@ -21,7 +23,7 @@ func Init() {
 
 		MaxOpenConnections: cfg.MaxOpenConnections,
 		MaxIdleConnections: cfg.MaxIdleConnections,
-		ConnMaxLifetime:    cfg.ConnMaxLifetime,
+		ConnMaxLifetime:    cfg.ConnMaxLifetime.Duration,
 		Debug:              cfg.Debug,
 		Local:              cfg.Local,
 	}
@@ -30,4 +32,6 @@ func Init() {
 		panic(err)
 	}
 	DB = db
+	// the database/sql pool stats, as go_sql_* labeled by db_name
+	metrics.MustRegister(collectors.NewDBStatsCollector(db.DB.DB(), cfg.DbName))
 }
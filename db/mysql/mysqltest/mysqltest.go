@@ -0,0 +1,47 @@
+// Package mysqltest stand a sqlite database in for mysql in the tests of the packages using mysql.DB
+package mysqltest
+
+import (
+	"template_project/db/mysql"
+	"path/filepath"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/mattn/go-sqlite3"
+)
+
+// duplicateEntry is the mysql error number of a unique index violation
+const duplicateEntry = 1062
+
+// Use set mysql.DB to an empty database for the test, with the tables of models.
+// Unique index violations are answered with the mysql error 1062, like mysql does.
+func Use(t *testing.T, models ...interface{}) *mysql.Service {
+	t.Helper()
+	db, err := gorm.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Callback().Create().After("gorm:create").Register("mysqltest:duplicate_entry", duplicateEntryError)
+	db.Callback().Update().After("gorm:update").Register("mysqltest:duplicate_entry", duplicateEntryError)
+	if err := db.CreateTable(models...).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	saved := mysql.DB
+	mysql.DB = &mysql.Service{DB: db}
+	t.Cleanup(func() {
+		mysql.DB = saved
+		db.Close()
+	})
+	return mysql.DB
+}
+
+// duplicateEntryError turn the sqlite constraint errors into the one of mysql
+func duplicateEntryError(scope *gorm.Scope) {
+	e, ok := scope.DB().Error.(sqlite3.Error)
+	if ok && (e.ExtendedCode == sqlite3.ErrConstraintUnique || e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		scope.DB().Error = &mysqldriver.MySQLError{Number: duplicateEntry, Message: e.Error()}
+	}
+}
@@ -18,7 +18,7 @@ type Config struct {
 
 	MaxOpenConnections int
 	MaxIdleConnections int
-	ConnMaxLifetime    int // unit second
+	ConnMaxLifetime    time.Duration
 	Debug              bool
 	Local              string
 }
@@ -64,10 +64,10 @@ func NewService(config Config) (*Service, error) {
 	db.DB().SetMaxIdleConns(maxIdleConns)
 
 	connMaxLifeTime := config.ConnMaxLifetime
-	if connMaxLifeTime < 30 {
-		connMaxLifeTime = 30
+	if connMaxLifeTime < 30*time.Second {
+		connMaxLifeTime = 30 * time.Second
 	}
-	db.DB().SetConnMaxLifetime(time.Duration(connMaxLifeTime) * time.Second)
+	db.DB().SetConnMaxLifetime(connMaxLifeTime)
 
 	db.LogMode(config.Debug)
 	err = db.DB().Ping()
@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// Batch queue commands to be sent to redis in a single round trip by ServiceI.Pipeline
+//
+//	b := redis.NewBatch()
+//	name := b.Queue("get", "user:1:name")
+//	visits := b.Queue("incr", "user:1:visits")
+//	if err := redis.DB.Pipeline(b); err != nil { ... }
+//	n, err := visits.Int64()
+type Batch struct {
+	// Atomic wrap the commands in MULTI/EXEC so that no other client runs in between,
+	// in cluster mode their keys must share a slot
+	Atomic bool
+
+	cmds []*Reply
+}
+
+// Reply of a queued command, it is filled once the batch has run
+type Reply struct {
+	cmd   string
+	args  []interface{}
+	value interface{}
+	err   error
+}
+
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Queue add a command to the batch and return its future reply
+func (b *Batch) Queue(cmd string, args ...interface{}) *Reply {
+	r := &Reply{cmd: cmd, args: args}
+	b.cmds = append(b.cmds, r)
+	return r
+}
+
+// Len return the number of queued commands
+func (b *Batch) Len() int {
+	return len(b.cmds)
+}
+
+// Value return the raw reply
+func (r *Reply) Value() (interface{}, error) {
+	return r.value, r.err
+}
+
+// Err return the error redis answered to this command
+func (r *Reply) Err() error {
+	return r.err
+}
+
+func (r *Reply) Bytes() ([]byte, error) {
+	return redis.Bytes(r.value, r.err)
+}
+
+func (r *Reply) String() (string, error) {
+	return redis.String(r.value, r.err)
+}
+
+func (r *Reply) Int64() (int64, error) {
+	return redis.Int64(r.value, r.err)
+}
+
+func (r *Reply) Float64() (float64, error) {
+	return redis.Float64(r.value, r.err)
+}
+
+func (r *Reply) Bool() (bool, error) {
+	return redis.Bool(r.value, r.err)
+}
+
+func (r *Reply) ByteSlices() ([][]byte, error) {
+	return redis.ByteSlices(r.value, r.err)
+}
+
+func (r *Reply) Strings() ([]string, error) {
+	return redis.Strings(r.value, r.err)
+}
+
+// -----------------batch operation-------------------
+// Pipeline send every queued command in one round trip and fill their replies.
+// The returned error is a connection failure, errors of single commands are kept in their Reply.
+// In cluster mode a batch is sent to each node in parallel, and an atomic batch
+// whose keys span slots is refused with ErrCrossSlot.
+func (service *Service) Pipeline(b *Batch) error {
+	if len(b.cmds) == 0 {
+		return nil
+	}
+	if cluster, ok := service.pool.(*clusterPool); ok {
+		if !b.Atomic {
+			return cluster.pipeline(b.cmds)
+		}
+		if !sameSlot(b.cmds) {
+			return ErrCrossSlot
+		}
+	}
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	if b.Atomic {
+		return service.exec(conn, b)
+	}
+
+	for _, r := range b.cmds {
+		if err := conn.Send(r.cmd, r.args...); err != nil {
+			return err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	for _, r := range b.cmds {
+		r.value, r.err = conn.Receive()
+		if r.err != nil {
+			if _, ok := r.err.(redis.Error); !ok {
+				return r.err
+			}
+		}
+	}
+	return nil
+}
+
+func (service *Service) exec(conn redis.Conn, b *Batch) error {
+	if err := conn.Send("multi"); err != nil {
+		return err
+	}
+	for _, r := range b.cmds {
+		if err := conn.Send(r.cmd, r.args...); err != nil {
+			return err
+		}
+	}
+	values, err := redis.Values(conn.Do("exec"))
+	if err != nil {
+		return err
+	}
+	for i, r := range b.cmds {
+		if i >= len(values) {
+			break
+		}
+		if e, ok := values[i].(redis.Error); ok {
+			r.err = e
+			continue
+		}
+		r.value = values[i]
+	}
+	return nil
+}
+
+// doWithExpire run cmd and EXPIRE key in a single MULTI/EXEC when ttl > 0,
+// so that a crash in between can not leave a key without expiry
+func doWithExpire(conn redis.Conn, key string, ttl int64, cmd string, args ...interface{}) (interface{}, error) {
+	if ttl <= 0 {
+		return conn.Do(cmd, args...)
+	}
+
+	if err := conn.Send("multi"); err != nil {
+		return nil, err
+	}
+	if err := conn.Send(cmd, args...); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("expire", key, ttl); err != nil {
+		return nil, err
+	}
+	values, err := redis.Values(conn.Do("exec"))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if e, ok := v.(redis.Error); ok {
+			return nil, e
+		}
+	}
+	return values[0], nil
+}
@@ -0,0 +1,731 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	clusterSlots = 16384
+	// clusterMaxRedirects bound the MOVED, ASK and TRYAGAIN retries of one command
+	clusterMaxRedirects = 5
+	clusterRetryWait    = 50 * time.Millisecond
+)
+
+var (
+	// ErrNoSlot is returned when no known master serves the slot of a key
+	ErrNoSlot = errors.New("redis cluster: no master serves the slot")
+	// ErrCrossSlot is returned when a transaction, or a pipeline on one connection, spans slots
+	ErrCrossSlot = errors.New("redis cluster: the keys of a transaction must share a slot, use {hash tags}")
+)
+
+// hashSlot return the cluster slot of key, honoring {hash tags}
+func hashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 is the CRC-16/XMODEM checksum redis cluster uses
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// commandKey return the key a command is routed by, false for commands that may run on any node
+func commandKey(cmd string, args []interface{}) (string, bool) {
+	switch strings.ToLower(cmd) {
+	case "", "multi", "exec", "discard", "ping", "echo", "scan", "publish", "auth", "select",
+		"asking", "cluster", "info", "script", "time", "role", "dbsize", "randomkey":
+		return "", false
+	case "eval", "evalsha":
+		// EVALSHA sha numkeys key [key ...] arg [arg ...]
+		if len(args) < 3 {
+			return "", false
+		}
+		if n, err := strconv.Atoi(argString(args[1])); err != nil || n < 1 {
+			return "", false
+		}
+		return argString(args[2]), true
+	case "xread", "xreadgroup":
+		for i, a := range args {
+			if strings.EqualFold(argString(a), "streams") && i+1 < len(args) {
+				return argString(args[i+1]), true
+			}
+		}
+		return "", false
+	case "xgroup", "xinfo", "object":
+		if len(args) < 2 {
+			return "", false
+		}
+		return argString(args[1]), true
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	return argString(args[0]), true
+}
+
+// redirect parse MOVED and ASK errors, "MOVED 3999 127.0.0.1:6381"
+func redirect(err error) (kind string, slot int, addr string, ok bool) {
+	e, isRedis := err.(redis.Error)
+	if !isRedis {
+		return "", 0, "", false
+	}
+	parts := strings.Fields(string(e))
+	if len(parts) != 3 || (parts[0] != "MOVED" && parts[0] != "ASK") {
+		return "", 0, "", false
+	}
+	slot, serr := strconv.Atoi(parts[1])
+	if serr != nil {
+		return "", 0, "", false
+	}
+	return parts[0], slot, parts[2], true
+}
+
+// clusterPool keep the slot map of the cluster and a pool per node
+type clusterPool struct {
+	seeds   []string
+	newPool func(addr string) *redis.Pool
+
+	mu     sync.RWMutex
+	slots  [clusterSlots]string
+	loaded bool
+	pools  map[string]*redis.Pool
+
+	refreshMu  sync.Mutex
+	refreshing int32
+}
+
+func newClusterPool(cfg Config) *clusterPool {
+	d := newDialer(cfg, cfg.Username, cfg.Password, 0)
+	return &clusterPool{
+		seeds: append([]string{}, cfg.ClusterAddrs...),
+		newPool: func(addr string) *redis.Pool {
+			return newPool(cfg, func() (redis.Conn, error) { return d.dial(addr) })
+		},
+		pools: make(map[string]*redis.Pool),
+	}
+}
+
+func (c *clusterPool) Get() redis.Conn {
+	return &clusterConn{cluster: c, conns: make(map[string]redis.Conn)}
+}
+
+// dial connect to a master, a message published on any node reaches the subscribers of every node
+func (c *clusterPool) dial() (redis.Conn, error) {
+	pools, err := c.masters()
+	if err != nil {
+		return nil, err
+	}
+	return pools[0].Dial()
+}
+
+func (c *clusterPool) masters() ([]*redis.Pool, error) {
+	if err := c.ensure(); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	c.mu.RUnlock()
+	if len(addrs) == 0 {
+		return nil, ErrNoSlot
+	}
+
+	sort.Strings(addrs)
+	pools := make([]*redis.Pool, 0, len(addrs))
+	for _, addr := range addrs {
+		pools = append(pools, c.pool(addr))
+	}
+	return pools, nil
+}
+
+func (c *clusterPool) opened() []*redis.Pool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	pools := make([]*redis.Pool, 0, len(c.pools))
+	for _, p := range c.pools {
+		pools = append(pools, p)
+	}
+	return pools
+}
+
+func (c *clusterPool) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for addr, p := range c.pools {
+		if cerr := p.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(c.pools, addr)
+	}
+	return err
+}
+
+func (c *clusterPool) pool(addr string) *redis.Pool {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return p
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok = c.pools[addr]; !ok {
+		p = c.newPool(addr)
+		c.pools[addr] = p
+	}
+	return p
+}
+
+// ensure load the slot map once
+func (c *clusterPool) ensure() error {
+	c.mu.RLock()
+	loaded := c.loaded
+	c.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+	return c.refresh()
+}
+
+// addr return the master serving slot
+func (c *clusterPool) addr(slot int) (string, error) {
+	if err := c.ensure(); err != nil {
+		return "", err
+	}
+	c.mu.RLock()
+	addr := c.slots[slot]
+	c.mu.RUnlock()
+	if addr == "" {
+		return "", ErrNoSlot
+	}
+	return addr, nil
+}
+
+// anyAddr return the master of a random slot, for commands without key
+func (c *clusterPool) anyAddr() (string, error) {
+	return c.addr(int(time.Now().UnixNano() % clusterSlots))
+}
+
+// moved record the new owner of slot and reload the whole map in background
+func (c *clusterPool) moved(slot int, addr string) {
+	c.mu.Lock()
+	c.slots[slot] = addr
+	c.mu.Unlock()
+
+	if atomic.CompareAndSwapInt32(&c.refreshing, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&c.refreshing, 0)
+			c.refresh()
+		}()
+	}
+}
+
+// refresh reload the slot map from the first node answering CLUSTER SLOTS
+func (c *clusterPool) refresh() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	addrs := append([]string{}, c.seeds...)
+	for addr := range c.pools {
+		addrs = append(addrs, addr)
+	}
+	c.mu.RUnlock()
+
+	var errs []string
+	tried := make(map[string]bool)
+	for _, addr := range addrs {
+		if tried[addr] {
+			continue
+		}
+		tried[addr] = true
+
+		slots, err := c.clusterSlots(addr)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", addr, err))
+			continue
+		}
+		c.mu.Lock()
+		c.slots = slots
+		c.loaded = true
+		c.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("redis cluster: can not load the slots (%s)", strings.Join(errs, "; "))
+}
+
+// clusterSlots parse [[start, end, [ip, port, id], replicas...], ...]
+func (c *clusterPool) clusterSlots(addr string) ([clusterSlots]string, error) {
+	var slots [clusterSlots]string
+	conn := c.pool(addr).Get()
+	defer conn.Close()
+
+	ranges, err := redis.Values(conn.Do("cluster", "slots"))
+	if err != nil {
+		return slots, err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	for _, r := range ranges {
+		fields, err := redis.Values(r, nil)
+		if err != nil || len(fields) < 3 {
+			return slots, fmt.Errorf("unexpected cluster slots reply %v", r)
+		}
+		start, _ := redis.Int(fields[0], nil)
+		end, _ := redis.Int(fields[1], nil)
+		master, err := redis.Values(fields[2], nil)
+		if err != nil || len(master) < 2 || start < 0 || end >= clusterSlots {
+			return slots, fmt.Errorf("unexpected cluster slots reply %v", r)
+		}
+		ip, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+		if ip == "" {
+			// an empty ip means the node we asked
+			ip = host
+		}
+		node := net.JoinHostPort(ip, strconv.Itoa(port))
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = node
+		}
+	}
+	return slots, nil
+}
+
+// sameSlot report whether the keys of cmds hash to one slot
+func sameSlot(cmds []*Reply) bool {
+	slot := -1
+	for _, r := range cmds {
+		key, ok := commandKey(r.cmd, r.args)
+		if !ok {
+			continue
+		}
+		if s := hashSlot(key); slot < 0 {
+			slot = s
+		} else if s != slot {
+			return false
+		}
+	}
+	return true
+}
+
+// retryable report whether the node refused a command without running it
+func retryable(err error) bool {
+	if _, _, _, ok := redirect(err); ok {
+		return true
+	}
+	e, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(e), "TRYAGAIN")
+}
+
+// pipeline send cmds to the master of their key, one round trip per node and the nodes in parallel,
+// then run again alone the commands a node refused with MOVED, ASK or TRYAGAIN
+func (c *clusterPool) pipeline(cmds []*Reply) error {
+	groups := make(map[string][]*Reply)
+	var addrs []string
+	for _, r := range cmds {
+		var (
+			addr string
+			err  error
+		)
+		if key, ok := commandKey(r.cmd, r.args); ok {
+			addr, err = c.addr(hashSlot(key))
+		} else {
+			addr, err = c.anyAddr()
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := groups[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+		groups[addr] = append(groups[addr], r)
+	}
+
+	errs := make([]error, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			errs[i] = c.send(addr, groups[addr])
+		}(i, addr)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	conn := c.Get()
+	defer conn.Close()
+	for _, r := range cmds {
+		if !retryable(r.err) {
+			continue
+		}
+		r.value, r.err = conn.Do(r.cmd, r.args...)
+		if r.err != nil {
+			if _, ok := r.err.(redis.Error); !ok {
+				return r.err
+			}
+		}
+	}
+	return nil
+}
+
+// send pipeline cmds to the node at addr
+func (c *clusterPool) send(addr string, cmds []*Reply) error {
+	conn := c.pool(addr).Get()
+	defer conn.Close()
+
+	for _, r := range cmds {
+		if err := conn.Send(r.cmd, r.args...); err != nil {
+			return err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	for _, r := range cmds {
+		r.value, r.err = conn.Receive()
+		if r.err == nil {
+			continue
+		}
+		if _, ok := r.err.(redis.Error); !ok {
+			return r.err
+		}
+		if kind, slot, to, ok := redirect(r.err); ok && kind == "MOVED" {
+			c.moved(slot, to)
+		}
+	}
+	return nil
+}
+
+// clusterConn route each Do to the master of its key and follow the redirections.
+// Send binds the connection to the node of the first key it sees, so a pipeline or a MULTI/EXEC
+// transaction runs on one node and Send refuses keys of another slot with ErrCrossSlot.
+// A transaction aborted because its slot moved is sent again to the new owner, nothing of it ran.
+// Service.Pipeline splits non atomic batches per node instead.
+type clusterConn struct {
+	cluster *clusterPool
+	conns   map[string]redis.Conn
+
+	bound     redis.Conn
+	boundAddr string
+	slot      int // slot of the keys sent on bound, -1 before the first key
+	queued    []queuedCommand
+	pending   []queuedCommand // sent on bound, reply not received yet
+	err       error
+}
+
+type queuedCommand struct {
+	cmd  string
+	args []interface{}
+}
+
+func (c *clusterConn) Close() error {
+	var err error
+	for addr, conn := range c.conns {
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(c.conns, addr)
+	}
+	c.bound = nil
+	c.queued, c.pending = nil, nil
+	return err
+}
+
+func (c *clusterConn) Err() error {
+	if c.bound != nil {
+		return c.bound.Err()
+	}
+	return c.err
+}
+
+// node return the connection to addr, taken from its pool on first use
+func (c *clusterConn) node(addr string) redis.Conn {
+	conn, ok := c.conns[addr]
+	if !ok || conn.Err() != nil {
+		if ok {
+			conn.Close()
+		}
+		conn = c.cluster.pool(addr).Get()
+		c.conns[addr] = conn
+	}
+	return conn
+}
+
+// bind pin the connection to the node of key, or of any master when key is empty,
+// and send the commands queued until then
+func (c *clusterConn) bind(key string, hasKey bool) error {
+	var (
+		addr string
+		err  error
+	)
+	c.slot = -1
+	if hasKey {
+		c.slot = hashSlot(key)
+		addr, err = c.cluster.addr(c.slot)
+	} else {
+		addr, err = c.cluster.anyAddr()
+	}
+	if err != nil {
+		c.err = err
+		return err
+	}
+	c.bound, c.boundAddr = c.node(addr), addr
+	queued := c.queued
+	c.queued = nil
+	for _, q := range queued {
+		if err := c.bound.Send(q.cmd, q.args...); err != nil {
+			return err
+		}
+		c.pending = append(c.pending, q)
+	}
+	return nil
+}
+
+// sameSlot check that key belongs to the slot of the bound node
+func (c *clusterConn) sameSlot(key string) error {
+	slot := hashSlot(key)
+	if c.slot == slot {
+		return nil
+	}
+	if c.slot < 0 {
+		// bound by a command without key, any slot of that node will do for the first key
+		if addr, err := c.cluster.addr(slot); err == nil && addr == c.boundAddr {
+			c.slot = slot
+			return nil
+		}
+	}
+	return ErrCrossSlot
+}
+
+func (c *clusterConn) Send(cmd string, args ...interface{}) error {
+	key, hasKey := commandKey(cmd, args)
+	if c.bound == nil {
+		if !hasKey {
+			c.queued = append(c.queued, queuedCommand{cmd, args})
+			return nil
+		}
+		if err := c.bind(key, true); err != nil {
+			return err
+		}
+	} else if hasKey {
+		if err := c.sameSlot(key); err != nil {
+			return err
+		}
+	}
+	if err := c.bound.Send(cmd, args...); err != nil {
+		return err
+	}
+	c.pending = append(c.pending, queuedCommand{cmd, args})
+	return nil
+}
+
+func (c *clusterConn) Flush() error {
+	if c.bound == nil {
+		if len(c.queued) == 0 {
+			return nil
+		}
+		if err := c.bind("", false); err != nil {
+			return err
+		}
+	}
+	return c.bound.Flush()
+}
+
+func (c *clusterConn) Receive() (interface{}, error) {
+	return c.ReceiveWithTimeout(-1)
+}
+
+// ReceiveWithTimeout use the read timeout of the connection when timeout is negative
+func (c *clusterConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	if c.bound == nil {
+		if len(c.queued) == 0 {
+			return nil, errors.New("redis cluster: receive without pending command")
+		}
+		if err := c.bind("", false); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.pending) > 0 {
+		c.pending = c.pending[1:]
+	}
+	reply, err := c.receive(c.bound, timeout)
+	if kind, slot, addr, ok := redirect(err); ok && kind == "MOVED" {
+		// the command of this reply did not run, the next ones will reach the right node
+		c.cluster.moved(slot, addr)
+	}
+	return reply, err
+}
+
+func (c *clusterConn) receive(conn redis.Conn, timeout time.Duration) (interface{}, error) {
+	if timeout < 0 {
+		return conn.Receive()
+	}
+	return redis.ReceiveWithTimeout(conn, timeout)
+}
+
+func (c *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.DoWithTimeout(-1, cmd, args...)
+}
+
+// DoWithTimeout use the read timeout of the connection when timeout is negative
+func (c *clusterConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if c.bound == nil && len(c.queued) > 0 {
+		if err := c.bind("", false); err != nil {
+			return nil, err
+		}
+	}
+	if c.bound != nil {
+		// a pipeline or a transaction is in progress on the bound node
+		return c.doBound(timeout, cmd, args)
+	}
+
+	key, hasKey := commandKey(cmd, args)
+	if cmd == "" {
+		return nil, nil
+	}
+	var (
+		addr   string
+		err    error
+		asking bool
+	)
+	for i := 0; ; i++ {
+		if addr == "" {
+			if hasKey {
+				addr, err = c.cluster.addr(hashSlot(key))
+			} else {
+				addr, err = c.cluster.anyAddr()
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		conn := c.node(addr)
+		if conn.Err() != nil && i < clusterMaxRedirects {
+			// the node is unreachable, it may have failed over, nothing was sent yet so retry is safe
+			c.cluster.refresh()
+			addr = ""
+			continue
+		}
+		if asking {
+			if err := conn.Send("asking"); err != nil {
+				return nil, err
+			}
+		}
+		reply, err := c.do(conn, timeout, cmd, args)
+		if err == nil || i >= clusterMaxRedirects {
+			return reply, err
+		}
+
+		if kind, slot, to, ok := redirect(err); ok {
+			if kind == "MOVED" {
+				c.cluster.moved(slot, to)
+			}
+			addr, asking = to, kind == "ASK"
+			continue
+		}
+		if e, ok := err.(redis.Error); ok && (strings.HasPrefix(string(e), "TRYAGAIN") || strings.HasPrefix(string(e), "CLUSTERDOWN")) {
+			time.Sleep(clusterRetryWait * time.Duration(i+1))
+			addr, asking = "", false
+			continue
+		}
+		return reply, err
+	}
+}
+
+// doBound send cmd on the bound node and read every pending reply like redigo does:
+// the last reply is returned with the first error.
+// A MULTI/EXEC refused with MOVED is sent again as a whole to the new owner of the slot.
+func (c *clusterConn) doBound(timeout time.Duration, cmd string, args []interface{}) (interface{}, error) {
+	if cmd != "" {
+		if err := c.Send(cmd, args...); err != nil {
+			return nil, err
+		}
+	}
+	sent := c.pending
+	for i := 0; ; i++ {
+		if err := c.bound.Flush(); err != nil {
+			return nil, err
+		}
+		var (
+			reply interface{}
+			err   error
+			last  error
+			moved string
+		)
+		for range sent {
+			reply, last = c.ReceiveWithTimeout(timeout)
+			if last == nil {
+				continue
+			}
+			if _, ok := last.(redis.Error); !ok {
+				return nil, last
+			}
+			if kind, _, to, ok := redirect(last); ok && kind == "MOVED" {
+				moved = to
+			}
+			if err == nil {
+				err = last
+			}
+		}
+
+		// EXECABORT: a queued command was refused, so none of the transaction ran
+		aborted := last != nil && strings.HasPrefix(last.Error(), "EXECABORT")
+		if moved == "" || !aborted || i >= clusterMaxRedirects ||
+			!strings.EqualFold(sent[0].cmd, "multi") || !strings.EqualFold(sent[len(sent)-1].cmd, "exec") {
+			return reply, err
+		}
+
+		c.bound, c.boundAddr = c.node(moved), moved
+		for _, q := range sent {
+			if err := c.bound.Send(q.cmd, q.args...); err != nil {
+				return nil, err
+			}
+		}
+		c.pending = sent
+	}
+}
+
+func (c *clusterConn) do(conn redis.Conn, timeout time.Duration, cmd string, args []interface{}) (interface{}, error) {
+	if timeout < 0 {
+		return conn.Do(cmd, args...)
+	}
+	return redis.DoWithTimeout(conn, timeout, cmd, args...)
+}
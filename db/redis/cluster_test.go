@@ -0,0 +1,405 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// fakeCluster is a redis cluster of nodes fronting a miniredis each. A node answers
+// CLUSTER SLOTS, refuses keys of different slots with CROSSSLOT and keys of a slot it
+// does not own with MOVED, or with ASK while the slot is imported by another node.
+type fakeCluster struct {
+	mu        sync.Mutex
+	nodes     []*fakeNode
+	owner     [clusterSlots]int
+	importing map[int]int // slot -> node importing it
+}
+
+type fakeNode struct {
+	cluster  *fakeCluster
+	index    int
+	listener net.Listener
+	backend  *miniredis.Miniredis
+}
+
+func newFakeCluster(t *testing.T, n int) *fakeCluster {
+	c := &fakeCluster{importing: make(map[int]int)}
+	for i := 0; i < n; i++ {
+		mr, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		node := &fakeNode{cluster: c, index: i, listener: l, backend: mr}
+		c.nodes = append(c.nodes, node)
+		go node.serve()
+	}
+	for slot := range c.owner {
+		c.owner[slot] = slot * n / clusterSlots
+	}
+	t.Cleanup(func() {
+		for _, node := range c.nodes {
+			node.listener.Close()
+			node.backend.Close()
+		}
+	})
+	return c
+}
+
+func (c *fakeCluster) addr(i int) string {
+	return c.nodes[i].listener.Addr().String()
+}
+
+// service return a Service knowing only the first node
+func (c *fakeCluster) service(t *testing.T) *Service {
+	s := &Service{}
+	s.Initialize(Config{Mode: "cluster", ClusterAddrs: []string{c.addr(0)}, MaxIdle: 2})
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// nodeOf return the node owning key
+func (c *fakeCluster) nodeOf(key string) *fakeNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodes[c.owner[hashSlot(key)]]
+}
+
+// move give the slot of key to another node, as a finished resharding does
+func (c *fakeCluster) move(key string) *fakeNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	slot := hashSlot(key)
+	c.owner[slot] = (c.owner[slot] + 1) % len(c.nodes)
+	return c.nodes[c.owner[slot]]
+}
+
+// migrate start moving the slot of key to another node
+func (c *fakeCluster) migrate(key string) *fakeNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	slot := hashSlot(key)
+	to := (c.owner[slot] + 1) % len(c.nodes)
+	c.importing[slot] = to
+	return c.nodes[to]
+}
+
+func (c *fakeCluster) slots() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ranges []interface{}
+	start := 0
+	for slot := 1; slot <= clusterSlots; slot++ {
+		if slot < clusterSlots && c.owner[slot] == c.owner[start] {
+			continue
+		}
+		_, port, _ := net.SplitHostPort(c.addr(c.owner[start]))
+		p, _ := strconv.ParseInt(port, 10, 64)
+		ranges = append(ranges, []interface{}{int64(start), int64(slot - 1),
+			[]interface{}{[]byte("127.0.0.1"), p, []byte(fmt.Sprint("node", c.owner[start]))}})
+		start = slot
+	}
+	return ranges
+}
+
+// fakeKeys return the keys of a command
+func fakeKeys(cmd string, args [][]byte) []string {
+	var keys []string
+	switch cmd {
+	case "ping", "echo", "multi", "exec", "discard", "scan", "info", "script", "time", "select", "auth":
+	case "mget", "del", "exists", "unlink":
+		for _, a := range args {
+			keys = append(keys, string(a))
+		}
+	case "mset":
+		for i := 0; i < len(args); i += 2 {
+			keys = append(keys, string(args[i]))
+		}
+	case "eval", "evalsha":
+		n, _ := strconv.Atoi(string(args[1]))
+		for i := 0; i < n; i++ {
+			keys = append(keys, string(args[2+i]))
+		}
+	default:
+		if len(args) > 0 {
+			keys = append(keys, string(args[0]))
+		}
+	}
+	return keys
+}
+
+// route return the error the node answers instead of running a command on keys
+func (n *fakeNode) route(keys []string, asking bool) redis.Error {
+	if len(keys) == 0 {
+		return ""
+	}
+	slot := hashSlot(keys[0])
+	for _, key := range keys[1:] {
+		if hashSlot(key) != slot {
+			return "CROSSSLOT Keys in request don't hash to the same slot"
+		}
+	}
+
+	c := n.cluster
+	c.mu.Lock()
+	owner := c.owner[slot]
+	importer, migrating := c.importing[slot]
+	c.mu.Unlock()
+	switch {
+	case owner == n.index:
+		if migrating && !n.backend.Exists(keys[0]) {
+			return redis.Error(fmt.Sprintf("ASK %d %s", slot, c.addr(importer)))
+		}
+		return ""
+	case migrating && importer == n.index && asking:
+		return ""
+	}
+	return redis.Error(fmt.Sprintf("MOVED %d %s", slot, c.addr(owner)))
+}
+
+func (n *fakeNode) serve() {
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			return
+		}
+		go n.handle(conn)
+	}
+}
+
+func (n *fakeNode) handle(conn net.Conn) {
+	defer conn.Close()
+	backend, err := redis.Dial("tcp", n.backend.Addr())
+	if err != nil {
+		return
+	}
+	defer backend.Close()
+
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	var multi, dirty, asking bool
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToLower(string(args[0]))
+		params := make([]interface{}, len(args)-1)
+		for i, a := range args[1:] {
+			params[i] = a
+		}
+
+		var reply interface{}
+		switch {
+		case cmd == "cluster":
+			reply = n.cluster.slots()
+		case cmd == "asking":
+			reply = "OK"
+		case cmd == "exec" && dirty:
+			backend.Do("discard")
+			reply = redis.Error("EXECABORT Transaction discarded because of previous errors.")
+		default:
+			if e := n.route(fakeKeys(cmd, args[1:]), asking); e != "" {
+				reply, dirty = e, dirty || multi
+				break
+			}
+			if reply, err = backend.Do(cmd, params...); err != nil {
+				reply = redis.Error(err.Error())
+			}
+		}
+		switch cmd {
+		case "multi":
+			multi = true
+		case "exec", "discard":
+			multi, dirty = false, false
+		}
+		asking = cmd == "asking"
+
+		writeReply(w, reply)
+		if w.Flush() != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if line[0] != '*' {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	args := make([][]byte, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = buf[:size]
+	}
+	return args, nil
+}
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case redis.Error:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case []byte:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, e := range v {
+			writeReply(w, e)
+		}
+	}
+}
+
+// spread return keys owned by at least two nodes
+func spread(c *fakeCluster, keys ...string) []string {
+	nodes := make(map[*fakeNode]bool)
+	for _, key := range keys {
+		nodes[c.nodeOf(key)] = true
+	}
+	if len(nodes) < 2 {
+		panic("the keys should live on several nodes")
+	}
+	return keys
+}
+
+func TestClusterMultiKey(t *testing.T) {
+	c := newFakeCluster(t, 3)
+	s := c.service(t)
+	keys := spread(c, "a", "b", "c", "d", "e", "f")
+
+	var kvs [][2][]byte
+	for _, key := range keys {
+		kvs = append(kvs, [2][]byte{[]byte(key), []byte("v" + key)})
+	}
+	check(t, s.Mset(kvs, 0))
+	for _, key := range keys {
+		got, _ := c.nodeOf(key).backend.Get(key)
+		equal(t, "node value of "+key, got, "v"+key)
+	}
+	equal(t, "mget", mustList(s.Mget([]string{"f", "missing", "a", "c"})), [][]byte{[]byte("vf"), nil, []byte("va"), []byte("vc")})
+
+	check(t, s.Mset(kvs[:3], 100))
+	for _, key := range keys[:3] {
+		equal(t, "ttl of "+key, c.nodeOf(key).backend.TTL(key), 100*time.Second)
+	}
+
+	check(t, s.Dels(keys))
+	equal(t, "mget after dels", mustList(s.Mget(keys[:2])), [][]byte{nil, nil})
+}
+
+func TestClusterPipelineMoved(t *testing.T) {
+	c := newFakeCluster(t, 3)
+	s := c.service(t)
+	spread(c, "a", "b")
+	check(t, s.Set("a", []byte("old"), 0))
+
+	// the client still routes a to its former owner, which answers MOVED
+	to := c.move("a")
+	b := NewBatch()
+	setA := b.Queue("set", "a", "1")
+	setB := b.Queue("set", "b", "2")
+	getA := b.Queue("get", "a")
+	check(t, s.Pipeline(b))
+	check(t, setA.Err())
+	check(t, setB.Err())
+	equal(t, "get a", string(mustBytes(getA.Bytes())), "1")
+
+	got, _ := to.backend.Get("a")
+	equal(t, "value on the new owner", got, "1")
+}
+
+func TestClusterPipelineAsk(t *testing.T) {
+	c := newFakeCluster(t, 3)
+	s := c.service(t)
+	check(t, s.Set("b", []byte("2"), 0))
+
+	to := c.migrate("a")
+	b := NewBatch()
+	setA := b.Queue("set", "a", "1")
+	getB := b.Queue("get", "b")
+	check(t, s.Pipeline(b))
+	check(t, setA.Err())
+	equal(t, "get b", string(mustBytes(getB.Bytes())), "2")
+
+	got, _ := to.backend.Get("a")
+	equal(t, "value on the importing node", got, "1")
+
+	check(t, s.Set("a", []byte("3"), 0))
+	got, _ = to.backend.Get("a")
+	equal(t, "value set by Do", got, "3")
+}
+
+func TestClusterTransaction(t *testing.T) {
+	c := newFakeCluster(t, 3)
+	s := c.service(t)
+	spread(c, "a", "b")
+
+	b := &Batch{Atomic: true}
+	b.Queue("set", "a", "1")
+	b.Queue("set", "b", "2")
+	if err := s.Pipeline(b); err != ErrCrossSlot {
+		t.Fatalf("cross slot transaction error = %v, want ErrCrossSlot", err)
+	}
+
+	conn := s.pool.Get()
+	check(t, conn.Send("multi"))
+	check(t, conn.Send("set", "a", "1"))
+	if err := conn.Send("set", "b", "2"); err != ErrCrossSlot {
+		t.Fatalf("cross slot send error = %v, want ErrCrossSlot", err)
+	}
+	conn.Close()
+
+	b = &Batch{Atomic: true}
+	b.Queue("set", "{user:1}:name", "ann")
+	incr := b.Queue("incr", "{user:1}:visits")
+	check(t, s.Pipeline(b))
+	equal(t, "incr", mustInt(incr.Int64()), int64(1))
+
+	// the slot moved since the client loaded the map: the aborted transaction is sent again
+	to := c.move("{user:1}")
+	b = &Batch{Atomic: true}
+	b.Queue("set", "{user:1}:name", "bob")
+	incr = b.Queue("incr", "{user:1}:visits")
+	check(t, s.Pipeline(b))
+	equal(t, "incr on the new owner", mustInt(incr.Int64()), int64(1))
+	got, _ := to.backend.Get("{user:1}:name")
+	equal(t, "name on the new owner", got, "bob")
+
+	to = c.move("s")
+	check(t, s.SAdd("s", 100, []byte("x")))
+	equal(t, "ttl with expire", to.backend.TTL("s"), 100*time.Second)
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// connPool is where Service takes its connections from,
+// a single redis.Pool in standalone and sentinel mode and one pool per master in cluster mode
+type connPool interface {
+	// Get return a connection, in cluster mode it routes every command to the master of its key
+	Get() redis.Conn
+	// dial open a connection outside of the pool, used by subscriptions
+	dial() (redis.Conn, error)
+	// masters return the pool of every master, for commands like SCAN that run per node
+	masters() ([]*redis.Pool, error)
+	// opened return the pools opened so far, without connecting
+	opened() []*redis.Pool
+	Close() error
+}
+
+// singlePool serve every command from one pool
+type singlePool struct {
+	*redis.Pool
+	onClose func()
+}
+
+func (p *singlePool) dial() (redis.Conn, error) {
+	return p.Pool.Dial()
+}
+
+func (p *singlePool) masters() ([]*redis.Pool, error) {
+	return []*redis.Pool{p.Pool}, nil
+}
+
+func (p *singlePool) opened() []*redis.Pool {
+	return []*redis.Pool{p.Pool}
+}
+
+func (p *singlePool) Close() error {
+	if p.onClose != nil {
+		p.onClose()
+	}
+	return p.Pool.Close()
+}
+
+// NewTLSConfig build the client tls config of redis connections, caFile defaults to the system roots
+func NewTLSConfig(caFile, certFile, keyFile, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls ca file: %v", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis tls ca file %s: no certificate found", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// dialer open authenticated connections to any node with the options of Config
+type dialer struct {
+	username, password string
+	db                 int
+	options            []redis.DialOption
+}
+
+func newDialer(cfg Config, username, password string, db int) *dialer {
+	options := []redis.DialOption{
+		redis.DialConnectTimeout(cfg.DialTimeout),
+		redis.DialReadTimeout(cfg.ReadTimeout),
+		redis.DialWriteTimeout(cfg.WriteTimeout),
+		redis.DialKeepAlive(5 * time.Minute),
+	}
+	if cfg.TLS != nil {
+		options = append(options, redis.DialUseTLS(true), redis.DialTLSConfig(cfg.TLS))
+	}
+	return &dialer{username: username, password: password, db: db, options: options}
+}
+
+// dial connect to addr, then AUTH and SELECT as configured.
+// AUTH is sent by hand since this redigo version has no ACL username option.
+func (d *dialer) dial(addr string) (redis.Conn, error) {
+	c, err := redis.Dial("tcp", addr, d.options...)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case d.username != "":
+		_, err = c.Do("auth", d.username, d.password)
+	case d.password != "":
+		_, err = c.Do("auth", d.password)
+	}
+	if err == nil && d.db != 0 {
+		_, err = c.Do("select", d.db)
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &instrumentedConn{Conn: c}, nil
+}
+
+// newPool build a pool dialing with dial and the pool settings of cfg
+func newPool(cfg Config, dial func() (redis.Conn, error)) *redis.Pool {
+	return &redis.Pool{
+		IdleTimeout: cfg.IdleTimeout,
+		MaxIdle:     cfg.MaxIdle,
+		MaxActive:   cfg.MaxActive,
+		Wait:        true,
+		Dial:        dial,
+	}
+}
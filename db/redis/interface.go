@@ -1,5 +1,11 @@
 package redis
 
+import (
+	"context"
+	"time"
+)
+
+// ServiceI is implemented by the redis backed Service and by the in-memory MemoryService
 type ServiceI interface {
 	// string
 	Set(key string, value []byte, ttl int64) error
@@ -11,13 +17,20 @@ type ServiceI interface {
 	Dels(keys []string) error
 	Keys(keyFormat string) ([][]byte, error)
 
+	// scan
+	Scan(ctx context.Context, opts ScanOptions, fn ScanFunc) error
+	SScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error
+	HScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error
+	ZScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error
+	DeleteByPattern(ctx context.Context, pattern string, count int64) (int64, error)
+
 	// set
 	SAdd(key string, ttl int64, members ...[]byte) error
 	SRem(key string, members ...[]byte) (int64, error)
 	SCard(key string) (int64, error)
 	SIsMember(key string, member []byte) (bool, error)
 	SMembers(key string) ([][]byte, error)
-	SetNX(key string, value int64, ttl int) (bool, error)
+	SetNX(key string, value int64, ttl int64) (bool, error)
 	// zset
 	ZAdd(key string, ttl int64, args ...[]byte) error
 	ZRem(key string, args ...[]byte) (int64, error)
@@ -27,6 +40,7 @@ type ServiceI interface {
 	ZRange(key string, start, stop int64, withScores bool) ([][]byte, error)
 	ZRangeByScore(key string, min, max interface{}, withScores bool) ([][]byte, error)
 	ZRevRange(key string, start, stop int64, withScores bool) ([][]byte, error)
+	ZRemRangeByScore(key string, start, stop int64) (int64, error)
 
 	// hash
 	HSet(key string, ttl int64, field string, value []byte) error
@@ -47,4 +61,25 @@ type ServiceI interface {
 	LLpop(key string) ([]byte, error)
 	LIndex(key string, index int64) ([]byte, error)
 	LLlen(key string) (int64, error)
+
+	// lock
+	SetNXBytes(key string, value []byte, ttl time.Duration) (bool, error)
+	CompareAndDelete(key string, value []byte) (bool, error)
+	CompareAndExpire(key string, value []byte, ttl time.Duration) (bool, error)
+
+	// rate limit
+	SlidingWindow(key string, limit int64, window time.Duration) (RateLimitResult, error)
+	TokenBucket(key string, capacity int64, refill time.Duration) (RateLimitResult, error)
+
+	// batch
+	Pipeline(b *Batch) error
+
+	PubSubI
+	StreamI
+
+	// Ping check that redis answers, every master in cluster mode
+	Ping(ctx context.Context) error
+
+	// Close release the underlying connections
+	Close() error
 }
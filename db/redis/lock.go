@@ -0,0 +1,238 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	mrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	defaultLockRetryMin = 10 * time.Millisecond
+	defaultLockRetryMax = 500 * time.Millisecond
+)
+
+var (
+	// ErrLockNotHeld is returned when releasing or extending a lock owned by someone else or expired
+	ErrLockNotHeld = errors.New("redis lock is not held")
+	// ErrLockTTL is returned for a ttl redis can not store, it counts in milliseconds
+	ErrLockTTL = errors.New("redis lock ttl must be at least 1ms")
+)
+
+var (
+	compareAndDeleteScript = redis.NewScript(1, `
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+	compareAndExpireScript = redis.NewScript(1, `
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// -----------------lock operation--------------------
+// SetNXBytes set key to value with ttl only when key does not exist and report whether it was set
+func (service *Service) SetNXBytes(key string, value []byte, ttl time.Duration) (bool, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	reply, err := conn.Do("set", key, value, "px", ttl.Nanoseconds()/int64(time.Millisecond), "nx")
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+// CompareAndDelete delete key only when it holds value
+func (service *Service) CompareAndDelete(key string, value []byte) (bool, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	n, err := redis.Int64(compareAndDeleteScript.Do(conn, key, value))
+	return n == 1, err
+}
+
+// CompareAndExpire reset the ttl of key only when it holds value
+func (service *Service) CompareAndExpire(key string, value []byte, ttl time.Duration) (bool, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	n, err := redis.Int64(compareAndExpireScript.Do(conn, key, value, ttl.Nanoseconds()/int64(time.Millisecond)))
+	return n == 1, err
+}
+
+// Lock is a distributed mutex stored in a redis key.
+// The key holds a random owner token so that only the owner can release or extend it.
+type Lock struct {
+	// RetryMin and RetryMax bound the backoff between two attempts of Acquire
+	RetryMin time.Duration
+	RetryMax time.Duration
+
+	service ServiceI
+	key     string
+	token   []byte
+	ttl     time.Duration
+
+	mu   sync.Mutex
+	stop chan struct{}
+	lost chan struct{}
+}
+
+// NewLock build a lock on key expiring after ttl unless extended, it is not acquired yet
+func NewLock(service ServiceI, key string, ttl time.Duration) (*Lock, error) {
+	if ttl < time.Millisecond {
+		return nil, ErrLockTTL
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return &Lock{
+		RetryMin: defaultLockRetryMin,
+		RetryMax: defaultLockRetryMax,
+		service:  service,
+		key:      key,
+		token:    []byte(hex.EncodeToString(token)),
+		ttl:      ttl,
+	}, nil
+}
+
+// Key return the redis key of the lock
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token return the owner token stored in the key
+func (l *Lock) Token() string {
+	return string(l.token)
+}
+
+// TryAcquire take the lock once without waiting
+func (l *Lock) TryAcquire() (bool, error) {
+	return l.service.SetNXBytes(l.key, l.token, l.ttl)
+}
+
+// Acquire wait for the lock with a jittered exponential backoff until ctx is done
+func (l *Lock) Acquire(ctx context.Context) error {
+	wait := l.RetryMin
+	for {
+		ok, err := l.TryAcquire()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		// sleep between wait/2 and wait so that waiters do not retry in step
+		sleep := wait/2 + time.Duration(mrand.Int63n(int64(wait/2)+1))
+		timer := time.NewTimer(sleep)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if wait *= 2; wait > l.RetryMax {
+			wait = l.RetryMax
+		}
+	}
+}
+
+// Extend reset the ttl of a held lock
+func (l *Lock) Extend(ttl time.Duration) error {
+	ok, err := l.service.CompareAndExpire(l.key, l.token, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release free a held lock and stop its watchdog
+func (l *Lock) Release() error {
+	l.mu.Lock()
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.mu.Unlock()
+
+	ok, err := l.service.CompareAndDelete(l.key, l.token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// StartWatchdog renew the lock every third of its ttl until Release.
+// The returned channel is closed if the lock could not be renewed and may be lost.
+func (l *Lock) StartWatchdog() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stop != nil {
+		return l.lost
+	}
+
+	stop, lost := make(chan struct{}), make(chan struct{})
+	l.stop, l.lost = stop, lost
+	go func() {
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := l.Extend(l.ttl); err != nil {
+					close(lost)
+					return
+				}
+			}
+		}
+	}()
+	return lost
+}
+
+// WithLock run fn while holding the lock on key, renewed by a watchdog.
+// The context given to fn is cancelled if the lock is lost.
+func WithLock(ctx context.Context, service ServiceI, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	l, err := NewLock(service, key, ttl)
+	if err != nil {
+		return err
+	}
+	if err := l.Acquire(ctx); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lost := l.StartWatchdog()
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err = fn(ctx)
+	if rerr := l.Release(); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLock(t *testing.T, service ServiceI, ttl time.Duration) *Lock {
+	t.Helper()
+	l, err := NewLock(service, "lock:job", ttl)
+	check(t, err)
+	return l
+}
+
+func TestNewLockTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second, time.Microsecond, time.Millisecond - 1} {
+		if _, err := NewLock(NewMemoryService(), "lock:job", ttl); err != ErrLockTTL {
+			t.Errorf("NewLock(%v) error = %v, want ErrLockTTL", ttl, err)
+		}
+	}
+	if _, err := NewLock(NewMemoryService(), "lock:job", time.Millisecond); err != nil {
+		t.Errorf("NewLock(1ms) error = %v", err)
+	}
+}
+
+func TestLockAcquireRelease(t *testing.T) {
+	b := newMemoryBackend()
+	first := newTestLock(t, b.service, time.Minute)
+	second := newTestLock(t, b.service, time.Minute)
+	second.RetryMin, second.RetryMax = time.Millisecond, 2*time.Millisecond
+
+	check(t, first.Acquire(context.Background()))
+	equal(t, "held token", string(mustBytes(b.service.Get("lock:job"))), first.Token())
+	equal(t, "second try", mustBool(second.TryAcquire()), false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := second.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Acquire of a held lock error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// a release by another owner must leave the lock alone
+	if err := second.Release(); err != ErrLockNotHeld {
+		t.Fatalf("Release with the wrong token error = %v, want ErrLockNotHeld", err)
+	}
+	equal(t, "token after wrong release", string(mustBytes(b.service.Get("lock:job"))), first.Token())
+
+	check(t, first.Release())
+	equal(t, "exists after release", mustBool(b.service.Exists("lock:job")), false)
+	check(t, second.Acquire(context.Background()))
+	if err := first.Release(); err != ErrLockNotHeld {
+		t.Fatalf("second Release error = %v, want ErrLockNotHeld", err)
+	}
+}
+
+func TestLockExtend(t *testing.T) {
+	b := newMemoryBackend()
+	l := newTestLock(t, b.service, 10*time.Second)
+	check(t, l.Acquire(context.Background()))
+
+	b.advance(8 * time.Second)
+	check(t, l.Extend(10*time.Second))
+	b.advance(8 * time.Second)
+	equal(t, "held after extend", mustBool(b.service.Exists("lock:job")), true)
+
+	b.advance(3 * time.Second)
+	if err := l.Extend(10 * time.Second); err != ErrLockNotHeld {
+		t.Fatalf("Extend of an expired lock error = %v, want ErrLockNotHeld", err)
+	}
+	other := newTestLock(t, b.service, 10*time.Second)
+	equal(t, "acquire after expiry", mustBool(other.TryAcquire()), true)
+	if err := l.Extend(10 * time.Second); err != ErrLockNotHeld {
+		t.Fatalf("Extend of a lock taken over error = %v, want ErrLockNotHeld", err)
+	}
+}
+
+func TestLockWatchdog(t *testing.T) {
+	m := NewMemoryService()
+	l := newTestLock(t, m, 30*time.Millisecond)
+	check(t, l.Acquire(context.Background()))
+
+	lost := l.StartWatchdog()
+	time.Sleep(100 * time.Millisecond)
+	equal(t, "held past its ttl", mustBool(m.Exists("lock:job")), true)
+	select {
+	case <-lost:
+		t.Fatal("watchdog lost a held lock")
+	default:
+	}
+
+	check(t, m.Del("lock:job"))
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not report the lost lock")
+	}
+}
+
+func TestLockWatchdogStopsOnRelease(t *testing.T) {
+	m := NewMemoryService()
+	l := newTestLock(t, m, 30*time.Millisecond)
+	check(t, l.Acquire(context.Background()))
+	lost := l.StartWatchdog()
+	check(t, l.Release())
+
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-lost:
+		t.Fatal("watchdog kept running after Release")
+	default:
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	m := NewMemoryService()
+	fail := errors.New("job failed")
+	err := WithLock(context.Background(), m, "lock:job", time.Second, func(ctx context.Context) error {
+		equal(t, "held in fn", mustBool(m.Exists("lock:job")), true)
+		return fail
+	})
+	if err != fail {
+		t.Fatalf("WithLock error = %v, want the error of fn", err)
+	}
+	equal(t, "exists after WithLock", mustBool(m.Exists("lock:job")), false)
+
+	if err := WithLock(context.Background(), m, "lock:job", 0, nil); err != ErrLockTTL {
+		t.Fatalf("WithLock(0) error = %v, want ErrLockTTL", err)
+	}
+}
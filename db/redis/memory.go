@@ -0,0 +1,1025 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ ServiceI = (*MemoryService)(nil)
+
+// ErrWrongType is returned when a key holds a value of another type, like redis WRONGTYPE
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+type memEntry struct {
+	// one of []byte, memSet, memZSet, memHash, memList or *memStream
+	value    interface{}
+	expireAt time.Time
+}
+
+type (
+	memSet  map[string]struct{}
+	memZSet map[string]float64
+	memHash map[string][]byte
+	memList [][]byte
+)
+
+// MemoryService is a pure go ServiceI keeping the data in process memory.
+// It mirrors the replies of Service, including the expiry of keys,
+// so that code depending on ServiceI can be tested without a redis server.
+type MemoryService struct {
+	mu   sync.Mutex
+	data map[string]*memEntry
+	now  func() time.Time
+
+	// subs are the live subscriptions, guarded by mu
+	subs map[*memSubscription]struct{}
+	// added is closed and replaced whenever an entry is appended to a stream
+	added chan struct{}
+	// seq make the members of the rate limit windows unique
+	seq uint64
+}
+
+func NewMemoryService() *MemoryService {
+	return &MemoryService{
+		data:  make(map[string]*memEntry),
+		now:   time.Now,
+		subs:  make(map[*memSubscription]struct{}),
+		added: make(chan struct{}),
+	}
+}
+
+// Ping implements ServiceI, memory always answers
+func (m *MemoryService) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Close implements ServiceI, the data is kept
+func (m *MemoryService) Close() error {
+	return nil
+}
+
+// entry return the live entry of key, expired entries are removed
+func (m *MemoryService) entry(key string) *memEntry {
+	e, ok := m.data[key]
+	if !ok {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !m.now().Before(e.expireAt) {
+		delete(m.data, key)
+		return nil
+	}
+	return e
+}
+
+func (m *MemoryService) expire(key string, ttl int64) {
+	if e := m.entry(key); e != nil && ttl > 0 {
+		e.expireAt = m.now().Add(time.Duration(ttl) * time.Second)
+	}
+}
+
+// drop remove key once its collection is empty, as redis does
+func (m *MemoryService) drop(key string, size int) {
+	if size == 0 {
+		delete(m.data, key)
+	}
+}
+
+func (m *MemoryService) getString(key string) ([]byte, bool, error) {
+	e := m.entry(key)
+	if e == nil {
+		return nil, false, nil
+	}
+	v, ok := e.value.([]byte)
+	if !ok {
+		return nil, false, ErrWrongType
+	}
+	return v, true, nil
+}
+
+func (m *MemoryService) getSet(key string, create bool) (memSet, error) {
+	e := m.entry(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &memEntry{value: memSet{}}
+		m.data[key] = e
+	}
+	v, ok := e.value.(memSet)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return v, nil
+}
+
+func (m *MemoryService) getZSet(key string, create bool) (memZSet, error) {
+	e := m.entry(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &memEntry{value: memZSet{}}
+		m.data[key] = e
+	}
+	v, ok := e.value.(memZSet)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return v, nil
+}
+
+func (m *MemoryService) getHash(key string, create bool) (memHash, error) {
+	e := m.entry(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &memEntry{value: memHash{}}
+		m.data[key] = e
+	}
+	v, ok := e.value.(memHash)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return v, nil
+}
+
+func (m *MemoryService) getList(key string) (memList, bool, error) {
+	e := m.entry(key)
+	if e == nil {
+		return nil, false, nil
+	}
+	v, ok := e.value.(memList)
+	if !ok {
+		return nil, false, ErrWrongType
+	}
+	return v, true, nil
+}
+
+func (m *MemoryService) setList(key string, list memList) {
+	if len(list) == 0 {
+		delete(m.data, key)
+		return
+	}
+	if e := m.entry(key); e != nil {
+		e.value = list
+		return
+	}
+	m.data[key] = &memEntry{value: list}
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
+
+// -----------------string operation------------------
+func (m *MemoryService) Set(key string, value []byte, ttl int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[key] = &memEntry{value: copyBytes(value)}
+	m.expire(key, ttl)
+	return nil
+}
+
+func (m *MemoryService) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok, err := m.getString(key)
+	if err != nil || !ok {
+		return []byte{}, err
+	}
+	return copyBytes(v), nil
+}
+
+func (m *MemoryService) Mset(keyvalues [][2][]byte, ttl int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, kv := range keyvalues {
+		key := string(kv[0])
+		m.data[key] = &memEntry{value: copyBytes(kv[1])}
+		m.expire(key, ttl)
+	}
+	return nil
+}
+
+func (m *MemoryService) Mget(keys []string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := [][]byte{}
+	for _, key := range keys {
+		v, ok, err := m.getString(key)
+		if err != nil || !ok {
+			// redis answers nil for keys of another type too
+			res = append(res, nil)
+			continue
+		}
+		res = append(res, copyBytes(v))
+	}
+	return res, nil
+}
+
+func (m *MemoryService) Exists(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.entry(key) != nil, nil
+}
+
+func (m *MemoryService) Del(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data, key)
+	return nil
+}
+
+func (m *MemoryService) Dels(keys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.data, key)
+	}
+	return nil
+}
+
+func (m *MemoryService) Keys(keyFormat string) ([][]byte, error) {
+	return collectKeys(func(fn ScanFunc) error {
+		return m.Scan(context.Background(), ScanOptions{Match: keyFormat}, fn)
+	})
+}
+
+func (m *MemoryService) SetNX(key string, value int64, ttl int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entry(key) != nil {
+		return false, nil
+	}
+	m.data[key] = &memEntry{value: []byte(strconv.FormatInt(value, 10))}
+	m.expire(key, ttl)
+	return true, nil
+}
+
+// -----------------set operation---------------------
+func (m *MemoryService) SAdd(key string, ttl int64, members ...[]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, err := m.getSet(key, true)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		set[string(member)] = struct{}{}
+	}
+	m.expire(key, ttl)
+	return nil
+}
+
+func (m *MemoryService) SRem(key string, members ...[]byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, err := m.getSet(key, false)
+	if err != nil || set == nil {
+		return 0, err
+	}
+	var n int64
+	for _, member := range members {
+		if _, ok := set[string(member)]; ok {
+			delete(set, string(member))
+			n++
+		}
+	}
+	m.drop(key, len(set))
+	return n, nil
+}
+
+func (m *MemoryService) SCard(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, err := m.getSet(key, false)
+	return int64(len(set)), err
+}
+
+func (m *MemoryService) SIsMember(key string, member []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, err := m.getSet(key, false)
+	if err != nil {
+		return false, err
+	}
+	_, ok := set[string(member)]
+	return ok, nil
+}
+
+// SMembers return the members sorted, redis does not define an order
+func (m *MemoryService) SMembers(key string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, err := m.getSet(key, false)
+	res := [][]byte{}
+	if err != nil {
+		return res, err
+	}
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	for _, member := range members {
+		res = append(res, []byte(member))
+	}
+	return res, nil
+}
+
+// -----------------zset operation-------------------
+type zMember struct {
+	member string
+	score  float64
+}
+
+// sorted return the members ordered by score then member, as redis does
+func (z memZSet) sorted() []zMember {
+	list := make([]zMember, 0, len(z))
+	for member, score := range z {
+		list = append(list, zMember{member, score})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].score != list[j].score {
+			return list[i].score < list[j].score
+		}
+		return list[i].member < list[j].member
+	})
+	return list
+}
+
+func formatScore(score float64) []byte {
+	return []byte(strconv.FormatFloat(score, 'f', -1, 64))
+}
+
+func zReply(list []zMember, withScores bool) [][]byte {
+	res := [][]byte{}
+	for _, z := range list {
+		res = append(res, []byte(z.member))
+		if withScores {
+			res = append(res, formatScore(z.score))
+		}
+	}
+	return res
+}
+
+// rangeIndexes convert redis start/stop indexes, negative counting from the end, to a slice range
+func rangeIndexes(start, stop int64, size int) (int, int, bool) {
+	n := int64(size)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return 0, 0, false
+	}
+	return int(start), int(stop) + 1, true
+}
+
+// scoreBound parse a ZRANGEBYSCORE bound such as 1, "1.5", "(2", "-inf" or "+inf"
+func scoreBound(v interface{}) (float64, bool, error) {
+	var s string
+	switch b := v.(type) {
+	case []byte:
+		s = string(b)
+	default:
+		s = fmt.Sprint(b)
+	}
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+	switch strings.ToLower(s) {
+	case "-inf":
+		return math.Inf(-1), exclusive, nil
+	case "+inf", "inf":
+		return math.Inf(1), exclusive, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, errors.New("ERR min or max is not a float")
+	}
+	return f, exclusive, nil
+}
+
+func (m *MemoryService) ZAdd(key string, ttl int64, args ...[]byte) error {
+	if len(args)%2 != 0 {
+		return errors.New("the length of `args` must be even")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scores := make([]float64, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		score, err := strconv.ParseFloat(string(args[i]), 64)
+		if err != nil {
+			return errors.New("ERR value is not a valid float")
+		}
+		scores = append(scores, score)
+	}
+
+	z, err := m.getZSet(key, true)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(args); i += 2 {
+		z[string(args[i+1])] = scores[i/2]
+	}
+	m.expire(key, ttl)
+	return nil
+}
+
+func (m *MemoryService) ZRem(key string, args ...[]byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z, err := m.getZSet(key, false)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	var n int64
+	for _, member := range args {
+		if _, ok := z[string(member)]; ok {
+			delete(z, string(member))
+			n++
+		}
+	}
+	m.drop(key, len(z))
+	return n, nil
+}
+
+func (m *MemoryService) ZCard(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z, err := m.getZSet(key, false)
+	return int64(len(z)), err
+}
+
+func (m *MemoryService) zRank(key string, member []byte, reverse bool) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z, err := m.getZSet(key, false)
+	if err != nil {
+		return -1, err
+	}
+	list := z.sorted()
+	for i, item := range list {
+		if item.member == string(member) {
+			if reverse {
+				return int64(len(list) - 1 - i), nil
+			}
+			return int64(i), nil
+		}
+	}
+	return -1, nil
+}
+
+func (m *MemoryService) ZRank(key string, member []byte) (int64, error) {
+	return m.zRank(key, member, false)
+}
+
+func (m *MemoryService) ZRevRank(key string, member []byte) (int64, error) {
+	return m.zRank(key, member, true)
+}
+
+func (m *MemoryService) zRange(key string, start, stop int64, withScores, reverse bool) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z, err := m.getZSet(key, false)
+	if err != nil {
+		return [][]byte{}, err
+	}
+	list := z.sorted()
+	if reverse {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+	from, to, ok := rangeIndexes(start, stop, len(list))
+	if !ok {
+		return [][]byte{}, nil
+	}
+	return zReply(list[from:to], withScores), nil
+}
+
+func (m *MemoryService) ZRange(key string, start, stop int64, withScores bool) ([][]byte, error) {
+	return m.zRange(key, start, stop, withScores, false)
+}
+
+func (m *MemoryService) ZRevRange(key string, start, stop int64, withScores bool) ([][]byte, error) {
+	return m.zRange(key, start, stop, withScores, true)
+}
+
+func (m *MemoryService) ZRangeByScore(key string, min, max interface{}, withScores bool) ([][]byte, error) {
+	lo, loExclusive, err := scoreBound(min)
+	if err != nil {
+		return [][]byte{}, err
+	}
+	hi, hiExclusive, err := scoreBound(max)
+	if err != nil {
+		return [][]byte{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z, err := m.getZSet(key, false)
+	if err != nil {
+		return [][]byte{}, err
+	}
+	var list []zMember
+	for _, item := range z.sorted() {
+		if item.score < lo || (loExclusive && item.score == lo) {
+			continue
+		}
+		if item.score > hi || (hiExclusive && item.score == hi) {
+			continue
+		}
+		list = append(list, item)
+	}
+	return zReply(list, withScores), nil
+}
+
+func (m *MemoryService) ZRemRangeByScore(key string, start, stop int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z, err := m.getZSet(key, false)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	var n int64
+	for member, score := range z {
+		if score >= float64(start) && score <= float64(stop) {
+			delete(z, member)
+			n++
+		}
+	}
+	m.drop(key, len(z))
+	return n, nil
+}
+
+// -----------------hash operation-------------------
+func (m *MemoryService) HSet(key string, ttl int64, field string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, true)
+	if err != nil {
+		return err
+	}
+	h[field] = copyBytes(value)
+	m.expire(key, ttl)
+	return nil
+}
+
+func (m *MemoryService) HGet(key string, field []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	if err != nil {
+		return []byte{}, err
+	}
+	v, ok := h[string(field)]
+	if !ok {
+		return []byte{}, nil
+	}
+	return copyBytes(v), nil
+}
+
+func (m *MemoryService) HMSet(key string, ttl int64, args ...[]byte) error {
+	if len(args)%2 != 0 {
+		return errors.New("the length of `args` must be even")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, true)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(args); i += 2 {
+		h[string(args[i])] = copyBytes(args[i+1])
+	}
+	m.expire(key, ttl)
+	return nil
+}
+
+func (m *MemoryService) HMGet(key string, fields ...[]byte) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	res := [][]byte{}
+	if err != nil {
+		return res, err
+	}
+	for _, field := range fields {
+		v, ok := h[string(field)]
+		if !ok {
+			res = append(res, []byte{})
+			continue
+		}
+		res = append(res, copyBytes(v))
+	}
+	return res, nil
+}
+
+func (m *MemoryService) HDel(key string, fields ...[]byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	if err != nil || h == nil {
+		return 0, err
+	}
+	var n int64
+	for _, field := range fields {
+		if _, ok := h[string(field)]; ok {
+			delete(h, string(field))
+			n++
+		}
+	}
+	m.drop(key, len(h))
+	return n, nil
+}
+
+func (m *MemoryService) HExists(key string, field []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	if err != nil {
+		return false, err
+	}
+	_, ok := h[string(field)]
+	return ok, nil
+}
+
+// sortedFields return the fields of h sorted, redis does not define an order
+func (h memHash) sortedFields() []string {
+	fields := make([]string, 0, len(h))
+	for field := range h {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (m *MemoryService) HKeys(key string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	res := [][]byte{}
+	if err != nil {
+		return res, err
+	}
+	for _, field := range h.sortedFields() {
+		res = append(res, []byte(field))
+	}
+	return res, nil
+}
+
+func (m *MemoryService) HVals(key string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	res := [][]byte{}
+	if err != nil {
+		return res, err
+	}
+	for _, field := range h.sortedFields() {
+		res = append(res, copyBytes(h[field]))
+	}
+	return res, nil
+}
+
+func (m *MemoryService) HGetAll(key string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	res := [][]byte{}
+	if err != nil {
+		return res, err
+	}
+	for _, field := range h.sortedFields() {
+		res = append(res, []byte(field), copyBytes(h[field]))
+	}
+	return res, nil
+}
+
+func (m *MemoryService) HLen(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, false)
+	return int64(len(h)), err
+}
+
+// -----------------list operation--------------------
+// the list helpers keep the command mapping of Service: LRpush runs LPUSH and LLpush runs RPUSH
+func (m *MemoryService) push(key string, left bool, args [][]byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, _, err := m.getList(key)
+	if err != nil {
+		return 0, err
+	}
+	for _, v := range args {
+		if left {
+			list = append(memList{copyBytes(v)}, list...)
+		} else {
+			list = append(list, copyBytes(v))
+		}
+	}
+	m.setList(key, list)
+	return int64(len(list)), nil
+}
+
+func (m *MemoryService) pop(key string, left bool) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, ok, err := m.getList(key)
+	if err != nil || !ok {
+		return []byte{}, err
+	}
+	var v []byte
+	if left {
+		v, list = list[0], list[1:]
+	} else {
+		v, list = list[len(list)-1], list[:len(list)-1]
+	}
+	m.setList(key, list)
+	return v, nil
+}
+
+func (m *MemoryService) LRpush(key string, args ...[]byte) (int64, error) {
+	return m.push(key, true, args)
+}
+
+func (m *MemoryService) LLpush(key string, args ...[]byte) (int64, error) {
+	return m.push(key, false, args)
+}
+
+func (m *MemoryService) LRpop(key string) ([]byte, error) {
+	return m.pop(key, false)
+}
+
+func (m *MemoryService) LLpop(key string) ([]byte, error) {
+	return m.pop(key, true)
+}
+
+func (m *MemoryService) LIndex(key string, index int64) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, _, err := m.getList(key)
+	if err != nil {
+		return []byte{}, err
+	}
+	if index < 0 {
+		index += int64(len(list))
+	}
+	if index < 0 || index >= int64(len(list)) {
+		return []byte{}, nil
+	}
+	return copyBytes(list[index]), nil
+}
+
+func (m *MemoryService) LLlen(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, _, err := m.getList(key)
+	return int64(len(list)), err
+}
+
+// -----------------lock operation--------------------
+func (m *MemoryService) SetNXBytes(key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entry(key) != nil {
+		return false, nil
+	}
+	m.data[key] = &memEntry{value: copyBytes(value), expireAt: m.now().Add(ttl)}
+	return true, nil
+}
+
+func (m *MemoryService) CompareAndDelete(key string, value []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok, _ := m.getString(key)
+	if !ok || string(v) != string(value) {
+		return false, nil
+	}
+	delete(m.data, key)
+	return true, nil
+}
+
+func (m *MemoryService) CompareAndExpire(key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok, _ := m.getString(key)
+	if !ok || string(v) != string(value) {
+		return false, nil
+	}
+	m.data[key].expireAt = m.now().Add(ttl)
+	return true, nil
+}
+
+// -----------------scan operation--------------------
+// the scans iterate over a sorted snapshot taken when they start, so fn may use the service
+
+func (m *MemoryService) Scan(ctx context.Context, opts ScanOptions, fn ScanFunc) error {
+	re, err := globRegexp(matchAll(opts.Match))
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	var items [][]byte
+	for key := range m.data {
+		if m.entry(key) != nil && re.MatchString(key) {
+			items = append(items, []byte(key))
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool { return string(items[i]) < string(items[j]) })
+	return scanBatches(ctx, items, 1, opts.count(), fn)
+}
+
+func (m *MemoryService) SScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error {
+	members, err := m.SMembers(key)
+	if err != nil {
+		return err
+	}
+	items, err := matchItems(members, 1, opts.Match)
+	if err != nil {
+		return err
+	}
+	return scanBatches(ctx, items, 1, opts.count(), fn)
+}
+
+func (m *MemoryService) HScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error {
+	pairs, err := m.HGetAll(key)
+	if err != nil {
+		return err
+	}
+	items, err := matchItems(pairs, 2, opts.Match)
+	if err != nil {
+		return err
+	}
+	return scanBatches(ctx, items, 2, opts.count(), fn)
+}
+
+func (m *MemoryService) ZScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error {
+	pairs, err := m.ZRange(key, 0, -1, true)
+	if err != nil {
+		return err
+	}
+	items, err := matchItems(pairs, 2, opts.Match)
+	if err != nil {
+		return err
+	}
+	return scanBatches(ctx, items, 2, opts.count(), fn)
+}
+
+func (m *MemoryService) DeleteByPattern(ctx context.Context, pattern string, count int64) (int64, error) {
+	var deleted int64
+	err := m.Scan(ctx, ScanOptions{Match: pattern, Count: count}, func(batch [][]byte) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, key := range batch {
+			if m.entry(string(key)) != nil {
+				delete(m.data, string(key))
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func matchAll(pattern string) string {
+	if pattern == "" {
+		return "*"
+	}
+	return pattern
+}
+
+// matchItems keep the groups of width elements whose first element matches pattern
+func matchItems(items [][]byte, width int, pattern string) ([][]byte, error) {
+	re, err := globRegexp(matchAll(pattern))
+	if err != nil {
+		return nil, err
+	}
+	var res [][]byte
+	for i := 0; i+width <= len(items); i += width {
+		if re.Match(items[i]) {
+			res = append(res, items[i:i+width]...)
+		}
+	}
+	return res, nil
+}
+
+// scanBatches yield items by count groups of width elements
+func scanBatches(ctx context.Context, items [][]byte, width int, count int64, fn ScanFunc) error {
+	step := int(count) * width
+	for i := 0; i < len(items); i += step {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := i + step
+		if end > len(items) {
+			end = len(items)
+		}
+		if err := fn(items[i:end]); err != nil {
+			if err == ErrStopScan {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// globRegexp translate a redis glob pattern (* ? [abc] [^a] [a-z] \x) to a regexp
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			b.WriteString("(?s:.*)")
+		case '?':
+			b.WriteString("(?s:.)")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			} else {
+				b.WriteString(`\\`)
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + strings.Replace(class[1:], `\`, `\\`, -1)
+			} else {
+				class = strings.Replace(class, `\`, `\\`, -1)
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,448 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// memCommand run one command while the MemoryService lock is held,
+// replies use the types redigo returns: []byte, int64, string status, nil or []interface{}
+type memCommand func(m *MemoryService, args []string) (interface{}, error)
+
+// memCommands are the commands a Batch may queue against a MemoryService
+var memCommands = map[string]memCommand{
+	"get":       memGet,
+	"set":       memSetString,
+	"del":       memDel,
+	"unlink":    memDel,
+	"exists":    memExists,
+	"expire":    memExpire,
+	"ttl":       memTTL,
+	"incr":      func(m *MemoryService, a []string) (interface{}, error) { return memIncrBy(m, a, 1) },
+	"decr":      func(m *MemoryService, a []string) (interface{}, error) { return memIncrBy(m, a, -1) },
+	"incrby":    memIncrByArg(1),
+	"decrby":    memIncrByArg(-1),
+	"sadd":      memSAdd,
+	"srem":      memSRem,
+	"sismember": memSIsMember,
+	"scard":     memSCard,
+	"hset":      memHSet,
+	"hget":      memHGet,
+	"hdel":      memHDel,
+	"hlen":      memHLen,
+	"zadd":      memZAdd,
+	"zrem":      memZRem,
+	"zcard":     memZCard,
+	"zscore":    memZScore,
+	"lpush":     func(m *MemoryService, a []string) (interface{}, error) { return memPush(m, a, true) },
+	"rpush":     func(m *MemoryService, a []string) (interface{}, error) { return memPush(m, a, false) },
+	"llen":      memLLen,
+}
+
+// Pipeline run the queued commands under one lock, so a batch is always atomic here.
+// Only the commands of memCommands are supported, others fail in their Reply.
+func (m *MemoryService) Pipeline(b *Batch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range b.cmds {
+		args := make([]string, 0, len(r.args))
+		for _, a := range r.args {
+			args = append(args, argString(a))
+		}
+		fn, ok := memCommands[strings.ToLower(r.cmd)]
+		if !ok {
+			r.value, r.err = nil, redis.Error(fmt.Sprintf("ERR unsupported command '%s' in memory service", r.cmd))
+			continue
+		}
+		r.value, r.err = fn(m, args)
+		if r.err != nil {
+			r.err = redis.Error(r.err.Error())
+		}
+	}
+	return nil
+}
+
+func argString(a interface{}) string {
+	switch v := a.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func arity(args []string, min int, even bool) error {
+	if len(args) < min || (even && (len(args)-min)%2 != 0) {
+		return fmt.Errorf("ERR wrong number of arguments")
+	}
+	return nil
+}
+
+func memGet(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 1, false); err != nil {
+		return nil, err
+	}
+	v, ok, err := m.getString(a[0])
+	if err != nil || !ok {
+		return nil, err
+	}
+	return copyBytes(v), nil
+}
+
+// memSetString support SET key value [EX seconds] [PX milliseconds] [NX|XX]
+func memSetString(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	var (
+		ttl    time.Duration
+		nx, xx bool
+	)
+	for i := 2; i < len(a); i++ {
+		switch strings.ToLower(a[i]) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "ex", "px":
+			if i+1 >= len(a) {
+				return nil, fmt.Errorf("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(a[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("ERR invalid expire time in set")
+			}
+			unit := time.Second
+			if strings.ToLower(a[i]) == "px" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return nil, fmt.Errorf("ERR syntax error")
+		}
+	}
+
+	exists := m.entry(a[0]) != nil
+	if (nx && exists) || (xx && !exists) {
+		return nil, nil
+	}
+	e := &memEntry{value: []byte(a[1])}
+	if ttl > 0 {
+		e.expireAt = m.now().Add(ttl)
+	}
+	m.data[a[0]] = e
+	return "OK", nil
+}
+
+func memDel(m *MemoryService, a []string) (interface{}, error) {
+	var n int64
+	for _, key := range a {
+		if m.entry(key) != nil {
+			delete(m.data, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func memExists(m *MemoryService, a []string) (interface{}, error) {
+	var n int64
+	for _, key := range a {
+		if m.entry(key) != nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func memExpire(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	ttl, err := strconv.ParseInt(a[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("ERR value is not an integer or out of range")
+	}
+	e := m.entry(a[0])
+	if e == nil {
+		return int64(0), nil
+	}
+	if ttl <= 0 {
+		delete(m.data, a[0])
+		return int64(1), nil
+	}
+	e.expireAt = m.now().Add(time.Duration(ttl) * time.Second)
+	return int64(1), nil
+}
+
+func memTTL(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 1, false); err != nil {
+		return nil, err
+	}
+	e := m.entry(a[0])
+	switch {
+	case e == nil:
+		return int64(-2), nil
+	case e.expireAt.IsZero():
+		return int64(-1), nil
+	}
+	return int64((e.expireAt.Sub(m.now()) + time.Second - 1) / time.Second), nil
+}
+
+func memIncrBy(m *MemoryService, a []string, delta int64) (interface{}, error) {
+	if err := arity(a, 1, false); err != nil {
+		return nil, err
+	}
+	v, ok, err := m.getString(a[0])
+	if err != nil {
+		return nil, err
+	}
+	var n int64
+	if ok {
+		if n, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			return nil, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+	}
+	n += delta
+	if e := m.entry(a[0]); e != nil {
+		e.value = []byte(strconv.FormatInt(n, 10))
+	} else {
+		m.data[a[0]] = &memEntry{value: []byte(strconv.FormatInt(n, 10))}
+	}
+	return n, nil
+}
+
+func memIncrByArg(sign int64) memCommand {
+	return func(m *MemoryService, a []string) (interface{}, error) {
+		if err := arity(a, 2, false); err != nil {
+			return nil, err
+		}
+		delta, err := strconv.ParseInt(a[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		return memIncrBy(m, a[:1], sign*delta)
+	}
+}
+
+func memSAdd(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	set, err := m.getSet(a[0], true)
+	if err != nil {
+		return nil, err
+	}
+	var n int64
+	for _, member := range a[1:] {
+		if _, ok := set[member]; !ok {
+			set[member] = struct{}{}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func memSRem(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	set, err := m.getSet(a[0], false)
+	if err != nil || set == nil {
+		return int64(0), err
+	}
+	var n int64
+	for _, member := range a[1:] {
+		if _, ok := set[member]; ok {
+			delete(set, member)
+			n++
+		}
+	}
+	m.drop(a[0], len(set))
+	return n, nil
+}
+
+func memSIsMember(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	set, err := m.getSet(a[0], false)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := set[a[1]]; ok {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func memSCard(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 1, false); err != nil {
+		return nil, err
+	}
+	set, err := m.getSet(a[0], false)
+	return int64(len(set)), err
+}
+
+func memHSet(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 3, true); err != nil {
+		return nil, err
+	}
+	h, err := m.getHash(a[0], true)
+	if err != nil {
+		return nil, err
+	}
+	var n int64
+	for i := 1; i < len(a); i += 2 {
+		if _, ok := h[a[i]]; !ok {
+			n++
+		}
+		h[a[i]] = []byte(a[i+1])
+	}
+	return n, nil
+}
+
+func memHGet(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	h, err := m.getHash(a[0], false)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := h[a[1]]
+	if !ok {
+		return nil, nil
+	}
+	return copyBytes(v), nil
+}
+
+func memHDel(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	h, err := m.getHash(a[0], false)
+	if err != nil || h == nil {
+		return int64(0), err
+	}
+	var n int64
+	for _, field := range a[1:] {
+		if _, ok := h[field]; ok {
+			delete(h, field)
+			n++
+		}
+	}
+	m.drop(a[0], len(h))
+	return n, nil
+}
+
+func memHLen(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 1, false); err != nil {
+		return nil, err
+	}
+	h, err := m.getHash(a[0], false)
+	return int64(len(h)), err
+}
+
+func memZAdd(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 3, true); err != nil {
+		return nil, err
+	}
+	scores := make([]float64, 0, len(a)/2)
+	for i := 1; i < len(a); i += 2 {
+		score, err := strconv.ParseFloat(a[i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("ERR value is not a valid float")
+		}
+		scores = append(scores, score)
+	}
+	z, err := m.getZSet(a[0], true)
+	if err != nil {
+		return nil, err
+	}
+	var n int64
+	for i := 1; i < len(a); i += 2 {
+		if _, ok := z[a[i+1]]; !ok {
+			n++
+		}
+		z[a[i+1]] = scores[i/2]
+	}
+	return n, nil
+}
+
+func memZRem(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	z, err := m.getZSet(a[0], false)
+	if err != nil || z == nil {
+		return int64(0), err
+	}
+	var n int64
+	for _, member := range a[1:] {
+		if _, ok := z[member]; ok {
+			delete(z, member)
+			n++
+		}
+	}
+	m.drop(a[0], len(z))
+	return n, nil
+}
+
+func memZCard(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 1, false); err != nil {
+		return nil, err
+	}
+	z, err := m.getZSet(a[0], false)
+	return int64(len(z)), err
+}
+
+func memZScore(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	z, err := m.getZSet(a[0], false)
+	if err != nil {
+		return nil, err
+	}
+	score, ok := z[a[1]]
+	if !ok {
+		return nil, nil
+	}
+	return formatScore(score), nil
+}
+
+func memPush(m *MemoryService, a []string, left bool) (interface{}, error) {
+	if err := arity(a, 2, false); err != nil {
+		return nil, err
+	}
+	list, _, err := m.getList(a[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range a[1:] {
+		if left {
+			list = append(memList{[]byte(v)}, list...)
+		} else {
+			list = append(list, []byte(v))
+		}
+	}
+	m.setList(a[0], list)
+	return int64(len(list)), nil
+}
+
+func memLLen(m *MemoryService, a []string) (interface{}, error) {
+	if err := arity(a, 1, false); err != nil {
+		return nil, err
+	}
+	list, _, err := m.getList(a[0])
+	return int64(len(list)), err
+}
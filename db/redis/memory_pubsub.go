@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"context"
+	"regexp"
+	"sync"
+)
+
+// memSubscription is a Subscribe or PSubscribe call on a MemoryService
+type memSubscription struct {
+	ctx      context.Context
+	channels map[string]struct{}
+	patterns []*regexp.Regexp
+	sources  []string
+
+	// mu serializes deliveries with the close of ch
+	mu     sync.Mutex
+	closed bool
+	ch     chan Message
+}
+
+// match return the pattern matching channel, "" for a plain subscription
+func (s *memSubscription) match(channel string) (string, bool) {
+	if _, ok := s.channels[channel]; ok {
+		return "", true
+	}
+	for i, re := range s.patterns {
+		if re.MatchString(channel) {
+			return s.sources[i], true
+		}
+	}
+	return "", false
+}
+
+// deliver wait for the subscriber to take msg unless it went away
+func (s *memSubscription) deliver(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- msg:
+	case <-s.ctx.Done():
+	}
+}
+
+// -----------------pubsub operation------------------
+// Publish deliver message synchronously to the matching subscriptions
+func (m *MemoryService) Publish(channel string, message []byte) (int64, error) {
+	type target struct {
+		sub     *memSubscription
+		pattern string
+	}
+	m.mu.Lock()
+	var targets []target
+	for sub := range m.subs {
+		if pattern, ok := sub.match(channel); ok {
+			targets = append(targets, target{sub, pattern})
+		}
+	}
+	m.mu.Unlock()
+
+	// deliver outside the lock, a subscriber may call the service while handling a message
+	for _, t := range targets {
+		t.sub.deliver(Message{Pattern: t.pattern, Channel: channel, Data: copyBytes(message)})
+	}
+	return int64(len(targets)), nil
+}
+
+func (m *MemoryService) Subscribe(ctx context.Context, channels ...string) (<-chan Message, error) {
+	sub := &memSubscription{ctx: ctx, channels: make(map[string]struct{}, len(channels))}
+	for _, channel := range channels {
+		sub.channels[channel] = struct{}{}
+	}
+	return m.subscribe(sub), nil
+}
+
+func (m *MemoryService) PSubscribe(ctx context.Context, patterns ...string) (<-chan Message, error) {
+	sub := &memSubscription{ctx: ctx}
+	for _, pattern := range patterns {
+		re, err := globRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		sub.patterns = append(sub.patterns, re)
+		sub.sources = append(sub.sources, pattern)
+	}
+	return m.subscribe(sub), nil
+}
+
+func (m *MemoryService) subscribe(sub *memSubscription) <-chan Message {
+	sub.ch = make(chan Message, pubSubBuffer)
+
+	m.mu.Lock()
+	m.subs[sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-sub.ctx.Done()
+
+		m.mu.Lock()
+		delete(m.subs, sub)
+		m.mu.Unlock()
+
+		sub.mu.Lock()
+		sub.closed = true
+		close(sub.ch)
+		sub.mu.Unlock()
+	}()
+	return sub.ch
+}
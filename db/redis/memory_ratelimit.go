@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// -----------------rate limit operation--------------
+// SlidingWindow keep the window in a sorted set as the redis script does
+func (m *MemoryService) SlidingWindow(key string, limit int64, window time.Duration) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z, err := m.getZSet(key, true)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	now := m.now()
+	ms := now.UnixNano() / int64(time.Millisecond)
+	start := float64(ms - millis(window))
+	for member, score := range z {
+		if score <= start {
+			delete(z, member)
+		}
+	}
+
+	allowed := int64(len(z)) < limit
+	if allowed {
+		m.seq++
+		z[strconv.FormatInt(ms, 10)+"-"+strconv.FormatUint(m.seq, 10)] = float64(ms)
+	}
+	var reset int64
+	if len(z) > 0 {
+		oldest := math.Inf(1)
+		for _, score := range z {
+			oldest = math.Min(oldest, score)
+		}
+		reset = int64(oldest) + millis(window) - ms
+	}
+	m.data[key].expireAt = now.Add(time.Duration(millis(window)) * time.Millisecond)
+	return slidingWindowResult(allowed, limit-int64(len(z)), reset), nil
+}
+
+// TokenBucket keep the bucket in a hash as the redis script does
+func (m *MemoryService) TokenBucket(key string, capacity int64, refill time.Duration) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, err := m.getHash(key, true)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	now := m.now()
+	ms := float64(now.UnixNano() / int64(time.Millisecond))
+	rate := float64(capacity) / float64(millis(refill))
+
+	tokens, ts := float64(capacity), ms
+	if v, err := strconv.ParseFloat(string(h["tokens"]), 64); err == nil {
+		tokens = v
+	}
+	if v, err := strconv.ParseFloat(string(h["ts"]), 64); err == nil {
+		ts = v
+	}
+	tokens = math.Min(float64(capacity), tokens+math.Max(0, ms-ts)*rate)
+
+	r := RateLimitResult{}
+	if tokens >= 1 {
+		tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = time.Duration(math.Ceil((1-tokens)/rate)) * time.Millisecond
+	}
+	r.Remaining = int64(math.Floor(tokens))
+	r.Reset = time.Duration(math.Ceil((float64(capacity)-tokens)/rate)) * time.Millisecond
+
+	h["tokens"] = []byte(strconv.FormatFloat(tokens, 'f', -1, 64))
+	h["ts"] = []byte(strconv.FormatFloat(ms, 'f', -1, 64))
+	m.data[key].expireAt = now.Add(time.Duration(millis(refill)) * time.Millisecond)
+	return r, nil
+}
+
+// Sweep remove the expired keys, which are otherwise only dropped when accessed.
+// A long lived MemoryService with many short lived keys should call it periodically.
+func (m *MemoryService) Sweep() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int
+	for key := range m.data {
+		if m.entry(key) == nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,325 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamID is the ms-seq id of a stream entry
+type streamID struct {
+	ms, seq uint64
+}
+
+func (id streamID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+func (id streamID) less(o streamID) bool {
+	return id.ms < o.ms || (id.ms == o.ms && id.seq < o.seq)
+}
+
+func parseStreamID(s string) (streamID, error) {
+	parts := strings.SplitN(s, "-", 2)
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return streamID{}, fmt.Errorf("ERR Invalid stream ID specified as stream command argument")
+	}
+	var seq uint64
+	if len(parts) == 2 {
+		if seq, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+			return streamID{}, fmt.Errorf("ERR Invalid stream ID specified as stream command argument")
+		}
+	}
+	return streamID{ms, seq}, nil
+}
+
+type memStreamEntry struct {
+	id     streamID
+	values map[string][]byte
+}
+
+type memPending struct {
+	consumer   string
+	delivered  time.Time
+	deliveries int64
+}
+
+type memGroup struct {
+	lastDelivered streamID
+	pending       map[streamID]*memPending
+}
+
+type memStream struct {
+	entries []memStreamEntry
+	last    streamID
+	groups  map[string]*memGroup
+}
+
+func (s *memStream) find(id streamID) (memStreamEntry, bool) {
+	i := sort.Search(len(s.entries), func(i int) bool { return !s.entries[i].id.less(id) })
+	if i < len(s.entries) && s.entries[i].id == id {
+		return s.entries[i], true
+	}
+	return memStreamEntry{}, false
+}
+
+func (s *memStream) trim(maxLen int64) int64 {
+	n := int64(len(s.entries)) - maxLen
+	if n <= 0 {
+		return 0
+	}
+	s.entries = append([]memStreamEntry{}, s.entries[n:]...)
+	return n
+}
+
+func (e memStreamEntry) message() StreamMessage {
+	values := make(map[string][]byte, len(e.values))
+	for field, v := range e.values {
+		values[field] = copyBytes(v)
+	}
+	return StreamMessage{ID: e.id.String(), Values: values}
+}
+
+// getStream unlike other types keep an empty stream, as redis does
+func (m *MemoryService) getStream(key string, create bool) (*memStream, error) {
+	e := m.entry(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		e = &memEntry{value: &memStream{groups: make(map[string]*memGroup)}}
+		m.data[key] = e
+	}
+	v, ok := e.value.(*memStream)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return v, nil
+}
+
+func (m *MemoryService) getGroup(stream, group string) (*memStream, *memGroup, error) {
+	s, err := m.getStream(stream, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s != nil {
+		if g, ok := s.groups[group]; ok {
+			return s, g, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s'", stream, group)
+}
+
+// -----------------stream operation------------------
+// XAdd trims exactly when maxLen > 0
+func (m *MemoryService) XAdd(stream string, maxLen int64, values map[string][]byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getStream(stream, true)
+	if err != nil {
+		return "", err
+	}
+	id := streamID{ms: uint64(m.now().UnixNano() / int64(time.Millisecond))}
+	if !s.last.less(id) {
+		id = streamID{s.last.ms, s.last.seq + 1}
+	}
+	entry := memStreamEntry{id: id, values: make(map[string][]byte, len(values))}
+	for field, v := range values {
+		entry.values[field] = copyBytes(v)
+	}
+	s.entries = append(s.entries, entry)
+	s.last = id
+	if maxLen > 0 {
+		s.trim(maxLen)
+	}
+
+	// wake up the blocked readers
+	close(m.added)
+	m.added = make(chan struct{})
+	return id.String(), nil
+}
+
+func (m *MemoryService) XLen(stream string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getStream(stream, false)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	return int64(len(s.entries)), nil
+}
+
+func (m *MemoryService) XTrim(stream string, maxLen int64, approx bool) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getStream(stream, false)
+	if err != nil || s == nil {
+		return 0, err
+	}
+	return s.trim(maxLen), nil
+}
+
+func (m *MemoryService) XGroupCreate(stream, group, start string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getStream(stream, true)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.groups[group]; ok {
+		return nil
+	}
+	last := s.last
+	if start != "$" {
+		if last, err = parseStreamID(start); err != nil {
+			return err
+		}
+	}
+	s.groups[group] = &memGroup{lastDelivered: last, pending: make(map[streamID]*memPending)}
+	return nil
+}
+
+func (m *MemoryService) XReadGroup(ctx context.Context, group, consumer, stream string, count int64, block time.Duration) ([]StreamMessage, error) {
+	var timeout <-chan time.Time
+	if block > 0 {
+		timer := time.NewTimer(block)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		m.mu.Lock()
+		messages, err := m.readGroup(group, consumer, stream, count)
+		added := m.added
+		m.mu.Unlock()
+		if err != nil || len(messages) > 0 || block <= 0 {
+			return messages, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, nil
+		case <-added:
+		}
+	}
+}
+
+func (m *MemoryService) readGroup(group, consumer, stream string, count int64) ([]StreamMessage, error) {
+	s, g, err := m.getGroup(stream, group)
+	if err != nil {
+		return nil, err
+	}
+	var messages []StreamMessage
+	now := m.now()
+	for _, e := range s.entries {
+		if count > 0 && int64(len(messages)) >= count {
+			break
+		}
+		if !g.lastDelivered.less(e.id) {
+			continue
+		}
+		g.lastDelivered = e.id
+		g.pending[e.id] = &memPending{consumer: consumer, delivered: now, deliveries: 1}
+		messages = append(messages, e.message())
+	}
+	return messages, nil
+}
+
+func (m *MemoryService) XAck(stream, group string, ids ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, g, err := m.getGroup(stream, group)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, s := range ids {
+		id, err := parseStreamID(s)
+		if err != nil {
+			return n, err
+		}
+		if _, ok := g.pending[id]; ok {
+			delete(g.pending, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryService) XPending(stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, g, err := m.getGroup(stream, group)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		count = defaultPendingCount
+	}
+	ids := make([]streamID, 0, len(g.pending))
+	for id := range g.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].less(ids[j]) })
+	if int64(len(ids)) > count {
+		ids = ids[:count]
+	}
+
+	now := m.now()
+	pending := make([]PendingMessage, 0, len(ids))
+	for _, id := range ids {
+		p := g.pending[id]
+		if idle := now.Sub(p.delivered); idle >= minIdle {
+			pending = append(pending, PendingMessage{ID: id.String(), Consumer: p.consumer, Idle: idle, Deliveries: p.deliveries})
+		}
+	}
+	return pending, nil
+}
+
+// XClaim drop the pending entries deleted from the stream, as redis 7 does
+func (m *MemoryService) XClaim(stream, group, consumer string, minIdle time.Duration, ids ...string) ([]StreamMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, g, err := m.getGroup(stream, group)
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	messages := make([]StreamMessage, 0, len(ids))
+	for _, str := range ids {
+		id, err := parseStreamID(str)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := g.pending[id]
+		if !ok || now.Sub(p.delivered) < minIdle {
+			continue
+		}
+		e, ok := s.find(id)
+		if !ok {
+			delete(g.pending, id)
+			continue
+		}
+		p.consumer, p.delivered = consumer, now
+		p.deliveries++
+		messages = append(messages, e.message())
+	}
+	return messages, nil
+}
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemorySweep(t *testing.T) {
+	b := newMemoryBackend()
+	m := b.service.(*MemoryService)
+	check(t, m.Set("a", []byte("v"), 1))
+	check(t, m.SAdd("b", 5, []byte("m")))
+	check(t, m.Set("c", []byte("v"), 0))
+
+	b.advance(2 * time.Second)
+	equal(t, "swept", m.Sweep(), 1)
+	equal(t, "entries", len(m.data), 2)
+	b.advance(5 * time.Second)
+	equal(t, "swept later", m.Sweep(), 1)
+	equal(t, "entries later", len(m.data), 1)
+}
+
+func TestMemoryWrongType(t *testing.T) {
+	m := NewMemoryService()
+	check(t, m.Set("s", []byte("v"), 0))
+	if err := m.SAdd("s", 0, []byte("m")); err != ErrWrongType {
+		t.Errorf("sadd on a string = %v, want ErrWrongType", err)
+	}
+	if _, err := m.HGet("s", []byte("f")); err != ErrWrongType {
+		t.Errorf("hget on a string = %v, want ErrWrongType", err)
+	}
+	if _, err := m.LRpush("s", []byte("v")); err != ErrWrongType {
+		t.Errorf("push on a string = %v, want ErrWrongType", err)
+	}
+}
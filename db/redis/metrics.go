@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"template_project/metrics"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// commandBuckets are the latency buckets of the redis commands, in seconds
+var commandBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+var (
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "redis_command_duration_seconds",
+		Help:    "Latency of the redis commands.",
+		Buckets: commandBuckets,
+	}, []string{"command"})
+	commandErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_command_errors_total",
+		Help: "Number of redis commands that failed, with an error reply or a connection error.",
+	}, []string{"command"})
+)
+
+func init() {
+	metrics.MustRegister(commandDuration, commandErrors, poolCollector{})
+}
+
+// PoolStats is the number of connections of every pool of a Service
+type PoolStats struct {
+	Active int // in use and idle
+	Idle   int
+}
+
+// PoolStats sum the stats of the pools, one per master in cluster mode
+func (service *Service) PoolStats() PoolStats {
+	var stats PoolStats
+	for _, p := range service.pool.opened() {
+		s := p.Stats()
+		stats.Active += s.ActiveCount
+		stats.Idle += s.IdleCount
+	}
+	return stats
+}
+
+var (
+	poolActiveDesc = prometheus.NewDesc("redis_pool_active_connections",
+		"Number of connections of the pool, in use and idle.", nil, nil)
+	poolIdleDesc = prometheus.NewDesc("redis_pool_idle_connections",
+		"Number of idle connections of the pool.", nil, nil)
+)
+
+// poolCollector expose the pool stats of DB, nothing while redis is disabled or in memory
+type poolCollector struct{}
+
+func (poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolActiveDesc
+	ch <- poolIdleDesc
+}
+
+func (poolCollector) Collect(ch chan<- prometheus.Metric) {
+	service, ok := DB.(*Service)
+	if !ok {
+		return
+	}
+	stats := service.PoolStats()
+	ch <- prometheus.MustNewConstMetric(poolActiveDesc, prometheus.GaugeValue, float64(stats.Active))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stats.Idle))
+}
+
+// instrumentedConn observe the latency of the commands sent with Do.
+// The pipelined commands are observed as a whole under "pipeline".
+type instrumentedConn struct {
+	redis.Conn
+}
+
+func (c *instrumentedConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+	reply, err := c.Conn.Do(cmd, args...)
+	observeCommand(cmd, start, reply, err)
+	return reply, err
+}
+
+func (c *instrumentedConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+	observeCommand(cmd, start, reply, err)
+	return reply, err
+}
+
+func (c *instrumentedConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+func observeCommand(cmd string, start time.Time, reply interface{}, err error) {
+	name := strings.ToLower(cmd)
+	if name == "" {
+		name = "pipeline"
+	}
+	commandDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if _, isReply := reply.(redis.Error); err != nil || isReply {
+		commandErrors.WithLabelValues(name).Inc()
+	}
+}
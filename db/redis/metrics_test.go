@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPoolMetrics(t *testing.T) {
+	saved := DB
+	defer func() { DB = saved }()
+
+	DB = NewMemoryService()
+	if n := testutil.CollectAndCount(poolCollector{}); n != 0 {
+		t.Errorf("in memory: %d pool metrics, want none", n)
+	}
+
+	b := newRedisBackend(t)
+	DB = b.service
+	check(t, DB.Set("k", []byte("v"), 0))
+	want := `
+# HELP redis_pool_active_connections Number of connections of the pool, in use and idle.
+# TYPE redis_pool_active_connections gauge
+redis_pool_active_connections 1
+# HELP redis_pool_idle_connections Number of idle connections of the pool.
+# TYPE redis_pool_idle_connections gauge
+redis_pool_idle_connections 1
+`
+	if err := testutil.CollectAndCompare(poolCollector{}, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+
+	// an error reply counts as a failed command
+	before := testutil.ToFloat64(commandErrors.WithLabelValues("sadd"))
+	if err := DB.SAdd("k", 0, []byte("m")); err == nil {
+		t.Fatal("SADD to a string: want an error")
+	}
+	if got := testutil.ToFloat64(commandErrors.WithLabelValues("sadd")); got != before+1 {
+		t.Errorf("sadd errors = %v, want %v", got, before+1)
+	}
+}
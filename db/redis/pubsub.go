@@ -0,0 +1,153 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// pubSubPingInterval keep idle subscriptions alive and detect dead connections
+	pubSubPingInterval = 30 * time.Second
+	pubSubRetryMin     = 100 * time.Millisecond
+	pubSubRetryMax     = 10 * time.Second
+	pubSubBuffer       = 64
+)
+
+// Message received by a subscription, Pattern is set for pattern subscriptions
+type Message struct {
+	Pattern string
+	Channel string
+	Data    []byte
+}
+
+// PubSubI publish messages and deliver subscriptions on go channels
+type PubSubI interface {
+	// Publish send message to channel and return the number of receivers
+	Publish(channel string, message []byte) (int64, error)
+	// Subscribe deliver the messages of channels until ctx is done, then close the returned channel
+	Subscribe(ctx context.Context, channels ...string) (<-chan Message, error)
+	// PSubscribe deliver the messages of the channels matching patterns until ctx is done
+	PSubscribe(ctx context.Context, patterns ...string) (<-chan Message, error)
+}
+
+// -----------------pubsub operation------------------
+func (service *Service) Publish(channel string, message []byte) (int64, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(conn.Do("publish", channel, message))
+}
+
+// Subscribe run on a dedicated connection that is dialed again when it breaks,
+// messages published while reconnecting are lost as pub/sub has no history
+func (service *Service) Subscribe(ctx context.Context, channels ...string) (<-chan Message, error) {
+	return service.subscribe(ctx, false, channels)
+}
+
+func (service *Service) PSubscribe(ctx context.Context, patterns ...string) (<-chan Message, error) {
+	return service.subscribe(ctx, true, patterns)
+}
+
+func (service *Service) subscribe(ctx context.Context, pattern bool, names []string) (<-chan Message, error) {
+	// the first connection is made synchronously so that configuration errors surface to the caller
+	psc, err := service.dialPubSub(pattern, names)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Message, pubSubBuffer)
+	go func() {
+		defer close(out)
+		wait := pubSubRetryMin
+		for {
+			if psc != nil {
+				if service.receive(ctx, psc, out) {
+					wait = pubSubRetryMin
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			if wait *= 2; wait > pubSubRetryMax {
+				wait = pubSubRetryMax
+			}
+			psc, _ = service.dialPubSub(pattern, names)
+		}
+	}()
+	return out, nil
+}
+
+func (service *Service) dialPubSub(pattern bool, names []string) (*redis.PubSubConn, error) {
+	conn, err := service.pool.dial()
+	if err != nil {
+		return nil, err
+	}
+	psc := &redis.PubSubConn{Conn: conn}
+
+	args := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		args = append(args, name)
+	}
+	if pattern {
+		err = psc.PSubscribe(args...)
+	} else {
+		err = psc.Subscribe(args...)
+	}
+	if err != nil {
+		psc.Close()
+		return nil, err
+	}
+	return psc, nil
+}
+
+// receive forward messages to out until the connection breaks or ctx is done,
+// it reports whether a message went through so that the retry backoff can be reset
+func (service *Service) receive(ctx context.Context, psc *redis.PubSubConn, out chan<- Message) bool {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pubSubPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// unblock the pending receive
+				psc.Close()
+				return
+			case <-done:
+				psc.Close()
+				return
+			case <-ticker.C:
+				if err := psc.Ping(""); err != nil {
+					psc.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	received := false
+	for {
+		switch v := psc.ReceiveWithTimeout(2 * pubSubPingInterval).(type) {
+		case redis.Message:
+			received = true
+			select {
+			case out <- Message{Pattern: v.Pattern, Channel: v.Channel, Data: v.Data}:
+			case <-ctx.Done():
+				return received
+			}
+		case error:
+			return received
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RateLimitResult is the outcome of one request against a rate limit
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int64
+	// RetryAfter is how long a denied request should wait, 0 when allowed
+	RetryAfter time.Duration
+	// Reset is how long until the limit is fully available again
+	Reset time.Duration
+}
+
+var (
+	// the window is a sorted set of the request timestamps in ms,
+	// KEYS[1] window, ARGV now, window length, limit, unique member
+	slidingWindowScript = redis.NewScript(1, `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("zremrangebyscore", KEYS[1], "-inf", now - window)
+local count = redis.call("zcard", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("zadd", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+local reset = 0
+local oldest = redis.call("zrange", KEYS[1], 0, 0, "withscores")
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+redis.call("pexpire", KEYS[1], window)
+return {allowed, limit - count, reset}`)
+
+	// the bucket is a hash of the tokens left and the time they were counted in ms,
+	// KEYS[1] bucket, ARGV now, capacity, refill of the whole bucket
+	tokenBucketScript = redis.NewScript(1, `
+local now = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local refill = tonumber(ARGV[3])
+local rate = capacity / refill
+local state = redis.call("hmget", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call("hmset", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("pexpire", KEYS[1], refill)
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}`)
+)
+
+// -----------------rate limit operation--------------
+// SlidingWindow count a request in the window of key and allow it when fewer than limit
+// requests were allowed during the last window. The clock of the caller is used,
+// so the pods sharing a limit should keep their clocks in sync.
+func (service *Service) SlidingWindow(key string, limit int64, window time.Duration) (RateLimitResult, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	member := make([]byte, 8)
+	if _, err := rand.Read(member); err != nil {
+		return RateLimitResult{}, err
+	}
+	now := nowMillis()
+	values, err := redis.Int64s(slidingWindowScript.Do(conn, key, now, millis(window), limit,
+		strconv.FormatInt(now, 10)+"-"+hex.EncodeToString(member)))
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return slidingWindowResult(values[0] == 1, values[1], values[2]), nil
+}
+
+// TokenBucket take a token from the bucket of key, holding up to capacity tokens
+// and refilled completely in refill
+func (service *Service) TokenBucket(key string, capacity int64, refill time.Duration) (RateLimitResult, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Int64s(tokenBucketScript.Do(conn, key, nowMillis(), capacity, millis(refill)))
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  values[1],
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+func slidingWindowResult(allowed bool, remaining, reset int64) RateLimitResult {
+	r := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: remaining,
+		Reset:     time.Duration(reset) * time.Millisecond,
+	}
+	if !allowed {
+		// a slot frees up when the oldest request leaves the window
+		r.RetryAfter = r.Reset
+	}
+	return r
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// millis convert d to milliseconds, at least 1
+func millis(d time.Duration) int64 {
+	if ms := int64(d / time.Millisecond); ms > 0 {
+		return ms
+	}
+	return 1
+}
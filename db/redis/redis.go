@@ -2,18 +2,36 @@ package redis
 
 import "template_project/config"
 
-var DB *Service
+// DB is the redis service used by the application, tests may set it to a MemoryService
+var DB ServiceI
 
 func Init() {
 	var service = &Service{}
 	cfg := config.GetConfig().Redis
 	config := Config{
-		Host:        cfg.Host,
-		Port:        cfg.Port,
-		Password:    cfg.Password,
-		IdleTimeout: cfg.IdleTimeout,
-		MaxActive:   cfg.MaxActive,
-		MaxIdle:     cfg.MaxIdle,
+		Mode:             cfg.Mode,
+		Host:             cfg.Host,
+		Port:             cfg.Port,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		MasterName:       cfg.MasterName,
+		SentinelAddrs:    cfg.SentinelAddrs,
+		SentinelPassword: cfg.SentinelPassword,
+		ClusterAddrs:     cfg.ClusterAddrs,
+		DialTimeout:      cfg.DialTimeout.Duration,
+		ReadTimeout:      cfg.ReadTimeout.Duration,
+		WriteTimeout:     cfg.WriteTimeout.Duration,
+		IdleTimeout:      cfg.IdleTimeout.Duration,
+		MaxActive:        cfg.MaxActive,
+		MaxIdle:          cfg.MaxIdle,
+	}
+	if cfg.TLS.Enable {
+		tlsConfig, err := NewTLSConfig(cfg.TLS.CAFile, cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ServerName, cfg.TLS.InsecureSkipVerify)
+		if err != nil {
+			panic(err)
+		}
+		config.TLS = tlsConfig
 	}
 	service.Initialize(config)
 	DB = service
@@ -0,0 +1,236 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	defaultScanCount = 100
+	// unlinkPipelineDepth is how many UNLINK replies DeleteByPattern leaves in flight
+	unlinkPipelineDepth = 16
+)
+
+// KeysLimit cap the number of keys Keys collects before it gives up with ErrTooManyKeys
+var KeysLimit = 10000
+
+var (
+	// ErrStopScan may be returned by a ScanFunc to end the iteration without error
+	ErrStopScan = errors.New("stop scan")
+	// ErrTooManyKeys is returned by Keys together with the first KeysLimit keys
+	ErrTooManyKeys = errors.New("too many keys match the pattern")
+)
+
+// ScanOptions of the SCAN family of commands
+type ScanOptions struct {
+	Match string // glob pattern, empty matches everything
+	Count int64  // COUNT hint of each round trip, default 100
+}
+
+func (o ScanOptions) count() int64 {
+	if o.Count <= 0 {
+		return defaultScanCount
+	}
+	return o.Count
+}
+
+// ScanFunc receive the batches of a scan.
+// HScan batches alternate field and value, ZScan batches alternate member and score.
+// A batch may repeat elements of a previous one, as redis only guarantees at least once delivery.
+type ScanFunc func(batch [][]byte) error
+
+// -----------------scan operation--------------------
+// Scan iterate the keys matching opts.Match without blocking the server like KEYS does,
+// in cluster mode every master is scanned in turn
+func (service *Service) Scan(ctx context.Context, opts ScanOptions, fn ScanFunc) error {
+	pools, err := service.pool.masters()
+	if err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		conn := pool.Get()
+		err := scanConn(ctx, conn, "scan", nil, opts, fn)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SScan iterate the members of a set
+func (service *Service) SScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error {
+	return service.scan(ctx, "sscan", []interface{}{key}, opts, fn)
+}
+
+// HScan iterate the fields and values of a hash
+func (service *Service) HScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error {
+	return service.scan(ctx, "hscan", []interface{}{key}, opts, fn)
+}
+
+// ZScan iterate the members and scores of a sorted set
+func (service *Service) ZScan(ctx context.Context, key string, opts ScanOptions, fn ScanFunc) error {
+	return service.scan(ctx, "zscan", []interface{}{key}, opts, fn)
+}
+
+func (service *Service) scan(ctx context.Context, cmd string, prefix []interface{}, opts ScanOptions, fn ScanFunc) error {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	return scanConn(ctx, conn, cmd, prefix, opts, fn)
+}
+
+func scanConn(ctx context.Context, conn redis.Conn, cmd string, prefix []interface{}, opts ScanOptions, fn ScanFunc) error {
+	var cursor int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		vs := append([]interface{}{}, prefix...)
+		vs = append(vs, cursor)
+		if opts.Match != "" {
+			vs = append(vs, "match", opts.Match)
+		}
+		vs = append(vs, "count", opts.count())
+
+		values, err := redis.Values(conn.Do(cmd, vs...))
+		if err != nil {
+			return err
+		}
+		if len(values) != 2 {
+			return fmt.Errorf("unexpected %s reply", cmd)
+		}
+		if cursor, err = redis.Int64(values[0], nil); err != nil {
+			return err
+		}
+		batch, err := redis.ByteSlices(values[1], nil)
+		if err != nil {
+			return err
+		}
+
+		if len(batch) > 0 {
+			if err := fn(batch); err != nil {
+				if err == ErrStopScan {
+					return nil
+				}
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// DeleteByPattern unlink every key matching pattern, scanning in batches of count keys
+// and pipelining one UNLINK per batch. It returns the number of keys removed.
+// In cluster mode each master is cleaned in turn and a batch is split by slot.
+func (service *Service) DeleteByPattern(ctx context.Context, pattern string, count int64) (int64, error) {
+	pools, err := service.pool.masters()
+	if err != nil {
+		return 0, err
+	}
+	_, cluster := service.pool.(*clusterPool)
+
+	var deleted int64
+	for _, pool := range pools {
+		n, err := deleteByPattern(ctx, pool, pattern, count, cluster)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+func deleteByPattern(ctx context.Context, pool *redis.Pool, pattern string, count int64, bySlot bool) (int64, error) {
+	scan := pool.Get()
+	defer scan.Close()
+	conn := pool.Get()
+	defer conn.Close()
+
+	var (
+		deleted int64
+		pending int
+	)
+	receive := func(n int) error {
+		for ; n > 0; n-- {
+			reply, err := redis.Int64(conn.Receive())
+			if err != nil {
+				return err
+			}
+			deleted += reply
+			pending--
+		}
+		return nil
+	}
+
+	err := scanConn(ctx, scan, "scan", nil, ScanOptions{Match: pattern, Count: count}, func(batch [][]byte) error {
+		groups := [][][]byte{batch}
+		if bySlot {
+			groups = groupBySlot(batch)
+		}
+		for _, keys := range groups {
+			vs := make([]interface{}, 0, len(keys))
+			for _, key := range keys {
+				vs = append(vs, key)
+			}
+			if err := conn.Send("unlink", vs...); err != nil {
+				return err
+			}
+			pending++
+		}
+		if err := conn.Flush(); err != nil {
+			return err
+		}
+		if pending > unlinkPipelineDepth {
+			return receive(pending - unlinkPipelineDepth)
+		}
+		return nil
+	})
+	if rerr := receive(pending); err == nil {
+		err = rerr
+	}
+	return deleted, err
+}
+
+// groupBySlot split keys so that a multi-key command of each group is allowed by a cluster
+func groupBySlot(keys [][]byte) [][][]byte {
+	index := make(map[int]int)
+	var groups [][][]byte
+	for _, key := range keys {
+		slot := hashSlot(string(key))
+		i, ok := index[slot]
+		if !ok {
+			i = len(groups)
+			index[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// collectKeys gather the keys of a scan up to KeysLimit
+func collectKeys(scan func(fn ScanFunc) error) ([][]byte, error) {
+	res := [][]byte{}
+	seen := make(map[string]bool)
+	err := scan(func(batch [][]byte) error {
+		for _, key := range batch {
+			if seen[string(key)] {
+				continue
+			}
+			if len(res) >= KeysLimit {
+				return ErrTooManyKeys
+			}
+			seen[string(key)] = true
+			res = append(res, key)
+		}
+		return nil
+	})
+	return res, err
+}
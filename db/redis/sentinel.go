@@ -0,0 +1,218 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// sentinelRetry is the pause of the failover watcher between two sentinel connections
+const sentinelRetry = time.Second
+
+// sentinel resolve the address of the master named masterName and follow its failovers
+type sentinel struct {
+	masterName string
+	dialer     *dialer
+
+	mu     sync.Mutex
+	addrs  []string
+	master string
+	stop   chan struct{}
+	conn   redis.Conn
+}
+
+func newSentinel(masterName string, addrs []string, dialer *dialer) *sentinel {
+	return &sentinel{
+		masterName: masterName,
+		dialer:     dialer,
+		addrs:      append([]string{}, addrs...),
+		stop:       make(chan struct{}),
+	}
+}
+
+// current return the last known master address
+func (s *sentinel) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.master
+}
+
+func (s *sentinel) setMaster(addr string) {
+	s.mu.Lock()
+	s.master = addr
+	s.mu.Unlock()
+}
+
+// resolve ask the sentinels in turn for the master address.
+// The sentinel that answered is moved first, as the sentinel client guidelines recommend.
+func (s *sentinel) resolve() (string, error) {
+	s.mu.Lock()
+	addrs := append([]string{}, s.addrs...)
+	s.mu.Unlock()
+
+	var errs []string
+	for i, addr := range addrs {
+		master, err := s.queryMaster(addr)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", addr, err))
+			continue
+		}
+		s.mu.Lock()
+		if i > 0 && i < len(s.addrs) && s.addrs[i] == addr {
+			s.addrs[0], s.addrs[i] = s.addrs[i], s.addrs[0]
+		}
+		s.master = master
+		s.mu.Unlock()
+		return master, nil
+	}
+	return "", fmt.Errorf("redis sentinel: no sentinel knows master %q (%s)", s.masterName, strings.Join(errs, "; "))
+}
+
+func (s *sentinel) queryMaster(addr string) (string, error) {
+	c, err := s.dialer.dial(addr)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	res, err := redis.Strings(c.Do("sentinel", "get-master-addr-by-name", s.masterName))
+	if err == redis.ErrNil {
+		return "", errors.New("unknown master")
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(res) != 2 {
+		return "", fmt.Errorf("unexpected reply %v", res)
+	}
+	return net.JoinHostPort(res[0], res[1]), nil
+}
+
+// masterConn is a connection remembering the master it was dialed to
+type masterConn struct {
+	redis.Conn
+	addr string
+}
+
+func (c *masterConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c *masterConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+// dialMaster resolve the master and check its role, a sentinel may still announce a demoted master
+func (s *sentinel) dialMaster(d *dialer) (redis.Conn, error) {
+	addr, err := s.resolve()
+	if err != nil {
+		return nil, err
+	}
+	c, err := d.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	role, err := redis.Values(c.Do("role"))
+	if err == nil && len(role) > 0 {
+		if r, _ := redis.String(role[0], nil); r != "master" {
+			err = fmt.Errorf("redis sentinel: %s is a %s, not the master", addr, r)
+		}
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &masterConn{Conn: c, addr: addr}, nil
+}
+
+// testOnBorrow drop the pooled connections of a master that failed over
+func (s *sentinel) testOnBorrow(c redis.Conn, _ time.Time) error {
+	if mc, ok := c.(*masterConn); ok && mc.addr != s.current() {
+		return errors.New("redis sentinel: master changed")
+	}
+	return nil
+}
+
+// watch follow the +switch-master events of the sentinels until close
+func (s *sentinel) watch() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		s.mu.Lock()
+		addrs := append([]string{}, s.addrs...)
+		s.mu.Unlock()
+		for _, addr := range addrs {
+			c, err := s.dialer.dial(addr)
+			if err != nil {
+				continue
+			}
+			s.mu.Lock()
+			s.conn = c
+			s.mu.Unlock()
+			s.listen(redis.PubSubConn{Conn: c})
+			c.Close()
+			break
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(sentinelRetry):
+		}
+	}
+}
+
+func (s *sentinel) listen(psc redis.PubSubConn) {
+	if err := psc.Subscribe("+switch-master"); err != nil {
+		return
+	}
+	// the failover may have happened while no sentinel was watched
+	s.resolve()
+	for {
+		// a quiet connection is recycled, which also catches sentinels that went away silently
+		switch v := psc.ReceiveWithTimeout(2 * pubSubPingInterval).(type) {
+		case redis.Message:
+			// <master name> <old ip> <old port> <new ip> <new port>
+			parts := strings.Fields(string(v.Data))
+			if len(parts) == 5 && parts[0] == s.masterName {
+				s.setMaster(net.JoinHostPort(parts[3], parts[4]))
+			}
+		case error:
+			return
+		}
+	}
+}
+
+func (s *sentinel) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+	close(s.stop)
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+func newSentinelPool(cfg Config) *singlePool {
+	s := newSentinel(cfg.MasterName, cfg.SentinelAddrs, newDialer(cfg, "", cfg.SentinelPassword, 0))
+	d := newDialer(cfg, cfg.Username, cfg.Password, cfg.DB)
+	pool := newPool(cfg, func() (redis.Conn, error) {
+		return s.dialMaster(d)
+	})
+	pool.TestOnBorrow = s.testOnBorrow
+	go s.watch()
+	return &singlePool{Pool: pool, onClose: s.close}
+}
@@ -1,28 +1,47 @@
 package redis
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
 
 type Config struct {
-	Host        string
-	Port        string
-	Password    string
-	IdleTimeout int
+	Mode     string // standalone, sentinel or cluster, default standalone
+	Host     string
+	Port     string
+	Username string
+	Password string
+	DB       int
+
+	MasterName       string
+	SentinelAddrs    []string
+	SentinelPassword string
+	ClusterAddrs     []string
+
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	TLS          *tls.Config // nil for plain tcp
+
+	IdleTimeout time.Duration
 	MaxIdle     int
 	MaxActive   int
 }
 
+var _ ServiceI = (*Service)(nil)
+
 type Service struct {
 	config Config
-	pool   *redis.Pool
+	pool   connPool
 }
 
-// Initialize redis service init
+// Initialize redis service init, no connection is made until the first command
 func (service *Service) Initialize(config interface{}) {
 	cfg, ok := config.(Config)
 	if !ok {
@@ -30,28 +49,19 @@ func (service *Service) Initialize(config interface{}) {
 	}
 
 	service.config = cfg
-	service.pool = &redis.Pool{
-		IdleTimeout: time.Duration(cfg.IdleTimeout) * time.Second,
-		MaxIdle:     cfg.MaxIdle,
-		MaxActive:   cfg.MaxActive,
-		Wait:        true,
-		Dial: func() (redis.Conn, error) {
-			address := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-			var (
-				c   redis.Conn
-				err error
-			)
-			if c, err = redis.Dial("tcp", address); err != nil {
-				return nil, err
-			}
-			if len(cfg.Password) > 0 {
-				if _, err = c.Do("AUTH", cfg.Password); err != nil {
-					c.Close()
-					return nil, err
-				}
-			}
-			return c, nil
-		},
+	switch cfg.Mode {
+	case "", "standalone":
+		d := newDialer(cfg, cfg.Username, cfg.Password, cfg.DB)
+		address := net.JoinHostPort(cfg.Host, cfg.Port)
+		service.pool = &singlePool{Pool: newPool(cfg, func() (redis.Conn, error) {
+			return d.dial(address)
+		})}
+	case "sentinel":
+		service.pool = newSentinelPool(cfg)
+	case "cluster":
+		service.pool = newClusterPool(cfg)
+	default:
+		panic(fmt.Sprintf("redis service mode %q is not supported", cfg.Mode))
 	}
 }
 
@@ -65,20 +75,44 @@ func (service *Service) Stop() {
 
 }
 
+// Close release all connections of the pool
+func (service *Service) Close() error {
+	return service.pool.Close()
+}
+
+// Ping send PING to every master, within the deadline of ctx when it has one
+func (service *Service) Ping(ctx context.Context) error {
+	pools, err := service.pool.masters()
+	if err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		conn := pool.Get()
+		if deadline, ok := ctx.Deadline(); ok {
+			_, err = redis.DoWithTimeout(conn, time.Until(deadline), "ping")
+		} else {
+			_, err = conn.Do("ping")
+		}
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // -----------------string operation------------------
 // when set exist key, old key ttl must reset it
 func (service *Service) Set(key string, value []byte, ttl int64) error {
 	conn := service.pool.Get()
 	defer conn.Close()
 
-	if _, err := conn.Do("set", key, value); err != nil {
-		return err
-	}
-
+	vs := []interface{}{key, value}
 	if ttl > 0 {
-		if _, err := conn.Do("expire", key, ttl); err != nil {
-			return err
-		}
+		vs = append(vs, "ex", ttl)
+	}
+	if _, err := conn.Do("set", vs...); err != nil {
+		return err
 	}
 	return nil
 }
@@ -97,41 +131,103 @@ func (service *Service) Get(key string) ([]byte, error) {
 	}
 }
 
-func (service *Service) Mset(keyvalues [][2][]byte, ttl int64) error {
-	conn := service.pool.Get()
-	defer conn.Close()
-
-	if len(keyvalues) <= 0 {
+// slotGroups return the indexes of keys grouped by cluster slot, in order of first key,
+// or nil outside cluster mode where a multi-key command may mix any keys
+func (service *Service) slotGroups(keys []string) [][]int {
+	if _, ok := service.pool.(*clusterPool); !ok {
 		return nil
 	}
+	var groups [][]int
+	index := make(map[int]int)
+	for i, key := range keys {
+		slot := hashSlot(key)
+		g, ok := index[slot]
+		if !ok {
+			g = len(groups)
+			index[slot] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
 
-	var list []interface{}
-	for _, v := range keyvalues {
-		list = append(list, v[0])
-		list = append(list, v[1])
+// batchErr return the first error of the replies
+func batchErr(b *Batch) error {
+	for _, r := range b.cmds {
+		if r.err != nil {
+			return r.err
+		}
 	}
+	return nil
+}
 
-	if _, err := conn.Do("mset", list...); err != nil {
-		return err
+// Mset set all keys at once, in cluster mode the keys of different slots are set separately
+func (service *Service) Mset(keyvalues [][2][]byte, ttl int64) error {
+	if len(keyvalues) <= 0 {
+		return nil
 	}
 
-	if ttl > 0 {
-		for _, kv := range keyvalues {
-			if _, err := conn.Do("expire", kv[0], ttl); err != nil {
-				return err
+	keys := make([]string, len(keyvalues))
+	for i, kv := range keyvalues {
+		keys[i] = string(kv[0])
+	}
+	if groups := service.slotGroups(keys); groups != nil {
+		b := NewBatch()
+		if ttl > 0 {
+			for _, kv := range keyvalues {
+				b.Queue("set", kv[0], kv[1], "ex", ttl)
+			}
+		} else {
+			for _, g := range groups {
+				var list []interface{}
+				for _, i := range g {
+					list = append(list, keyvalues[i][0], keyvalues[i][1])
+				}
+				b.Queue("mset", list...)
 			}
 		}
+		if err := service.Pipeline(b); err != nil {
+			return err
+		}
+		return batchErr(b)
 	}
-	return nil
-}
 
-func (service *Service) Mget(keys []string) ([][]byte, error) {
 	conn := service.pool.Get()
 	defer conn.Close()
 
+	if ttl <= 0 {
+		var list []interface{}
+		for _, v := range keyvalues {
+			list = append(list, v[0])
+			list = append(list, v[1])
+		}
+		_, err := conn.Do("mset", list...)
+		return err
+	}
+
+	// MSET has no expiry, so every key is set with SET EX inside one transaction
+	b := &Batch{Atomic: true}
+	for _, kv := range keyvalues {
+		b.Queue("set", kv[0], kv[1], "ex", ttl)
+	}
+	if err := service.exec(conn, b); err != nil {
+		return err
+	}
+	return batchErr(b)
+}
+
+func (service *Service) Mget(keys []string) ([][]byte, error) {
 	if keys == nil || len(keys) <= 0 {
 		return [][]byte{}, nil
 	}
+	if groups := service.slotGroups(keys); groups != nil {
+		return service.clusterMget(keys, groups)
+	}
+
+	conn := service.pool.Get()
+	defer conn.Close()
+
 	var list []interface{}
 	for _, v := range keys {
 		list = append(list, v)
@@ -157,6 +253,35 @@ func (service *Service) Mget(keys []string) ([][]byte, error) {
 	}
 }
 
+// clusterMget send a MGET per slot and put the values back in the order of keys
+func (service *Service) clusterMget(keys []string, groups [][]int) ([][]byte, error) {
+	b := NewBatch()
+	replies := make([]*Reply, len(groups))
+	for i, g := range groups {
+		list := make([]interface{}, len(g))
+		for j, k := range g {
+			list[j] = keys[k]
+		}
+		replies[i] = b.Queue("mget", list...)
+	}
+	if err := service.Pipeline(b); err != nil {
+		return [][]byte{}, err
+	}
+
+	res := make([][]byte, len(keys))
+	for i, g := range groups {
+		values, err := replies[i].ByteSlices()
+		if err != nil {
+			return [][]byte{}, err
+		}
+		for j, k := range g {
+			if j < len(values) {
+				res[k] = values[j]
+			}
+		}
+	}
+	return res, nil
+}
 func (service *Service) Exists(key string) (bool, error) {
 	conn := service.pool.Get()
 	defer conn.Close()
@@ -186,13 +311,28 @@ func (service *Service) Del(key string) error {
 	return err
 }
 
+// Dels delete keys, in cluster mode with a DEL per slot
 func (service *Service) Dels(keys []string) error {
-	conn := service.pool.Get()
-	defer conn.Close()
-
 	if keys == nil || len(keys) <= 0 {
 		return nil
 	}
+	if groups := service.slotGroups(keys); groups != nil {
+		b := NewBatch()
+		for _, g := range groups {
+			list := make([]interface{}, len(g))
+			for j, k := range g {
+				list[j] = keys[k]
+			}
+			b.Queue("del", list...)
+		}
+		if err := service.Pipeline(b); err != nil {
+			return err
+		}
+		return batchErr(b)
+	}
+
+	conn := service.pool.Get()
+	defer conn.Close()
 
 	var list []interface{}
 	for _, v := range keys {
@@ -207,24 +347,11 @@ func (service *Service) Dels(keys []string) error {
 	return nil
 }
 
+// Keys list the keys matching keyFormat with SCAN, it gives up after KeysLimit keys
 func (service *Service) Keys(keyFormat string) ([][]byte, error) {
-	conn := service.pool.Get()
-	defer conn.Close()
-
-	reply, err := conn.Do("keys", keyFormat)
-
-	res := [][]byte{}
-	if nil != err {
-		return res, err
-	} else if nil != reply {
-		rs := reply.([]interface{})
-		for _, r := range rs {
-			if nil != r {
-				res = append(res, r.([]byte))
-			}
-		}
-	}
-	return res, err
+	return collectKeys(func(fn ScanFunc) error {
+		return service.Scan(context.Background(), ScanOptions{Match: keyFormat}, fn)
+	})
 }
 
 // -----------------set operation---------------------
@@ -237,16 +364,7 @@ func (service *Service) SAdd(key string, ttl int64, members ...[]byte) error {
 	for _, v := range members {
 		vs = append(vs, v)
 	}
-	_, err := conn.Do("sadd", vs...)
-	if nil != err {
-		return err
-	}
-	if ttl > 0 {
-		if _, err := conn.Do("expire", key, ttl); err != nil {
-
-			return err
-		}
-	}
+	_, err := doWithExpire(conn, key, ttl, "sadd", vs...)
 	return err
 }
 
@@ -270,21 +388,21 @@ func (service *Service) SRem(key string, members ...[]byte) (int64, error) {
 	}
 }
 
-func (service *Service) SetNX(key string, value int64, ttl int64) error {
+// SetNX set key only when it does not exist and report whether it was set
+func (service *Service) SetNX(key string, value int64, ttl int64) (bool, error) {
 	conn := service.pool.Get()
 	defer conn.Close()
-	if _, err := conn.Do("setnx", key, value); err != nil {
-
-		return err
-	}
 
+	vs := []interface{}{key, value, "nx"}
 	if ttl > 0 {
-		if _, err := conn.Do("expire", key, ttl); err != nil {
+		vs = append(vs, "ex", ttl)
+	}
+	reply, err := conn.Do("set", vs...)
+	if err != nil {
 
-			return err
-		}
+		return false, err
 	}
-	return nil
+	return reply != nil, nil
 }
 
 func (service *Service) SCard(key string) (int64, error) {
@@ -346,16 +464,7 @@ func (service *Service) ZAdd(key string, ttl int64, args ...[]byte) error {
 	for _, v := range args {
 		vs = append(vs, v)
 	}
-	_, err := conn.Do("zadd", vs...)
-	if nil != err {
-		return err
-	}
-	if ttl > 0 {
-		if _, err := conn.Do("expire", key, ttl); err != nil {
-
-			return err
-		}
-	}
+	_, err := doWithExpire(conn, key, ttl, "zadd", vs...)
 	return err
 }
 
@@ -542,16 +651,7 @@ func (service *Service) HSet(key string, ttl int64, field string, value []byte)
 	vs = append(vs, field)
 	vs = append(vs, value)
 
-	_, err := conn.Do("hset", vs...)
-	if nil != err {
-		return err
-	}
-	if ttl > 0 {
-		if _, err := conn.Do("expire", key, ttl); err != nil {
-
-			return err
-		}
-	}
+	_, err := doWithExpire(conn, key, ttl, "hset", vs...)
 	return err
 }
 
@@ -588,16 +688,7 @@ func (service *Service) HMSet(key string, ttl int64, args ...[]byte) error {
 	for _, v := range args {
 		vs = append(vs, v)
 	}
-	_, err := conn.Do("hmset", vs...)
-	if nil != err {
-		return err
-	}
-	if ttl > 0 {
-		if _, err := conn.Do("expire", key, ttl); err != nil {
-
-			return err
-		}
-	}
+	_, err := doWithExpire(conn, key, ttl, "hmset", vs...)
 	return err
 }
 
@@ -815,7 +906,7 @@ func (service *Service) LIndex(key string, index int64) ([]byte, error) {
 	vs = append(vs, key)
 	vs = append(vs, index)
 
-	reply, err := conn.Do("lindex", vs)
+	reply, err := conn.Do("lindex", vs...)
 
 	if nil != err {
 
@@ -0,0 +1,443 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// backend is a ServiceI under test with a way to move its clock forward
+type backend struct {
+	name    string
+	service ServiceI
+	advance func(d time.Duration)
+}
+
+// newMemoryBackend return a MemoryService on a fake clock
+func newMemoryBackend() backend {
+	m := NewMemoryService()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	return backend{name: "memory", service: m, advance: func(d time.Duration) {
+		m.mu.Lock()
+		now = now.Add(d)
+		m.mu.Unlock()
+	}}
+}
+
+// newRedisBackend return a Service connected to an in-process redis server
+func newRedisBackend(t *testing.T) backend {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	s := &Service{}
+	s.Initialize(Config{Host: host, Port: port, MaxIdle: 2})
+	t.Cleanup(func() {
+		s.Close()
+		mr.Close()
+	})
+	return backend{name: "redis", service: s, advance: mr.FastForward}
+}
+
+// backends return every ServiceI implementation, so that a test pins the behavior
+// MemoryService has to share with Service
+func backends(t *testing.T) []backend {
+	return []backend{newMemoryBackend(), newRedisBackend(t)}
+}
+
+func eachBackend(t *testing.T, fn func(t *testing.T, b backend)) {
+	for _, b := range backends(t) {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			fn(t, b)
+		})
+	}
+}
+
+func TestStrings(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		check(t, s.Set("a", []byte("1"), 0))
+		check(t, s.Set("b", []byte("2"), 10))
+
+		equal(t, "get a", mustBytes(s.Get("a")), []byte("1"))
+		equal(t, "get missing", mustBytes(s.Get("missing")), []byte{})
+		equal(t, "exists a", mustBool(s.Exists("a")), true)
+		equal(t, "exists missing", mustBool(s.Exists("missing")), false)
+
+		check(t, s.Mset([][2][]byte{{[]byte("c"), []byte("3")}, {[]byte("d"), []byte("4")}}, 0))
+		equal(t, "mget", mustList(s.Mget([]string{"a", "missing", "c", "d"})), [][]byte{[]byte("1"), nil, []byte("3"), []byte("4")})
+		equal(t, "mget nothing", mustList(s.Mget(nil)), [][]byte{})
+
+		check(t, s.Del("a"))
+		check(t, s.Dels([]string{"c", "d", "missing"}))
+		equal(t, "exists a after del", mustBool(s.Exists("a")), false)
+		equal(t, "mget after dels", mustList(s.Mget([]string{"c", "d"})), [][]byte{nil, nil})
+
+		equal(t, "setnx new", mustBool(s.SetNX("n", 1, 10)), true)
+		equal(t, "setnx taken", mustBool(s.SetNX("n", 2, 10)), false)
+		equal(t, "get setnx", mustBytes(s.Get("n")), []byte("1"))
+
+		// a key holding another type
+		check(t, s.SAdd("set", 0, []byte("m")))
+		if _, err := s.Get("set"); err == nil {
+			t.Error("get a set, want a wrong type error")
+		}
+	})
+}
+
+func TestExpiry(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		check(t, s.Set("short", []byte("v"), 10))
+		check(t, s.Set("long", []byte("v"), 100))
+		check(t, s.Set("forever", []byte("v"), 0))
+		check(t, s.Mset([][2][]byte{{[]byte("m1"), []byte("v")}, {[]byte("m2"), []byte("v")}}, 10))
+		check(t, s.SAdd("set", 10, []byte("m")))
+		check(t, s.HSet("hash", 10, "f", []byte("v")))
+		check(t, s.ZAdd("zset", 10, []byte("1"), []byte("m")))
+
+		b.advance(9 * time.Second)
+		equal(t, "short before ttl", mustBool(s.Exists("short")), true)
+
+		b.advance(2 * time.Second)
+		for _, key := range []string{"short", "m1", "m2", "set", "hash", "zset"} {
+			equal(t, key+" after ttl", mustBool(s.Exists(key)), false)
+		}
+		equal(t, "long after 11s", mustBool(s.Exists("long")), true)
+		equal(t, "forever", mustBool(s.Exists("forever")), true)
+
+		// set replaces the ttl of an existing key
+		check(t, s.Set("long", []byte("v"), 0))
+		b.advance(200 * time.Second)
+		equal(t, "long after set without ttl", mustBool(s.Exists("long")), true)
+
+		ok, err := s.SetNXBytes("lock", []byte("owner"), 1500*time.Millisecond)
+		check(t, err)
+		equal(t, "setnx bytes", ok, true)
+		b.advance(time.Second)
+		equal(t, "lock before ttl", mustBool(s.Exists("lock")), true)
+		b.advance(time.Second)
+		equal(t, "lock after ttl", mustBool(s.Exists("lock")), false)
+	})
+}
+
+func TestKeysAndScan(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		for i := 0; i < 30; i++ {
+			check(t, s.Set(fmt.Sprintf("user:%d", i), []byte("v"), 0))
+		}
+		check(t, s.Set("order:1", []byte("v"), 0))
+		check(t, s.Set("user:gone", []byte("v"), 1))
+		b.advance(2 * time.Second)
+
+		keys := sorted(mustList(s.Keys("user:*")))
+		if len(keys) != 30 {
+			t.Fatalf("keys user:* = %d keys, want 30", len(keys))
+		}
+		equal(t, "keys order:*", mustList(s.Keys("order:*")), [][]byte{[]byte("order:1")})
+		equal(t, "keys none", len(mustList(s.Keys("none:*"))), 0)
+
+		var scanned [][]byte
+		err := s.Scan(context.Background(), ScanOptions{Match: "user:*", Count: 7}, func(keys [][]byte) error {
+			scanned = append(scanned, keys...)
+			return nil
+		})
+		check(t, err)
+		equal(t, "scan user:*", sorted(unique(scanned)), keys)
+
+		n, err := s.DeleteByPattern(context.Background(), "user:1*", 5)
+		check(t, err)
+		equal(t, "delete user:1*", n, int64(11))
+		equal(t, "keys after delete", len(mustList(s.Keys("user:*"))), 19)
+		equal(t, "order kept", mustBool(s.Exists("order:1")), true)
+	})
+}
+
+func TestSets(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		check(t, s.SAdd("s", 0, []byte("a"), []byte("b"), []byte("c")))
+		check(t, s.SAdd("s", 0, []byte("a")))
+		equal(t, "scard", mustInt(s.SCard("s")), int64(3))
+		equal(t, "sismember a", mustBool(s.SIsMember("s", []byte("a"))), true)
+		equal(t, "sismember z", mustBool(s.SIsMember("s", []byte("z"))), false)
+		equal(t, "smembers", sorted(mustList(s.SMembers("s"))), [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		equal(t, "srem", mustInt(s.SRem("s", []byte("a"), []byte("z"))), int64(1))
+		equal(t, "scard missing", mustInt(s.SCard("missing")), int64(0))
+		equal(t, "smembers missing", len(mustList(s.SMembers("missing"))), 0)
+
+		var members [][]byte
+		check(t, s.SScan(context.Background(), "s", ScanOptions{}, func(keys [][]byte) error {
+			members = append(members, keys...)
+			return nil
+		}))
+		equal(t, "sscan", sorted(members), [][]byte{[]byte("b"), []byte("c")})
+
+		// the key goes with its last member
+		equal(t, "srem all", mustInt(s.SRem("s", []byte("b"), []byte("c"))), int64(2))
+		equal(t, "exists empty set", mustBool(s.Exists("s")), false)
+	})
+}
+
+func TestSortedSets(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		check(t, s.ZAdd("z", 0, []byte("3"), []byte("c"), []byte("1"), []byte("a"), []byte("2"), []byte("b")))
+		equal(t, "zcard", mustInt(s.ZCard("z")), int64(3))
+		equal(t, "zrange", mustList(s.ZRange("z", 0, -1, false)), list("a", "b", "c"))
+		equal(t, "zrange scores", mustList(s.ZRange("z", 0, 1, true)), list("a", "1", "b", "2"))
+		equal(t, "zrevrange", mustList(s.ZRevRange("z", 0, 0, false)), list("c"))
+		equal(t, "zrangebyscore", mustList(s.ZRangeByScore("z", 2, "+inf", false)), list("b", "c"))
+		equal(t, "zrangebyscore open", mustList(s.ZRangeByScore("z", "(1", 2, true)), list("b", "2"))
+		equal(t, "zrank", mustInt(s.ZRank("z", []byte("b"))), int64(1))
+		equal(t, "zrevrank", mustInt(s.ZRevRank("z", []byte("b"))), int64(1))
+
+		// a score update moves the member
+		check(t, s.ZAdd("z", 0, []byte("0"), []byte("c")))
+		equal(t, "zrange after update", mustList(s.ZRange("z", 0, -1, false)), list("c", "a", "b"))
+
+		equal(t, "zrem", mustInt(s.ZRem("z", []byte("a"), []byte("missing"))), int64(1))
+		equal(t, "zremrangebyscore", mustInt(s.ZRemRangeByScore("z", 0, 1)), int64(1))
+		equal(t, "zrange after removals", mustList(s.ZRange("z", 0, -1, false)), list("b"))
+		equal(t, "zcard missing", mustInt(s.ZCard("missing")), int64(0))
+
+		var scanned [][]byte
+		check(t, s.ZScan(context.Background(), "z", ScanOptions{}, func(keys [][]byte) error {
+			scanned = append(scanned, keys...)
+			return nil
+		}))
+		equal(t, "zscan", scanned, list("b", "2"))
+	})
+}
+
+func TestMissingRank(t *testing.T) {
+	var results []interface{}
+	for _, b := range backends(t) {
+		check(t, b.service.ZAdd("z", 0, []byte("1"), []byte("a")))
+		rank, err := b.service.ZRank("z", []byte("missing"))
+		results = append(results, fmt.Sprint(rank, err != nil))
+	}
+	equal(t, "zrank of a missing member, memory then redis", results[0], results[1])
+}
+
+func TestHashes(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		check(t, s.HSet("h", 0, "a", []byte("1")))
+		check(t, s.HMSet("h", 0, []byte("b"), []byte("2"), []byte("c"), []byte("3")))
+		equal(t, "hget", mustBytes(s.HGet("h", []byte("a"))), []byte("1"))
+		// missing fields are empty, unlike Mget
+		equal(t, "hmget", mustList(s.HMGet("h", []byte("b"), []byte("missing"), []byte("c"))), list("2", "", "3"))
+		equal(t, "hexists", mustBool(s.HExists("h", []byte("a"))), true)
+		equal(t, "hexists missing", mustBool(s.HExists("h", []byte("z"))), false)
+		equal(t, "hlen", mustInt(s.HLen("h")), int64(3))
+		equal(t, "hkeys", sorted(mustList(s.HKeys("h"))), list("a", "b", "c"))
+		equal(t, "hvals", sorted(mustList(s.HVals("h"))), list("1", "2", "3"))
+		equal(t, "hgetall", pairs(mustList(s.HGetAll("h"))), map[string]string{"a": "1", "b": "2", "c": "3"})
+		equal(t, "hdel", mustInt(s.HDel("h", []byte("a"), []byte("z"))), int64(1))
+		equal(t, "hlen after hdel", mustInt(s.HLen("h")), int64(2))
+
+		var scanned [][]byte
+		check(t, s.HScan(context.Background(), "h", ScanOptions{}, func(keys [][]byte) error {
+			scanned = append(scanned, keys...)
+			return nil
+		}))
+		equal(t, "hscan", pairs(scanned), map[string]string{"b": "2", "c": "3"})
+	})
+}
+
+func TestMissingHashField(t *testing.T) {
+	var results []string
+	for _, b := range backends(t) {
+		check(t, b.service.HSet("h", 0, "a", []byte("1")))
+		v, err := b.service.HGet("h", []byte("missing"))
+		results = append(results, fmt.Sprintf("%q %v", v, err != nil))
+	}
+	equal(t, "hget of a missing field, memory then redis", results[0], results[1])
+}
+
+func TestLists(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		// LRpush is LPUSH and LLpush is RPUSH, as they always were
+		equal(t, "lrpush", mustInt(s.LRpush("l", []byte("b"), []byte("c"))), int64(2))
+		equal(t, "llpush", mustInt(s.LLpush("l", []byte("a"))), int64(3))
+		equal(t, "llen", mustInt(s.LLlen("l")), int64(3))
+		equal(t, "lindex", mustBytes(s.LIndex("l", 0)), []byte("c"))
+		equal(t, "lindex last", mustBytes(s.LIndex("l", -1)), []byte("a"))
+		equal(t, "lpop", mustBytes(s.LLpop("l")), []byte("c"))
+		equal(t, "rpop", mustBytes(s.LRpop("l")), []byte("a"))
+		equal(t, "rpop last", mustBytes(s.LRpop("l")), []byte("b"))
+		equal(t, "exists empty list", mustBool(s.Exists("l")), false)
+		equal(t, "llen missing", mustInt(s.LLlen("l")), int64(0))
+	})
+}
+
+func TestEmptyPop(t *testing.T) {
+	var results []string
+	for _, b := range backends(t) {
+		v, err := b.service.LLpop("missing")
+		results = append(results, fmt.Sprintf("%q %v", v, err != nil))
+	}
+	equal(t, "pop of a missing list, memory then redis", results[0], results[1])
+}
+
+func TestCompareAnd(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		ok, err := s.SetNXBytes("k", []byte("token"), time.Second)
+		check(t, err)
+		equal(t, "setnx bytes", ok, true)
+		ok, err = s.SetNXBytes("k", []byte("other"), time.Second)
+		check(t, err)
+		equal(t, "setnx bytes taken", ok, false)
+
+		equal(t, "expire with another value", mustBool(s.CompareAndExpire("k", []byte("other"), time.Minute)), false)
+		equal(t, "expire with the value", mustBool(s.CompareAndExpire("k", []byte("token"), time.Minute)), true)
+		b.advance(30 * time.Second)
+		equal(t, "exists after the extended ttl started", mustBool(s.Exists("k")), true)
+
+		equal(t, "delete with another value", mustBool(s.CompareAndDelete("k", []byte("other"))), false)
+		equal(t, "delete with the value", mustBool(s.CompareAndDelete("k", []byte("token"))), true)
+		equal(t, "delete again", mustBool(s.CompareAndDelete("k", []byte("token"))), false)
+		equal(t, "expire missing", mustBool(s.CompareAndExpire("k", []byte("token"), time.Minute)), false)
+	})
+}
+
+func TestRateLimits(t *testing.T) {
+	eachBackend(t, func(t *testing.T, b backend) {
+		s := b.service
+		for i := 0; i < 3; i++ {
+			res, err := s.SlidingWindow("sw", 3, time.Minute)
+			check(t, err)
+			if !res.Allowed || res.Remaining != int64(2-i) {
+				t.Errorf("sliding window request %d = %+v, want allowed with %d remaining", i, res, 2-i)
+			}
+		}
+		res, err := s.SlidingWindow("sw", 3, time.Minute)
+		check(t, err)
+		if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > time.Minute {
+			t.Errorf("sliding window over the limit = %+v, want denied with a retry after", res)
+		}
+
+		for i := 0; i < 2; i++ {
+			res, err := s.TokenBucket("tb", 2, time.Minute)
+			check(t, err)
+			if !res.Allowed {
+				t.Errorf("token bucket request %d = %+v, want allowed", i, res)
+			}
+		}
+		res, err = s.TokenBucket("tb", 2, time.Minute)
+		check(t, err)
+		if res.Allowed || res.RetryAfter <= 0 {
+			t.Errorf("token bucket over the capacity = %+v, want denied with a retry after", res)
+		}
+	})
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func equal(t *testing.T, what string, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %s, want %s", what, show(got), show(want))
+	}
+}
+
+// show print the byte slices as strings
+func show(v interface{}) string {
+	switch value := v.(type) {
+	case []byte:
+		return fmt.Sprintf("%q", value)
+	case [][]byte:
+		s := make([]string, len(value))
+		for i, b := range value {
+			if b == nil {
+				s[i] = "nil"
+			} else {
+				s[i] = fmt.Sprintf("%q", b)
+			}
+		}
+		return fmt.Sprint(s)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func list(values ...string) [][]byte {
+	l := make([][]byte, len(values))
+	for i, v := range values {
+		l[i] = []byte(v)
+	}
+	return l
+}
+
+func sorted(l [][]byte) [][]byte {
+	l = append([][]byte(nil), l...)
+	sort.Slice(l, func(i, j int) bool { return bytes.Compare(l[i], l[j]) < 0 })
+	return l
+}
+
+// unique drop the duplicates SCAN may return
+func unique(l [][]byte) [][]byte {
+	seen := make(map[string]bool)
+	var u [][]byte
+	for _, b := range l {
+		if !seen[string(b)] {
+			seen[string(b)] = true
+			u = append(u, b)
+		}
+	}
+	return u
+}
+
+func pairs(l [][]byte) map[string]string {
+	m := make(map[string]string)
+	for i := 0; i+1 < len(l); i += 2 {
+		m[string(l[i])] = string(l[i+1])
+	}
+	return m
+}
+
+func mustBytes(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func mustList(l [][]byte, err error) [][]byte {
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+func mustBool(b bool, err error) bool {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func mustInt(n int64, err error) int64 {
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,262 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// streamBlockSlice bound a single blocking XREADGROUP so that the context is checked in between
+	streamBlockSlice = time.Second
+	// defaultPendingCount is used by XPending when count is not positive
+	defaultPendingCount = 100
+)
+
+// StreamMessage is an entry of a stream
+type StreamMessage struct {
+	ID     string
+	Values map[string][]byte
+}
+
+// PendingMessage is an entry delivered to a consumer of a group and not acknowledged yet
+type PendingMessage struct {
+	ID         string
+	Consumer   string
+	Idle       time.Duration
+	Deliveries int64
+}
+
+// StreamI append to streams and consume them through consumer groups
+type StreamI interface {
+	// XAdd append values to stream and return the generated id, maxLen > 0 trims the stream approximately
+	XAdd(stream string, maxLen int64, values map[string][]byte) (string, error)
+	XLen(stream string) (int64, error)
+	// XTrim keep the last maxLen entries of stream and return the number of removed entries
+	XTrim(stream string, maxLen int64, approx bool) (int64, error)
+	// XGroupCreate create group reading from start ("$" for new entries, "0" for the whole stream),
+	// the stream is created if missing and an existing group is not an error
+	XGroupCreate(stream, group, start string) error
+	// XReadGroup read up to count entries never delivered to group,
+	// waiting up to block for new ones when block > 0
+	XReadGroup(ctx context.Context, group, consumer, stream string, count int64, block time.Duration) ([]StreamMessage, error)
+	XAck(stream, group string, ids ...string) (int64, error)
+	// XPending return up to count pending entries of group idle for at least minIdle
+	XPending(stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error)
+	// XClaim give the pending entries idle for at least minIdle to consumer and return them
+	XClaim(stream, group, consumer string, minIdle time.Duration, ids ...string) ([]StreamMessage, error)
+}
+
+// -----------------stream operation------------------
+func (service *Service) XAdd(stream string, maxLen int64, values map[string][]byte) (string, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{stream}
+	if maxLen > 0 {
+		args = args.Add("maxlen", "~", maxLen)
+	}
+	args = args.Add("*")
+	for _, field := range sortedFields(values) {
+		args = args.Add(field, values[field])
+	}
+	return redis.String(conn.Do("xadd", args...))
+}
+
+func (service *Service) XLen(stream string) (int64, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(conn.Do("xlen", stream))
+}
+
+func (service *Service) XTrim(stream string, maxLen int64, approx bool) (int64, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{stream, "maxlen"}
+	if approx {
+		args = args.Add("~")
+	}
+	return redis.Int64(conn.Do("xtrim", args.Add(maxLen)...))
+}
+
+func (service *Service) XGroupCreate(stream, group, start string) error {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("xgroup", "create", stream, group, start, "mkstream")
+	if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// XReadGroup block in slices of streamBlockSlice, so ctx is honored within a second
+func (service *Service) XReadGroup(ctx context.Context, group, consumer, stream string, count int64, block time.Duration) ([]StreamMessage, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	deadline := time.Now().Add(block)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		args := redis.Args{"group", group, consumer}
+		if count > 0 {
+			args = args.Add("count", count)
+		}
+		wait := time.Until(deadline)
+		if wait > streamBlockSlice {
+			wait = streamBlockSlice
+		}
+		if block > 0 && wait > 0 {
+			// a block of 0 waits forever, so round up to one millisecond
+			ms := (wait + time.Millisecond - 1) / time.Millisecond
+			args = args.Add("block", int64(ms))
+		}
+		args = args.Add("streams", stream, ">")
+		var (
+			reply interface{}
+			err   error
+		)
+		if readTimeout := service.config.ReadTimeout; readTimeout > 0 && block > 0 {
+			// the server holds the reply for the block duration, so the read deadline has to wait as long
+			reply, err = redis.DoWithTimeout(conn, readTimeout+wait, "xreadgroup", args...)
+		} else {
+			reply, err = conn.Do("xreadgroup", args...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if reply != nil {
+			streams, err := redis.Values(reply, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range streams {
+				kv, err := redis.Values(s, nil)
+				if err != nil || len(kv) != 2 {
+					return nil, fmt.Errorf("redis: unexpected xreadgroup reply %v", s)
+				}
+				messages, err := parseStreamMessages(kv[1])
+				if err != nil || len(messages) > 0 {
+					return messages, err
+				}
+			}
+		}
+		if block <= 0 || !time.Now().Before(deadline) {
+			return nil, nil
+		}
+	}
+}
+
+func (service *Service) XAck(stream, group string, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(conn.Do("xack", redis.Args{stream, group}.AddFlat(ids)...))
+}
+
+// XPending filter on idle time itself, the IDLE option of XPENDING needs redis 6.2
+func (service *Service) XPending(stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error) {
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	if count <= 0 {
+		count = defaultPendingCount
+	}
+	values, err := redis.Values(conn.Do("xpending", stream, group, "-", "+", count))
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]PendingMessage, 0, len(values))
+	for _, v := range values {
+		fields, err := redis.Values(v, nil)
+		if err != nil || len(fields) != 4 {
+			return nil, fmt.Errorf("redis: unexpected xpending reply %v", v)
+		}
+		id, _ := redis.String(fields[0], nil)
+		consumer, _ := redis.String(fields[1], nil)
+		idle, _ := redis.Int64(fields[2], nil)
+		deliveries, _ := redis.Int64(fields[3], nil)
+		p := PendingMessage{
+			ID:         id,
+			Consumer:   consumer,
+			Idle:       time.Duration(idle) * time.Millisecond,
+			Deliveries: deliveries,
+		}
+		if p.Idle >= minIdle {
+			pending = append(pending, p)
+		}
+	}
+	return pending, nil
+}
+
+func (service *Service) XClaim(stream, group, consumer string, minIdle time.Duration, ids ...string) ([]StreamMessage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	conn := service.pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{stream, group, consumer, int64(minIdle / time.Millisecond)}.AddFlat(ids)
+	reply, err := conn.Do("xclaim", args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseStreamMessages(reply)
+}
+
+// parseStreamMessages decode [[id, [field, value, ...]], ...], entries deleted from the stream come as nil and are skipped
+func parseStreamMessages(reply interface{}) ([]StreamMessage, error) {
+	entries, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]StreamMessage, 0, len(entries))
+	for _, e := range entries {
+		if e == nil {
+			continue
+		}
+		kv, err := redis.Values(e, nil)
+		if err != nil || len(kv) != 2 {
+			return nil, fmt.Errorf("redis: unexpected stream entry %v", e)
+		}
+		id, err := redis.String(kv[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		if kv[1] == nil {
+			continue
+		}
+		fields, err := redis.ByteSlices(kv[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string][]byte, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			values[string(fields[i])] = fields[i+1]
+		}
+		messages = append(messages, StreamMessage{ID: id, Values: values})
+	}
+	return messages, nil
+}
+
+func sortedFields(values map[string][]byte) []string {
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"template_project/config"
+	"template_project/logger"
+	"template_project/middleware"
+	"template_project/utils/apperr"
+	"template_project/utils/constant"
+	"template_project/utils/render"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type setLogLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+	// TTL revert the level after it, e.g. "15m", logger.level_ttl when absent and never when "0"
+	TTL *string `json:"ttl"`
+}
+
+// ListLogLevels return the level of every log module, behind middleware.Require("logs:read")
+func ListLogLevels(c *gin.Context) {
+	render.RespJson(c, constant.Success, "ok", logger.Levels())
+}
+
+// SetLogLevel change the level of the :module log module at runtime, behind middleware.Require("logs:write")
+func SetLogLevel(c *gin.Context) {
+	var req setLogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Error(c, apperr.Binding(err))
+		return
+	}
+	ttl := config.GetConfig().Logger.LevelTTL.Duration
+	if req.TTL != nil {
+		d, err := time.ParseDuration(*req.TTL)
+		if err != nil || d < 0 {
+			render.Error(c, apperr.ErrParams.WithDetails(apperr.Detail{Field: "ttl", Message: "should be a duration such as 15m"}))
+			return
+		}
+		ttl = d
+	}
+
+	state, err := logger.SetLevel(c.Param("module"), req.Level, ttl)
+	if err != nil {
+		logLevelFailure(c, err)
+		return
+	}
+	logger.FromContext(c).Warn("log level of %s set to %s by %s for %v", state.Module, state.Level,
+		middleware.MustPrincipal(c).Subject, ttl)
+	render.RespJson(c, constant.Success, "ok", state)
+}
+
+// ResetLogLevel put the :module log module back to its configured level, behind middleware.Require("logs:write")
+func ResetLogLevel(c *gin.Context) {
+	state, err := logger.ResetLevel(c.Param("module"))
+	if err != nil {
+		logLevelFailure(c, err)
+		return
+	}
+	logger.FromContext(c).Warn("log level of %s reset to %s by %s", state.Module, state.Level,
+		middleware.MustPrincipal(c).Subject)
+	render.RespJson(c, constant.Success, "ok", state)
+}
+
+func logLevelFailure(c *gin.Context, err error) {
+	switch err {
+	case logger.ErrUnknownModule:
+		render.Error(c, apperr.ErrNotFound.WithMessage("%s %q", err.Error(), c.Param("module")))
+	case logger.ErrUnknownLevel:
+		render.Error(c, apperr.ErrParams.WithDetails(apperr.Detail{
+			Field:   "level",
+			Message: "should be one of panic, fatal, error, warn, info, debug",
+		}))
+	default:
+		render.Error(c, err)
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"template_project/auth"
+	"template_project/config"
+	"template_project/logger"
+	"template_project/middleware"
+	"template_project/utils/constant"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupLogLevels return the router of the log levels as an admin would reach it, with logger.level_ttl set to ttl
+func setupLogLevels(t *testing.T, ttl time.Duration) *gin.Engine {
+	config.Lock.Lock()
+	saved := config.Cfg.Logger.LevelTTL
+	config.Cfg.Logger.LevelTTL = config.Duration{Duration: ttl}
+	config.Lock.Unlock()
+	t.Cleanup(func() {
+		config.Lock.Lock()
+		config.Cfg.Logger.LevelTTL = saved
+		config.Lock.Unlock()
+		logger.ResetLevel(logger.ModuleMySQL)
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.PrincipalKey, &auth.Principal{Subject: "1", UserID: 1})
+	})
+	r.GET("/log-levels", ListLogLevels)
+	r.PUT("/log-levels/:module", SetLogLevel)
+	r.DELETE("/log-levels/:module", ResetLogLevel)
+	return r
+}
+
+func levelState(t *testing.T, resp testResponse) logger.LevelState {
+	t.Helper()
+	var s logger.LevelState
+	if err := json.Unmarshal(resp.Data, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSetLogLevel(t *testing.T) {
+	r := setupLogLevels(t, time.Hour)
+
+	status, resp := call(r, http.MethodPut, "/log-levels/mysql", gin.H{"level": "warn"})
+	if status != http.StatusOK || resp.Code != constant.Success {
+		t.Fatalf("set = %d %d", status, resp.Code)
+	}
+	// logger.level_ttl when the request has no ttl
+	s := levelState(t, resp)
+	if s.Module != logger.ModuleMySQL || s.Level != "warn" || s.ExpiresAt == nil || time.Until(*s.ExpiresAt) < 59*time.Minute {
+		t.Errorf("state = %+v, want warn for an hour", s)
+	}
+
+	status, resp = call(r, http.MethodPut, "/log-levels/mysql", gin.H{"level": "error", "ttl": "0"})
+	if s = levelState(t, resp); status != http.StatusOK || s.Level != "error" || s.ExpiresAt != nil {
+		t.Errorf("set without expiry = %d %+v", status, s)
+	}
+
+	status, resp = call(r, http.MethodGet, "/log-levels", nil)
+	var states []logger.LevelState
+	json.Unmarshal(resp.Data, &states)
+	found := false
+	for _, s := range states {
+		if s.Module == logger.ModuleMySQL {
+			found = s.Level == "error"
+		}
+	}
+	if status != http.StatusOK || !found {
+		t.Errorf("list = %d %+v, want mysql at error", status, states)
+	}
+
+	status, resp = call(r, http.MethodDelete, "/log-levels/mysql", nil)
+	if s = levelState(t, resp); status != http.StatusOK || s.Level == "error" {
+		t.Errorf("reset = %d %+v", status, s)
+	}
+}
+
+func TestSetLogLevelInvalid(t *testing.T) {
+	r := setupLogLevels(t, 0)
+	tests := []struct {
+		path   string
+		body   interface{}
+		status int
+		code   int
+	}{
+		{"/log-levels/mysql", gin.H{"level": "verbose"}, http.StatusBadRequest, constant.ParamsError},
+		{"/log-levels/mysql", gin.H{}, http.StatusBadRequest, constant.ParamsError},
+		{"/log-levels/mysql", gin.H{"level": "debug", "ttl": "soon"}, http.StatusBadRequest, constant.ParamsError},
+		{"/log-levels/mysql", gin.H{"level": "debug", "ttl": "-1m"}, http.StatusBadRequest, constant.ParamsError},
+		{"/log-levels/kafka", gin.H{"level": "debug"}, http.StatusNotFound, constant.NotFound},
+	}
+	for _, tt := range tests {
+		status, resp := call(r, http.MethodPut, tt.path, tt.body)
+		if status != tt.status || resp.Code != tt.code {
+			t.Errorf("PUT %s %v = %d %d, want %d %d", tt.path, tt.body, status, resp.Code, tt.status, tt.code)
+		}
+	}
+	if status, _ := call(r, http.MethodDelete, "/log-levels/kafka", nil); status != http.StatusNotFound {
+		t.Errorf("reset of an unknown module = %d, want 404", status)
+	}
+	for _, s := range logger.Levels() {
+		if s.Module == logger.ModuleMySQL && s.ExpiresAt != nil {
+			t.Errorf("a rejected request changed the level of mysql: %+v", s)
+		}
+	}
+}
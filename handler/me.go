@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"template_project/middleware"
+	"template_project/utils/constant"
+	"template_project/utils/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Me return the principal of the request, behind middleware.RequireAuth
+func Me(c *gin.Context) {
+	render.RespJson(c, constant.Success, "ok", middleware.MustPrincipal(c))
+}
@@ -1,10 +1,10 @@
 package handler
 
 import (
-	"template_project/model"
+	"template_project/logger"
+	"template_project/utils/apperr"
+	"template_project/utils/constant"
 	"template_project/utils/render"
-	"fmt"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,29 +14,16 @@ type Result struct {
 }
 
 func Ping(c *gin.Context) {
-	fmt.Println("------------ping-----pong------")
+	logger.FromContext(c).Debug("ping")
 	render.RespJson(c, 0, "success", "----template_project----pong")
 }
 
 func TestPost(ctx *gin.Context)  {
 	var body interface{}
 	if err := ctx.ShouldBindJSON( &body); err != nil {
-		fmt.Println(err)
-		render.RespJson(ctx, http.StatusInternalServerError, err.Error(), "pong")
+		render.Error(ctx, apperr.Binding(err))
 		return
 	}
-	fmt.Println("post body:", body)
-	render.RespJson(ctx, http.StatusOK, "ok", body)
-}
-
-func GetUser(ctx *gin.Context)  {
-	dao := &model.User{}
-	user, err := dao.QueryUserById(1)
-	if err != nil {
-		fmt.Println("-------GetUser---:err", err)
-		render.RespJson(ctx, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	render.RespJson(ctx, http.StatusOK, "ok", user)
+	logger.FromContext(ctx).WithField("body", body).Debug("test post")
+	render.RespJson(ctx, constant.Success, "ok", body)
 }
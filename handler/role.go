@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"template_project/model"
+	"template_project/utils/constant"
+	"template_project/utils/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRoles return every role, behind middleware.Require("roles:read")
+func ListRoles(c *gin.Context) {
+	roles, err := (&model.Role{}).QueryAll()
+	if err != nil {
+		render.Error(c, err)
+		return
+	}
+	render.RespJson(c, constant.Success, "ok", roles)
+}
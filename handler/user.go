@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"template_project/model"
+	"template_project/utils/apperr"
+	"template_project/utils/constant"
+	"template_project/utils/render"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+type CreateUserRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+	Age  int    `json:"age" binding:"min=0,max=150"`
+}
+
+// UpdateUserRequest replace every field of a user
+type UpdateUserRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+	Age  *int   `json:"age" binding:"exists,min=0,max=150"`
+}
+
+// PatchUserRequest update the fields present in the body
+type PatchUserRequest struct {
+	Name *string `json:"name" binding:"omitempty,max=64"`
+	Age  *int    `json:"age" binding:"omitempty,min=0,max=150"`
+}
+
+type ListUsersRequest struct {
+	Name   string `form:"name"` // users whose name contains it
+	MinAge *int   `form:"min_age" binding:"omitempty,min=0"`
+	MaxAge *int   `form:"max_age" binding:"omitempty,min=0"`
+	Sort   string `form:"sort"` // id, name, age or created_at, prefixed by - for descending order
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+	Cursor string `form:"cursor"` // next_cursor of the previous page
+}
+
+type UserPage struct {
+	Items      []model.User `json:"items"`
+	Total      *int64       `json:"total,omitempty"` // only for offset pagination
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// userCursor is the opaque cursor of the users list, it remembers the sort it was made for
+type userCursor struct {
+	Sort string `json:"s"`
+	model.UserCursor
+}
+
+func CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Error(c, apperr.Binding(err))
+		return
+	}
+	name, ok := userName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	user := &model.User{Name: name, Age: req.Age}
+	if err := user.Create(); err != nil {
+		userFailure(c, err)
+		return
+	}
+	render.RespJson(c, constant.Success, "ok", user)
+}
+
+func GetUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+	user, err := (&model.User{}).QueryUserById(id)
+	if err != nil {
+		userFailure(c, err)
+		return
+	}
+	render.RespJson(c, constant.Success, "ok", user)
+}
+
+func UpdateUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Error(c, apperr.Binding(err))
+		return
+	}
+	name, ok := userName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	user, err := (&model.User{}).Update(id, map[string]interface{}{"name": name, "age": *req.Age})
+	if err != nil {
+		userFailure(c, err)
+		return
+	}
+	render.RespJson(c, constant.Success, "ok", user)
+}
+
+func PatchUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+	var req PatchUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Error(c, apperr.Binding(err))
+		return
+	}
+	fields := map[string]interface{}{}
+	if req.Name != nil {
+		name, ok := userName(c, *req.Name)
+		if !ok {
+			return
+		}
+		fields["name"] = name
+	}
+	if req.Age != nil {
+		fields["age"] = *req.Age
+	}
+
+	user, err := (&model.User{}).Update(id, fields)
+	if err != nil {
+		userFailure(c, err)
+		return
+	}
+	render.RespJson(c, constant.Success, "ok", user)
+}
+
+func DeleteUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+	deleted, err := (&model.User{}).Delete(id)
+	if err != nil {
+		userFailure(c, err)
+		return
+	}
+	if !deleted {
+		render.Error(c, apperr.ErrNotFound.WithMessage("user not found"))
+		return
+	}
+	render.RespJson(c, constant.Success, "ok", nil)
+}
+
+// ListUsers page the users with either limit/offset, which also returns the total,
+// or limit/cursor which stays stable while users are added
+func ListUsers(c *gin.Context) {
+	var req ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Error(c, apperr.Binding(err))
+		return
+	}
+	if req.Cursor != "" && req.Offset > 0 {
+		render.Error(c, apperr.ErrParams.WithMessage("use either cursor or offset"))
+		return
+	}
+	if req.MinAge != nil && req.MaxAge != nil && *req.MinAge > *req.MaxAge {
+		render.Error(c, apperr.ErrParams.WithMessage("min_age should not exceed max_age"))
+		return
+	}
+
+	q := model.UserQuery{
+		Name:   req.Name,
+		MinAge: req.MinAge,
+		MaxAge: req.MaxAge,
+		Sort:   strings.TrimPrefix(req.Sort, "-"),
+		Desc:   strings.HasPrefix(req.Sort, "-"),
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}
+	if q.Sort == "" {
+		q.Sort = "id"
+	} else if !validUserSort(q.Sort) {
+		render.Error(c, apperr.ErrParams.WithDetails(apperr.Detail{
+			Field:   "sort",
+			Message: "should be one of " + strings.Join(model.UserSortColumns, ", "),
+		}))
+		return
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultUserPageSize
+	}
+	if req.Cursor != "" {
+		cursor, err := decodeUserCursor(req.Cursor)
+		if err != nil || cursor.Sort != req.Sort {
+			render.Error(c, apperr.ErrParams.WithMessage("invalid cursor"))
+			return
+		}
+		q.After = &cursor.UserCursor
+	}
+
+	// one more user tells whether there is a next page
+	limit := q.Limit
+	q.Limit++
+	users, err := (&model.User{}).QueryUsers(q)
+	if err != nil {
+		userFailure(c, err)
+		return
+	}
+	page := UserPage{Items: users}
+	if len(users) > limit {
+		page.Items = users[:limit]
+		page.NextCursor = encodeUserCursor(userCursor{Sort: req.Sort, UserCursor: q.CursorOf(users[limit-1])})
+	}
+	if req.Cursor == "" {
+		total, err := (&model.User{}).CountUsers(q)
+		if err != nil {
+			userFailure(c, err)
+			return
+		}
+		page.Total = &total
+	}
+	render.RespJson(c, constant.Success, "ok", page)
+}
+
+// userId read the :id path parameter, it answers the request when invalid
+func userId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		render.Error(c, apperr.ErrParams.WithMessage("invalid user id"))
+		return 0, false
+	}
+	return id, true
+}
+
+// userName trim name, it answers the request when nothing is left
+func userName(c *gin.Context, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		render.Error(c, apperr.ErrParams.WithMessage("name should not be blank"))
+		return "", false
+	}
+	return name, true
+}
+
+func userFailure(c *gin.Context, err error) {
+	switch {
+	case gorm.IsRecordNotFoundError(err):
+		render.Error(c, apperr.ErrNotFound.WithMessage("user not found"))
+	case err == model.ErrDuplicateName:
+		render.Error(c, apperr.ErrAlreadyExists.WithMessage("%s", err.Error()).WithDetails(apperr.Detail{
+			Field:   "name",
+			Message: "is already taken",
+		}))
+	default:
+		render.Error(c, err)
+	}
+}
+
+func validUserSort(sort string) bool {
+	for _, column := range model.UserSortColumns {
+		if sort == column {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeUserCursor(cursor userCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(s string) (userCursor, error) {
+	var cursor userCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
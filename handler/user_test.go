@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"template_project/db/mysql/mysqltest"
+	"template_project/model"
+	"template_project/utils/constant"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupUsers return the router of the users resource on an empty user table
+func setupUsers(t *testing.T) *gin.Engine {
+	db := mysqltest.Use(t, &model.User{}, &model.UserRole{})
+	if err := db.Model(&model.User{}).AddUniqueIndex("uix_user_name", "name").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/users", CreateUser)
+	r.GET("/users", ListUsers)
+	r.GET("/users/:id", GetUser)
+	r.PUT("/users/:id", UpdateUser)
+	r.PATCH("/users/:id", PatchUser)
+	r.DELETE("/users/:id", DeleteUser)
+	return r
+}
+
+func call(r http.Handler, method, path string, body interface{}) (int, testResponse) {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, reader))
+	var resp testResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func userOf(t *testing.T, resp testResponse) model.User {
+	t.Helper()
+	var u model.User
+	if err := json.Unmarshal(resp.Data, &u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestUserCRUD(t *testing.T) {
+	r := setupUsers(t)
+
+	status, resp := call(r, http.MethodPost, "/users", gin.H{"name": "  ann ", "age": 30})
+	if status != http.StatusOK || resp.Code != constant.Success {
+		t.Fatalf("create = %d %d", status, resp.Code)
+	}
+	if u := userOf(t, resp); u.Id != 1 || u.Name != "ann" || u.Age != 30 || u.CreatedAt.IsZero() {
+		t.Errorf("created %+v", u)
+	}
+
+	status, resp = call(r, http.MethodGet, "/users/1", nil)
+	if u := userOf(t, resp); status != http.StatusOK || u.Name != "ann" {
+		t.Errorf("get = %d %+v", status, u)
+	}
+
+	status, resp = call(r, http.MethodPatch, "/users/1", gin.H{"age": 31})
+	if u := userOf(t, resp); status != http.StatusOK || u.Name != "ann" || u.Age != 31 {
+		t.Errorf("patch the age = %d %+v", status, u)
+	}
+	status, resp = call(r, http.MethodPut, "/users/1", gin.H{"name": "anna", "age": 0})
+	if u := userOf(t, resp); status != http.StatusOK || u.Name != "anna" || u.Age != 0 {
+		t.Errorf("put = %d %+v", status, u)
+	}
+
+	if status, resp = call(r, http.MethodDelete, "/users/1", nil); status != http.StatusOK {
+		t.Errorf("delete = %d %d", status, resp.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPatch, http.MethodDelete} {
+		if status, resp = call(r, method, "/users/1", gin.H{"age": 1}); status != http.StatusNotFound || resp.Code != constant.NotFound {
+			t.Errorf("%s of a deleted user = %d %d, want 404", method, status, resp.Code)
+		}
+	}
+}
+
+func TestUserInvalid(t *testing.T) {
+	r := setupUsers(t)
+	tests := []struct {
+		method, path string
+		body         interface{}
+	}{
+		{http.MethodPost, "/users", gin.H{"age": 3}},
+		{http.MethodPost, "/users", gin.H{"name": "   "}},
+		{http.MethodPost, "/users", gin.H{"name": "ann", "age": -1}},
+		{http.MethodPut, "/users/1", gin.H{"name": "ann"}},
+		{http.MethodGet, "/users/0", nil},
+		{http.MethodGet, "/users/ann", nil},
+		{http.MethodGet, "/users?sort=password", nil},
+		{http.MethodGet, "/users?limit=101", nil},
+		{http.MethodGet, "/users?min_age=9&max_age=8", nil},
+		{http.MethodGet, "/users?cursor=x&offset=1", nil},
+		{http.MethodGet, "/users?cursor=garbage", nil},
+	}
+	for _, tt := range tests {
+		if status, resp := call(r, tt.method, tt.path, tt.body); status != http.StatusBadRequest || resp.Code != constant.ParamsError {
+			t.Errorf("%s %s %v = %d %d, want 400", tt.method, tt.path, tt.body, status, resp.Code)
+		}
+	}
+}
+
+func TestUserDuplicateName(t *testing.T) {
+	r := setupUsers(t)
+	call(r, http.MethodPost, "/users", gin.H{"name": "ann"})
+	call(r, http.MethodPost, "/users", gin.H{"name": "bob"})
+
+	status, resp := call(r, http.MethodPost, "/users", gin.H{"name": "ann"})
+	if status != http.StatusConflict || resp.Code != constant.AlreadyExists {
+		t.Errorf("create a second ann = %d %d, want 409", status, resp.Code)
+	}
+	status, resp = call(r, http.MethodPatch, "/users/2", gin.H{"name": "ann"})
+	if status != http.StatusConflict || resp.Code != constant.AlreadyExists {
+		t.Errorf("rename bob to ann = %d %d, want 409", status, resp.Code)
+	}
+}
+
+func listUsers(t *testing.T, r http.Handler, query string) UserPage {
+	t.Helper()
+	status, resp := call(r, http.MethodGet, "/users"+query, nil)
+	if status != http.StatusOK {
+		t.Fatalf("list %s = %d %d", query, status, resp.Code)
+	}
+	var page UserPage
+	if err := json.Unmarshal(resp.Data, &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func names(users []model.User) []string {
+	var list []string
+	for _, u := range users {
+		list = append(list, u.Name)
+	}
+	return list
+}
+
+func TestListUsersOffset(t *testing.T) {
+	r := setupUsers(t)
+	for _, name := range []string{"dan", "ann", "cid", "bob", "eve"} {
+		call(r, http.MethodPost, "/users", gin.H{"name": name, "age": len(name) * 10})
+	}
+
+	page := listUsers(t, r, "?sort=-name&limit=2&offset=1")
+	if got := names(page.Items); !reflect.DeepEqual(got, []string{"dan", "cid"}) {
+		t.Errorf("offset page = %v", got)
+	}
+	if page.Total == nil || *page.Total != 5 {
+		t.Errorf("total = %v, want 5", page.Total)
+	}
+	page = listUsers(t, r, "?name=n")
+	if got := names(page.Items); !reflect.DeepEqual(got, []string{"dan", "ann"}) || *page.Total != 2 || page.NextCursor != "" {
+		t.Errorf("filtered page = %v total %d cursor %q", got, *page.Total, page.NextCursor)
+	}
+}
+
+func TestListUsersCursor(t *testing.T) {
+	r := setupUsers(t)
+	for _, name := range []string{"b", "d", "f", "h", "j"} {
+		call(r, http.MethodPost, "/users", gin.H{"name": name})
+	}
+
+	var seen []string
+	query := "?sort=name&limit=2"
+	for i := 0; i < 5; i++ {
+		page := listUsers(t, r, query)
+		seen = append(seen, names(page.Items)...)
+		if i > 0 && page.Total != nil {
+			t.Errorf("page %d has a total, only the first page counts", i)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query = "?sort=name&limit=2&cursor=" + page.NextCursor
+		// a user sorted before the cursor does not shift the next pages
+		call(r, http.MethodPost, "/users", gin.H{"name": "a" + page.Items[0].Name})
+	}
+	if want := []string{"b", "d", "f", "h", "j"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("pages = %v, want %v", seen, want)
+	}
+
+	// a cursor is only valid for the sort it was made for
+	page := listUsers(t, r, "?sort=name&limit=1")
+	if status, _ := call(r, http.MethodGet, "/users?sort=-name&cursor="+page.NextCursor, nil); status != http.StatusBadRequest {
+		t.Errorf("cursor of another sort = %d, want 400", status)
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"template_project/auth"
+	"template_project/utils/apperr"
+	"template_project/utils/constant"
+	"template_project/utils/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+type walletChallengeRequest struct {
+	Address string `json:"address" binding:"required"`
+}
+
+type walletLoginRequest struct {
+	Address   string `json:"address" binding:"required"`
+	Nonce     string `json:"nonce" binding:"required"`
+	Method    string `json:"method"` // personal_sign (default) or eip712
+	Signature string `json:"signature" binding:"required"`
+}
+
+// WalletChallenge return a one-time challenge for the wallet to sign
+func WalletChallenge(c *gin.Context) {
+	w := auth.GetWallet()
+	if w == nil {
+		render.Error(c, apperr.ErrNotFound.WithMessage("wallet login is disabled"))
+		return
+	}
+	var req walletChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Error(c, apperr.Binding(err))
+		return
+	}
+
+	challenge, err := w.Challenge(req.Address)
+	switch err {
+	case nil:
+		render.RespJson(c, constant.Success, "ok", challenge)
+	case auth.ErrInvalidAddress:
+		render.Error(c, apperr.ErrParams.WithMessage("%s", err.Error()))
+	default:
+		render.Error(c, err)
+	}
+}
+
+// WalletLogin verify the signed challenge and return a jwt session
+func WalletLogin(c *gin.Context) {
+	w := auth.GetWallet()
+	if w == nil {
+		render.Error(c, apperr.ErrNotFound.WithMessage("wallet login is disabled"))
+		return
+	}
+	var req walletLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Error(c, apperr.Binding(err))
+		return
+	}
+
+	session, err := w.Login(req.Address, req.Nonce, req.Method, req.Signature)
+	switch err {
+	case nil:
+		render.RespJson(c, constant.Success, "ok", session)
+	case auth.ErrInvalidAddress, auth.ErrSignMethod:
+		render.Error(c, apperr.ErrParams.WithMessage("%s", err.Error()))
+	case auth.ErrChallengeNotFound, auth.ErrNonceUsed, auth.ErrSignature:
+		render.Error(c, apperr.ErrSignature.WithMessage("%s", err.Error()))
+	default:
+		render.Error(c, err)
+	}
+}
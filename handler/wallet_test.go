@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"template_project/auth"
+	"template_project/config"
+	"template_project/db/redis"
+	"template_project/utils/constant"
+	"template_project/utils/eth"
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+)
+
+const testAddress = "0x5B38Da6a701c568545dCfcB03FcB875f56beddC4"
+
+type testResponse struct {
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+}
+
+// setupWallet enable the wallet login on a MemoryService and return its router
+func setupWallet(t *testing.T) (*gin.Engine, *redis.MemoryService) {
+	store := redis.NewMemoryService()
+	redis.DB = store
+	err := auth.Init(config.AuthConfig{
+		JWT:    config.JWTConfig{Enable: true, Algorithms: []string{"HS256"}, Secret: "test secret"},
+		Wallet: config.WalletConfig{Enable: true, Domain: "example.com", ChainID: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		redis.DB = nil
+		auth.Init(config.AuthConfig{})
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/wallet/challenge", WalletChallenge)
+	r.POST("/wallet/login", WalletLogin)
+	return r, store
+}
+
+func post(r http.Handler, path string, body interface{}) (int, testResponse) {
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	var resp testResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestWalletChallenge(t *testing.T) {
+	r, store := setupWallet(t)
+
+	status, resp := post(r, "/wallet/challenge", gin.H{"address": testAddress})
+	if status != http.StatusOK || resp.Code != constant.Success {
+		t.Fatalf("challenge = %d %d, want 200 success", status, resp.Code)
+	}
+	var challenge auth.Challenge
+	if err := json.Unmarshal(resp.Data, &challenge); err != nil {
+		t.Fatal(err)
+	}
+	if len(challenge.Nonce) != 24 || challenge.Address != testAddress {
+		t.Errorf("challenge = nonce %q address %s", challenge.Nonce, challenge.Address)
+	}
+
+	// the nonce is kept in redis until the challenge expires
+	exists, err := store.Exists("wallet:challenge:" + challenge.Nonce)
+	if err != nil || !exists {
+		t.Errorf("challenge stored = %v, %v, want true", exists, err)
+	}
+	keys, _ := store.Keys("wallet:challenge:*")
+	if len(keys) != 1 {
+		t.Errorf("stored challenges = %d, want 1", len(keys))
+	}
+}
+
+func TestWalletChallengeInvalid(t *testing.T) {
+	r, store := setupWallet(t)
+
+	for _, body := range []interface{}{gin.H{"address": "0x1234"}, gin.H{}} {
+		status, resp := post(r, "/wallet/challenge", body)
+		if status != http.StatusBadRequest || resp.Code != constant.ParamsError {
+			t.Errorf("challenge %v = %d %d, want 400 params error", body, status, resp.Code)
+		}
+	}
+	if keys, _ := store.Keys("*"); len(keys) != 0 {
+		t.Errorf("stored keys = %d, want none", len(keys))
+	}
+}
+
+func TestWalletLoginUnknownChallenge(t *testing.T) {
+	r, _ := setupWallet(t)
+
+	_, resp := post(r, "/wallet/challenge", gin.H{"address": testAddress})
+	var challenge auth.Challenge
+	json.Unmarshal(resp.Data, &challenge)
+
+	tests := []struct {
+		name  string
+		nonce string
+		addr  string
+	}{
+		{"unknown nonce", "abcdefghijklmnopqrstuvwx", testAddress},
+		{"short nonce", "abc", testAddress},
+		{"nonce of another address", challenge.Nonce, "0xAb8483F64d9C6d1EcF9b849Ae677dD3315835cb2"},
+	}
+	for _, tt := range tests {
+		status, resp := post(r, "/wallet/login", gin.H{"address": tt.addr, "nonce": tt.nonce, "signature": "0x00"})
+		if status != http.StatusUnauthorized || resp.Code != constant.SignatureError {
+			t.Errorf("%s: login = %d %d, want 401 signature error", tt.name, status, resp.Code)
+		}
+	}
+}
+
+func TestWalletDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/wallet/challenge", WalletChallenge)
+
+	status, _ := post(r, "/wallet/challenge", gin.H{"address": testAddress})
+	if status != http.StatusNotFound {
+		t.Errorf("challenge with the wallet disabled = %d, want 404", status)
+	}
+}
+
+// sign answer a challenge the way a wallet does, with v as 27/28
+func sign(t *testing.T, key *ecdsa.PrivateKey, challenge auth.Challenge, method string) string {
+	t.Helper()
+	hash := eth.TextHash([]byte(challenge.Message))
+	if method == auth.SignTypedData {
+		var err error
+		if hash, err = eth.TypedDataHash(challenge.TypedData); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+	return "0x" + hex.EncodeToString(sig)
+}
+
+func TestWalletLogin(t *testing.T) {
+	r, _ := setupWallet(t)
+	key, _ := crypto.HexToECDSA("c85ef7d79691fe79573b1a7064c19c1a9819ebdbd1faaab1a8ec92344438aaf4")
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	other, _ := crypto.GenerateKey()
+
+	for _, method := range []string{auth.SignPersonal, auth.SignTypedData} {
+		_, resp := post(r, "/wallet/challenge", gin.H{"address": address})
+		var challenge auth.Challenge
+		if err := json.Unmarshal(resp.Data, &challenge); err != nil {
+			t.Fatal(err)
+		}
+
+		login := gin.H{"address": address, "nonce": challenge.Nonce, "method": method}
+		login["signature"] = sign(t, other, challenge, method)
+		if status, resp := post(r, "/wallet/login", login); status != http.StatusUnauthorized || resp.Code != constant.SignatureError {
+			t.Errorf("%s by another key = %d %d, want 401 signature error", method, status, resp.Code)
+		}
+
+		login["signature"] = sign(t, key, challenge, method)
+		status, resp := post(r, "/wallet/login", login)
+		if status != http.StatusOK || resp.Code != constant.Success {
+			t.Fatalf("%s login = %d %d, want 200 success", method, status, resp.Code)
+		}
+		var session auth.Session
+		json.Unmarshal(resp.Data, &session)
+		if session.Address != address || session.Token == "" {
+			t.Errorf("%s session = %+v", method, session)
+		}
+
+		if status, _ := post(r, "/wallet/login", login); status != http.StatusUnauthorized {
+			t.Errorf("%s replayed login = %d, want 401", method, status)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"template_project/config"
+	"template_project/db/mysql"
+	"template_project/db/redis"
+	"template_project/migration"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Init register the checks of the enabled dependencies, after mysql and redis are initialized
+func Init(cfg config.Configuration) {
+	if cfg.MySQL.Enable {
+		Register("mysql", checkMySQL)
+		Register("migrations", checkMigrations)
+	}
+	if cfg.Redis.Enable {
+		Register("redis", checkRedis)
+	}
+	for name, chain := range cfg.Chains {
+		if chain.RPC != "" {
+			Register("chain:"+name, checkChain(chain.RPC))
+		}
+	}
+}
+
+func checkMySQL(ctx context.Context) error {
+	if mysql.DB == nil {
+		return errors.New("not initialized")
+	}
+	return mysql.DB.DB.DB().PingContext(ctx)
+}
+
+// checkMigrations fail while a registered migration is not applied, the schema being older than the code
+func checkMigrations(ctx context.Context) error {
+	if mysql.DB == nil {
+		return errors.New("not initialized")
+	}
+	pending, err := migration.New(mysql.DB.DB).Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending, first %d_%s", len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+func checkRedis(ctx context.Context) error {
+	if redis.DB == nil {
+		return errors.New("not initialized")
+	}
+	return redis.DB.Ping(ctx)
+}
+
+// checkChain call eth_chainId on the json-rpc endpoint
+func checkChain(rpc string) Check {
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest(http.MethodPost, rpc, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+
+		var res struct {
+			Result string `json:"result"`
+			Error  *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			return fmt.Errorf("decode response: %v", err)
+		}
+		if res.Error != nil {
+			return fmt.Errorf("rpc error %d: %s", res.Error.Code, res.Error.Message)
+		}
+		if res.Result == "" {
+			return errors.New("empty chain id")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+// Package health answer the liveness and readiness probes of the orchestrator
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDraining = "draining"
+
+	// checkTimeout bound every dependency check, so that a hung dependency fails the probe instead of blocking it
+	checkTimeout = 3 * time.Second
+)
+
+// Check return nil when the component is usable
+type Check func(ctx context.Context) error
+
+// Component is the result of a check
+type Component struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the answer of a probe, Status is up only when every component is
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+var (
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining int32
+)
+
+// Register add the readiness check of the component name
+func Register(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks = append(checks, namedCheck{name, check})
+}
+
+// SetDraining make the readiness fail from now on, called when the graceful shutdown starts
+func SetDraining() {
+	atomic.StoreInt32(&draining, 1)
+}
+
+// Draining report whether the graceful shutdown started
+func Draining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// Live report that the process is able to answer
+func Live() Report {
+	return Report{Status: StatusUp}
+}
+
+// Ready run the registered checks concurrently, the report is down when any of them fails
+func Ready(ctx context.Context) Report {
+	if Draining() {
+		return Report{Status: StatusDraining}
+	}
+
+	mu.RLock()
+	list := append([]namedCheck(nil), checks...)
+	mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	components := make([]Component, len(list))
+	var wg sync.WaitGroup
+	for i, c := range list {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			components[i] = run(ctx, check)
+		}(i, c.check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(list))}
+	for i, c := range list {
+		report.Components[c.name] = components[i]
+		if components[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run call check, giving up when ctx is done even if check ignores it
+func run(ctx context.Context, check Check) Component {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c := Component{Status: StatusUp, LatencyMs: float64(time.Since(start)) / float64(time.Millisecond)}
+	if err != nil {
+		c.Status = StatusDown
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// LiveHandler answer the liveness probe, 200 as long as the process serves requests
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, Live())
+	})
+}
+
+// ReadyHandler answer the readiness probe, 503 while a dependency is down or the server drains.
+// The errors of the checks may name internal hosts, they are only shown with detailed.
+func ReadyHandler(detailed bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Ready(r.Context())
+		if !detailed {
+			for name, c := range report.Components {
+				c.Error = ""
+				report.Components[name] = c
+			}
+		}
+		writeReport(w, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,143 @@
+package health
+
+import (
+	"template_project/db/mysql/mysqltest"
+	"template_project/migration"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useChecks replace the registered checks with checks for the test
+func useChecks(t *testing.T, named map[string]Check) {
+	mu.Lock()
+	saved := checks
+	checks = nil
+	mu.Unlock()
+	for name, check := range named {
+		Register(name, check)
+	}
+	t.Cleanup(func() {
+		mu.Lock()
+		checks = saved
+		mu.Unlock()
+		atomic.StoreInt32(&draining, 0)
+	})
+}
+
+func up(ctx context.Context) error { return nil }
+
+func failing(ctx context.Context) error { return errors.New("dial tcp 10.0.0.3:6379: connection refused") }
+
+func TestReady(t *testing.T) {
+	useChecks(t, map[string]Check{"mysql": up, "redis": up})
+	if r := Ready(context.Background()); r.Status != StatusUp || len(r.Components) != 2 {
+		t.Errorf("report = %+v, want up", r)
+	}
+
+	useChecks(t, map[string]Check{"mysql": up, "redis": failing})
+	r := Ready(context.Background())
+	if r.Status != StatusDown {
+		t.Errorf("status = %s, want down", r.Status)
+	}
+	if c := r.Components["mysql"]; c.Status != StatusUp || c.Error != "" {
+		t.Errorf("mysql = %+v", c)
+	}
+	if c := r.Components["redis"]; c.Status != StatusDown || !strings.Contains(c.Error, "connection refused") {
+		t.Errorf("redis = %+v", c)
+	}
+}
+
+func TestReadyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	// a check ignoring its context still fails the probe at the timeout
+	hung := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+	useChecks(t, map[string]Check{"chain:eth": hung, "mysql": up})
+
+	start := time.Now()
+	r := Ready(context.Background())
+	elapsed := time.Since(start)
+	if elapsed < checkTimeout || elapsed > checkTimeout+time.Second {
+		t.Errorf("Ready took %v, want the %v timeout", elapsed, checkTimeout)
+	}
+	if c := r.Components["chain:eth"]; r.Status != StatusDown || c.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("report = %+v", r)
+	}
+	if c := r.Components["mysql"]; c.Status != StatusUp {
+		t.Errorf("mysql = %+v, the hung check failed the others", c)
+	}
+}
+
+func TestDraining(t *testing.T) {
+	var called int32
+	useChecks(t, map[string]Check{"mysql": func(ctx context.Context) error {
+		atomic.AddInt32(&called, 1)
+		return nil
+	}})
+
+	SetDraining()
+	if r := Ready(context.Background()); r.Status != StatusDraining {
+		t.Errorf("status = %s, want draining", r.Status)
+	}
+	w := httptest.NewRecorder()
+	ReadyHandler(true).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz while draining = %d, want 503", w.Code)
+	}
+	if n := atomic.LoadInt32(&called); n != 0 {
+		t.Errorf("checks ran %d times while draining", n)
+	}
+	// the process still lives
+	w = httptest.NewRecorder()
+	LiveHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz while draining = %d, want 200", w.Code)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	useChecks(t, map[string]Check{"mysql": up, "redis": failing})
+
+	for _, detailed := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		ReadyHandler(detailed).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("detailed %v: readyz = %d %v", detailed, w.Code, w.Header())
+		}
+		var r Report
+		if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		c := r.Components["redis"]
+		if c.Status != StatusDown {
+			t.Errorf("detailed %v: redis = %+v", detailed, c)
+		}
+		if shown := strings.Contains(w.Body.String(), "10.0.0.3"); shown != detailed {
+			t.Errorf("detailed %v: error shown %v: %s", detailed, shown, w.Body.String())
+		}
+	}
+}
+
+func TestCheckMigrations(t *testing.T) {
+	db := mysqltest.Use(t)
+
+	err := checkMigrations(context.Background())
+	first := migration.Migrations()[0]
+	if err == nil || !strings.Contains(err.Error(), first.Name) {
+		t.Errorf("check on a database never migrated = %v, want the migrations pending", err)
+	}
+	// the probe only reads, the migrate command creates the table
+	if db.HasTable(&migration.SchemaMigration{}) {
+		t.Error("the check created the table of the applied migrations")
+	}
+}
@@ -0,0 +1,29 @@
+package logger
+
+import "context"
+
+// ContextKey is the gin context key of the request logger
+const ContextKey = "logger"
+
+type contextKey struct{}
+
+// NewContext return a copy of ctx carrying l
+func NewContext(ctx context.Context, l LoggerI) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext return the logger carried by ctx, a request context or a *gin.Context,
+// falling back to Log
+func FromContext(ctx context.Context) LoggerI {
+	if ctx == nil {
+		return &Log
+	}
+	if l, ok := ctx.Value(contextKey{}).(LoggerI); ok {
+		return l
+	}
+	// gin.Context looks string keys up in its own keys
+	if l, ok := ctx.Value(ContextKey).(LoggerI); ok {
+		return l
+	}
+	return &Log
+}
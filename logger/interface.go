@@ -1,6 +1,12 @@
 package logger
 
+// Fields are the structured fields attached to the entries of a logger
+type Fields map[string]interface{}
+
 type LoggerI interface {
+	WithField(key string, value interface{}) LoggerI
+	WithFields(fields Fields) LoggerI
+
 	Debug(f interface{}, args ...interface{})
 	Info(f interface{}, args ...interface{})
 	Warn(f interface{}, args ...interface{})
@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the modules of the named loggers
+const (
+	// RootModule is the module of Log and of the modules without a level of their own
+	RootModule  = "root"
+	ModuleMySQL = "mysql"
+	ModuleRedis = "redis"
+	ModuleHTTP  = "http"
+	ModuleTask  = "task"
+)
+
+var (
+	ErrUnknownLevel  = errors.New("unknown log level")
+	ErrUnknownModule = errors.New("unknown log module")
+)
+
+// LevelState describe the level of a module
+type LevelState struct {
+	Module     string     `json:"module"`
+	Level      string     `json:"level"`                // the level in effect
+	Configured string     `json:"configured,omitempty"` // logger.level or logger.modules.<module>, empty when inherited
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // when a runtime level reverts
+}
+
+// override is a level set at runtime
+type override struct {
+	level     logrus.Level
+	expiresAt time.Time // zero when it does not revert
+	timer     *time.Timer
+}
+
+// levelRegistry holds the configured levels and the runtime overrides, which take precedence
+type levelRegistry struct {
+	mu        sync.RWMutex
+	root      logrus.Level
+	modules   map[string]logrus.Level
+	overrides map[string]*override
+	known     map[string]bool
+}
+
+var levels = &levelRegistry{
+	root:      logrus.DebugLevel,
+	modules:   map[string]logrus.Level{},
+	overrides: map[string]*override{},
+	known: map[string]bool{
+		RootModule:  true,
+		ModuleMySQL: true,
+		ModuleRedis: true,
+		ModuleHTTP:  true,
+		ModuleTask:  true,
+	},
+}
+
+// Named return the logger of module, its level is logger.modules.<module>, falling back to the root level
+func Named(module string) LoggerI {
+	levels.mu.Lock()
+	levels.known[module] = true
+	levels.mu.Unlock()
+	return &Logger{log: Log.log, module: module, fields: logrus.Fields{"module": module}}
+}
+
+// ParseLevel return the level named name, unlike GetLogLevel it rejects the unknown names
+func ParseLevel(name string) (logrus.Level, error) {
+	if v, ok := logLevelMap[name]; ok {
+		return v, nil
+	}
+	return 0, ErrUnknownLevel
+}
+
+// SetLevel change the level of module at runtime, "root" for the modules without a level of their own.
+// It reverts to the configured level after ttl, a ttl of 0 keeps it until ResetLevel or a config change.
+func SetLevel(module, name string, ttl time.Duration) (LevelState, error) {
+	level, err := ParseLevel(name)
+	if err != nil {
+		return LevelState{}, err
+	}
+
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if !levels.known[module] {
+		return LevelState{}, ErrUnknownModule
+	}
+	levels.drop(module)
+	o := &override{level: level}
+	if ttl > 0 {
+		o.expiresAt = time.Now().Add(ttl)
+		o.timer = time.AfterFunc(ttl, func() {
+			levels.mu.Lock()
+			defer levels.mu.Unlock()
+			// a later SetLevel replaced o, its own timer reverts it
+			if levels.overrides[module] == o {
+				delete(levels.overrides, module)
+			}
+		})
+	}
+	levels.overrides[module] = o
+	return levels.state(module), nil
+}
+
+// ResetLevel drop the runtime level of module, it goes back to the configured level
+func ResetLevel(module string) (LevelState, error) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if !levels.known[module] {
+		return LevelState{}, ErrUnknownModule
+	}
+	levels.drop(module)
+	return levels.state(module), nil
+}
+
+// Levels return the level of every module, root first
+func Levels() []LevelState {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	modules := make([]string, 0, len(levels.known))
+	for module := range levels.known {
+		if module != RootModule {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+
+	states := []LevelState{levels.state(RootModule)}
+	for _, module := range modules {
+		states = append(states, levels.state(module))
+	}
+	return states
+}
+
+// configure apply logger.level and logger.modules. On a reload the runtime level of a module
+// whose configured level changed is dropped, the config being the newer decision.
+func (r *levelRegistry) configure(root string, modules map[string]string, reload bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configured := map[string]logrus.Level{}
+	for module, name := range modules {
+		// validated with the config, an unknown name keeps the root level
+		if level, err := ParseLevel(name); err == nil {
+			configured[module] = level
+			r.known[module] = true
+		}
+	}
+	rootLevel := GetLogLevel(root)
+
+	if reload {
+		if rootLevel != r.root {
+			r.drop(RootModule)
+		}
+		for module := range r.known {
+			old, had := r.modules[module]
+			level, has := configured[module]
+			if had != has || old != level {
+				r.drop(module)
+			}
+		}
+	}
+	r.root = rootLevel
+	r.modules = configured
+}
+
+// effective return the level in effect for module, the root level for the root logger
+func (r *levelRegistry) effective(module string) logrus.Level {
+	if module == "" {
+		module = RootModule
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.level(module)
+}
+
+func (r *levelRegistry) level(module string) logrus.Level {
+	if o, ok := r.overrides[module]; ok {
+		return o.level
+	}
+	if module != RootModule {
+		if level, ok := r.modules[module]; ok {
+			return level
+		}
+		return r.level(RootModule)
+	}
+	return r.root
+}
+
+// drop remove the runtime level of module, r.mu must be held
+func (r *levelRegistry) drop(module string) {
+	if o, ok := r.overrides[module]; ok {
+		if o.timer != nil {
+			o.timer.Stop()
+		}
+		delete(r.overrides, module)
+	}
+}
+
+// state describe module, r.mu must be held
+func (r *levelRegistry) state(module string) LevelState {
+	s := LevelState{Module: module, Level: levelName(r.level(module))}
+	if module == RootModule {
+		s.Configured = levelName(r.root)
+	} else if level, ok := r.modules[module]; ok {
+		s.Configured = levelName(level)
+	}
+	if o, ok := r.overrides[module]; ok && !o.expiresAt.IsZero() {
+		expiresAt := o.expiresAt
+		s.ExpiresAt = &expiresAt
+	}
+	return s
+}
+
+// levelName return the config name of level, logrus names warn "warning"
+func levelName(level logrus.Level) string {
+	for name, l := range logLevelMap {
+		if l == level {
+			return name
+		}
+	}
+	return level.String()
+}
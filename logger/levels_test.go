@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// useLevels replace the levels with root info and mysql warn, and Log with one writing to the returned buffer
+func useLevels(t *testing.T) *bytes.Buffer {
+	savedLevels, savedLog := levels, Log.log
+	levels = &levelRegistry{
+		modules:   map[string]logrus.Level{},
+		overrides: map[string]*override{},
+		known:     map[string]bool{RootModule: true, ModuleMySQL: true, ModuleRedis: true},
+	}
+	levels.configure("info", map[string]string{ModuleMySQL: "warn"}, false)
+
+	var out bytes.Buffer
+	Log.log = logrus.New()
+	Log.log.Out = &out
+	Log.log.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() {
+		levels.mu.Lock()
+		for module := range levels.overrides {
+			levels.drop(module)
+		}
+		levels.mu.Unlock()
+		levels, Log.log = savedLevels, savedLog
+	})
+	return &out
+}
+
+// waitLevel wait up to a second for module to be at level
+func waitLevel(t *testing.T, module string, level logrus.Level) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for levels.effective(module) != level {
+		if time.Now().After(deadline) {
+			t.Fatalf("level of %s = %v, want %v", module, levels.effective(module), level)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSetLevelOverride(t *testing.T) {
+	out := useLevels(t)
+	redis := Named(ModuleRedis)
+
+	redis.Debug("before")
+	state, err := SetLevel(ModuleRedis, "debug", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Level != "debug" || state.Configured != "" || state.ExpiresAt != nil {
+		t.Errorf("state = %+v", state)
+	}
+	redis.Debug("after")
+	Named(ModuleMySQL).Info("mysql info")
+	Log.Debug("root debug")
+
+	logged := out.String()
+	if strings.Contains(logged, "before") || !strings.Contains(logged, "after") {
+		t.Errorf("the override of redis did not take effect:\n%s", logged)
+	}
+	if strings.Contains(logged, "mysql info") || strings.Contains(logged, "root debug") {
+		t.Errorf("the override of redis leaked to the other loggers:\n%s", logged)
+	}
+
+	// the root level is inherited by the modules without a level of their own
+	if _, err := SetLevel(RootModule, "error", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := levels.effective(ModuleHTTP); got != logrus.ErrorLevel {
+		t.Errorf("level of http = %v, want the error of root", got)
+	}
+	if got := levels.effective(ModuleMySQL); got != logrus.WarnLevel {
+		t.Errorf("level of mysql = %v, want its configured warn", got)
+	}
+
+	if state, _ = ResetLevel(ModuleRedis); state.Level != "error" {
+		t.Errorf("reset redis = %+v, want the root level", state)
+	}
+}
+
+func TestSetLevelTTL(t *testing.T) {
+	useLevels(t)
+
+	state, err := SetLevel(ModuleMySQL, "debug", 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Level != "debug" || state.Configured != "warn" || state.ExpiresAt == nil {
+		t.Errorf("state = %+v", state)
+	}
+	waitLevel(t, ModuleMySQL, logrus.WarnLevel)
+	if s := Levels(); s[0].Module != RootModule {
+		t.Errorf("levels = %+v, want root first", s)
+	}
+	for _, s := range Levels() {
+		if s.Module == ModuleMySQL && (s.Level != "warn" || s.ExpiresAt != nil) {
+			t.Errorf("reverted state = %+v", s)
+		}
+	}
+}
+
+func TestSetLevelReplacesTimer(t *testing.T) {
+	useLevels(t)
+
+	if _, err := SetLevel(ModuleMySQL, "debug", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// the second level outlives the timer of the first
+	if _, err := SetLevel(ModuleMySQL, "error", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := levels.effective(ModuleMySQL); got != logrus.ErrorLevel {
+		t.Fatalf("level of mysql = %v, the first timer reverted the second level", got)
+	}
+
+	if _, err := SetLevel(ModuleMySQL, "info", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetLevel(ModuleMySQL, "debug", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// a timed level reverts to the configured level, not to the level it replaced
+	waitLevel(t, ModuleMySQL, logrus.WarnLevel)
+}
+
+func TestSetLevelInvalid(t *testing.T) {
+	useLevels(t)
+	if _, err := SetLevel(ModuleMySQL, "verbose", 0); err != ErrUnknownLevel {
+		t.Errorf("unknown level error = %v", err)
+	}
+	if _, err := SetLevel("kafka", "debug", 0); err != ErrUnknownModule {
+		t.Errorf("unknown module error = %v", err)
+	}
+	if _, err := ResetLevel("kafka"); err != ErrUnknownModule {
+		t.Errorf("reset unknown module error = %v", err)
+	}
+	// Named makes the module known
+	Named("kafka")
+	if _, err := SetLevel("kafka", "debug", 0); err != nil {
+		t.Errorf("set the level of a named module: %v", err)
+	}
+}
+
+func TestConfigureReload(t *testing.T) {
+	useLevels(t)
+	SetLevel(ModuleMySQL, "debug", 0)
+	SetLevel(ModuleRedis, "debug", 0)
+
+	// only the override of the module whose configured level changed is dropped
+	levels.configure("info", map[string]string{ModuleMySQL: "error"}, true)
+	if got := levels.effective(ModuleMySQL); got != logrus.ErrorLevel {
+		t.Errorf("level of mysql = %v, want the new configured error", got)
+	}
+	if got := levels.effective(ModuleRedis); got != logrus.DebugLevel {
+		t.Errorf("level of redis = %v, want its override kept", got)
+	}
+}
@@ -2,6 +2,8 @@ package logger
 
 import (
 	"template_project/config"
+	"fmt"
+	"reflect"
 
 	"github.com/sirupsen/logrus"
 )
@@ -9,11 +11,21 @@ import (
 var Log Logger
 
 type Logger struct {
-	log *logrus.Logger
+	log    *logrus.Logger
+	module string // empty for the root logger
+	fields logrus.Fields
 }
 
 func Init() {
 	cfg := config.GetConfig().Logger
+	var hooks []logrus.Hook
+	if !cfg.Redact.Disable {
+		hook, err := NewRedactHook(cfg.Redact.Patterns, cfg.Redact.Custom, cfg.Redact.Fields)
+		if err != nil {
+			panic(fmt.Sprintf("logger redact: %v", err))
+		}
+		hooks = append(hooks, hook)
+	}
 	Log.log = NewService(&Config{
 		Level:          cfg.Level,
 		Formatter:      cfg.Formatter,
@@ -21,8 +33,19 @@ func Init() {
 		Write:          cfg.Write,
 		Path:           cfg.Path,
 		FileName:       cfg.FileName,
-		MaxAge:         cfg.MaxAge,
-		RotationTime:   cfg.RotationTime,
+		MaxAge:         cfg.MaxAge.Duration,
+		RotationTime:   cfg.RotationTime.Duration,
 		Debug:          cfg.Debug,
+		Hooks:          hooks,
+	})
+	// the loggers filter the entries by the level of their module, logrus lets everything through
+	Log.log.SetLevel(logrus.DebugLevel)
+	levels.configure(cfg.Level, cfg.Modules, false)
+
+	config.OnChange(func(old, new config.Configuration) {
+		l := new.Logger
+		if old.Logger.Level != l.Level || !reflect.DeepEqual(old.Logger.Modules, l.Modules) {
+			levels.configure(l.Level, l.Modules, true)
+		}
 	})
 }
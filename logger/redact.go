@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Mask replace the redacted values
+const Mask = "******"
+
+// rule replace the matches of re with repl, which may refer to the groups of re
+type rule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// builtinPatterns are the patterns of logger.redact.patterns
+var builtinPatterns = map[string][]rule{
+	"private_key": {
+		{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), Mask},
+		// the secp256k1 keys of the chains after their name, private_key=0x..., "privateKey": "...", secret: ...
+		{regexp.MustCompile(`(?i)\b((?:private|priv|secret|signing)[_-]?key|privkey|secret)(["']?\s*[:=]\s*["']?)(0x)?[0-9a-fA-F]{64}\b`), "${1}${2}" + Mask},
+	},
+	// any 64 hex digits, opt-in since transaction and block hashes look the same
+	"hex_key": {
+		{regexp.MustCompile(`\b(0x)?[0-9a-fA-F]{64}\b`), Mask},
+	},
+	"jwt": {
+		{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`), Mask},
+	},
+	"password": {
+		// password=..., "secret": "...", token: ... keep the key
+		{regexp.MustCompile(`(?i)\b(password|passwd|pwd|secret|token|api_key|apikey)(["']?\s*[:=]\s*["']?)[^\s"'&,;}]+`), "${1}${2}" + Mask},
+		// user:password@ of urls and dsn
+		{regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.-]*://[^:@/\s]+:|\b[A-Za-z0-9_.-]+:)[^@/\s]+(@(tcp|unix)?\(|@[A-Za-z0-9.-]+[:/])`), "${1}" + Mask + "${2}"},
+	},
+	"email": {
+		{regexp.MustCompile(`[A-Za-z0-9._%+-]+@([A-Za-z0-9-]+\.)+[A-Za-z]{2,}`), Mask},
+	},
+}
+
+var defaultRedactFields = []string{"password", "secret", "authorization", "token", "api_key"}
+
+// RedactHook is a logrus hook masking the secrets of the messages and fields of the entries.
+// It should be added before the hooks writing the entries.
+type RedactHook struct {
+	rules  []rule
+	fields map[string]bool
+}
+
+// NewRedactHook build a hook for the built-in patterns, all of them but hex_key when empty, the custom regular expressions
+// and the fields masked whole, password, secret, authorization, token and api_key when empty
+func NewRedactHook(patterns, custom, fields []string) (*RedactHook, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"private_key", "jwt", "password", "email"}
+	}
+	if len(fields) == 0 {
+		fields = defaultRedactFields
+	}
+
+	h := &RedactHook{fields: make(map[string]bool, len(fields))}
+	for _, name := range patterns {
+		rules, ok := builtinPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redact pattern %q", name)
+		}
+		h.rules = append(h.rules, rules...)
+	}
+	for _, expr := range custom {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %v", expr, err)
+		}
+		h.rules = append(h.rules, rule{re, Mask})
+	}
+	for _, name := range fields {
+		h.fields[strings.ToLower(name)] = true
+	}
+	return h, nil
+}
+
+// Levels implements logrus.Hook
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (h *RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.Redact(entry.Message)
+	for k, v := range entry.Data {
+		if h.fields[strings.ToLower(k)] {
+			entry.Data[k] = Mask
+			continue
+		}
+		entry.Data[k] = h.value(v)
+	}
+	return nil
+}
+
+// Redact mask the matches of the patterns in s
+func (h *RedactHook) Redact(s string) string {
+	for _, r := range h.rules {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+// value mask v, the maps and slices of the decoded json bodies are copied rather than changed
+func (h *RedactHook) value(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return h.Redact(value)
+	case error:
+		return errors.New(h.Redact(value.Error()))
+	case fmt.Stringer:
+		return h.Redact(value.String())
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(value))
+		for k, elem := range value {
+			if h.fields[strings.ToLower(k)] {
+				masked[k] = Mask
+				continue
+			}
+			masked[k] = h.value(elem)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(value))
+		for i, elem := range value {
+			masked[i] = h.value(elem)
+		}
+		return masked
+	case []string:
+		masked := make([]string, len(value))
+		for i, elem := range value {
+			masked[i] = h.Redact(elem)
+		}
+		return masked
+	}
+	return v
+}
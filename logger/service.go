@@ -34,6 +34,8 @@ type Config struct {
 	RotationTime time.Duration
 
 	Debug bool // if set true, separate
+
+	Hooks []logrus.Hook // added before the hooks writing the log files, e.g. a *RedactHook
 }
 
 func defaultConfig() *Config {
@@ -89,6 +91,18 @@ func NewService(config *Config) *logrus.Logger {
 
 	log.SetLevel(logLevel)
 
+	var formatter logrus.Formatter
+
+	formatter = &logrus.TextFormatter{}
+	if config.Formatter == "json" {
+		formatter = &logrus.JSONFormatter{}
+	}
+	log.Formatter = formatter
+
+	for _, hook := range config.Hooks {
+		log.AddHook(hook)
+	}
+
 	if config.Write {
 		storeLogDir := logDir
 
@@ -101,19 +115,13 @@ func NewService(config *Config) *logrus.Logger {
 		writer, err := rotatelogs.New(
 			path+".%Y-%m-%d",
 			rotatelogs.WithClock(rotatelogs.Local),
-			rotatelogs.WithMaxAge(time.Duration(maxAge)*time.Hour),
-			rotatelogs.WithRotationTime(time.Duration(rotationTime)*time.Hour),
+			rotatelogs.WithMaxAge(maxAge),
+			rotatelogs.WithRotationTime(rotationTime),
 		)
 		if err != nil {
 			panic(fmt.Sprintf("rotatelogs log failed: %s", err.Error()))
 		}
 
-		var formatter logrus.Formatter
-
-		formatter = &logrus.TextFormatter{}
-		if config.Formatter == "json" {
-			formatter = &logrus.JSONFormatter{}
-		}
 		if config.Debug {
 			log.AddHook(lfshook.NewHook(
 				lfshook.WriterMap{
@@ -175,72 +183,140 @@ func FormatLog(f interface{}, v ...interface{}) string {
 	return fmt.Sprintf(msg, v...)
 }
 
+// WithField return a logger adding key to the fields of l
+func (l *Logger) WithField(key string, value interface{}) LoggerI {
+	return l.WithFields(Fields{key: value})
+}
+
+// WithFields return a logger adding fields to the fields of l
+func (l *Logger) WithFields(fields Fields) LoggerI {
+	merged := make(logrus.Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{log: l.log, module: l.module, fields: merged}
+}
+
+func (l *Logger) entry() *logrus.Entry {
+	return l.logger().WithFields(l.fields)
+}
+
+// logger return the logrus logger of l, the named loggers made before Init write to Log,
+// and to the standard logger when Init is never called, as in the commands
+func (l *Logger) logger() *logrus.Logger {
+	if l.log != nil {
+		return l.log
+	}
+	if Log.log != nil {
+		return Log.log
+	}
+	return logrus.StandardLogger()
+}
+
+// enabled report whether level passes the level of the module of l
+func (l *Logger) enabled(level logrus.Level) bool {
+	return level <= levels.effective(l.module)
+}
+
 // Debug wrapper Debug logger
 func (l *Logger) Debug(f interface{}, args ...interface{}) {
-	l.log.Debug(FormatLog(f, args...))
+	if !l.enabled(logrus.DebugLevel) {
+		return
+	}
+	l.entry().Debug(FormatLog(f, args...))
 }
 
 // Info wrapper Info logger
 func (l *Logger) Info(f interface{}, args ...interface{}) {
-	l.log.Info(FormatLog(f, args...))
+	if !l.enabled(logrus.InfoLevel) {
+		return
+	}
+	l.entry().Info(FormatLog(f, args...))
 }
 
 // Warn wrapper Warn logger
 func (l *Logger) Warn(f interface{}, args ...interface{}) {
-	l.log.Warn(FormatLog(f, args...))
+	if !l.enabled(logrus.WarnLevel) {
+		return
+	}
+	l.entry().Warn(FormatLog(f, args...))
 }
 
 // Printf wrapper Printf logger
 func (l *Logger) Printf(f interface{}, args ...interface{}) {
-	l.log.Print(FormatLog(f, args...))
+	if !l.enabled(logrus.InfoLevel) {
+		return
+	}
+	l.entry().Print(FormatLog(f, args...))
 }
 
 // Panic wrapper Panic logger
 func (l *Logger) Panic(f interface{}, args ...interface{}) {
-	l.log.Panic(FormatLog(f, args...))
+	l.entry().Panic(FormatLog(f, args...))
 }
 
 // Fatal wrapper Fatal logger
 func (l *Logger) Fatal(f interface{}, args ...interface{}) {
-	l.log.Fatal(FormatLog(f, args...))
+	l.entry().Fatal(FormatLog(f, args...))
 }
 
 // Error wrapper Error logger
 func (l *Logger) Error(f interface{}, args ...interface{}) {
-	l.log.Error(FormatLog(f, args...))
+	if !l.enabled(logrus.ErrorLevel) {
+		return
+	}
+	l.entry().Error(FormatLog(f, args...))
 }
 
 // Debugln wrapper Debugln logger
 func (l *Logger) Debugln(v ...interface{}) {
-	l.log.Debug(fmt.Sprintln(v...))
+	if !l.enabled(logrus.DebugLevel) {
+		return
+	}
+	l.entry().Debug(fmt.Sprintln(v...))
 }
 
 // Infoln wrapper Infoln logger
 func (l *Logger) Infoln(args ...interface{}) {
-	l.log.Info(fmt.Sprintln(args...))
+	if !l.enabled(logrus.InfoLevel) {
+		return
+	}
+	l.entry().Info(fmt.Sprintln(args...))
 }
 
 // Warnln wrapper Warnln logger
 func (l *Logger) Warnln(args ...interface{}) {
-	l.log.Warn(fmt.Sprintln(args...))
+	if !l.enabled(logrus.WarnLevel) {
+		return
+	}
+	l.entry().Warn(fmt.Sprintln(args...))
 }
 
 // Printfln wrapper Printfln logger
 func (l *Logger) Printfln(args ...interface{}) {
-	l.log.Print(fmt.Sprintln(args...))
+	if !l.enabled(logrus.InfoLevel) {
+		return
+	}
+	l.entry().Print(fmt.Sprintln(args...))
 }
 
 // Panicln wrapper Panicln logger
 func (l *Logger) Panicln(args ...interface{}) {
-	l.log.Panic(fmt.Sprintln(args...))
+	l.entry().Panic(fmt.Sprintln(args...))
 }
 
 // Fatalln wrapper Fatalln logger
 func (l *Logger) Fatalln(args ...interface{}) {
-	l.log.Fatal(fmt.Sprintln(args...))
+	l.entry().Fatal(fmt.Sprintln(args...))
 }
 
 // Errorln wrapper Errorln logger
 func (l *Logger) Errorln(args ...interface{}) {
-	l.log.Error(fmt.Sprintln(args...))
+	if !l.enabled(logrus.ErrorLevel) {
+		return
+	}
+	l.entry().Error(fmt.Sprintln(args...))
 }
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry served by Handler, it has the go runtime and process metrics
+var Default = prometheus.NewRegistry()
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// MustRegister add collectors to Default, panic when one is invalid or collides with a registered one
+func MustRegister(cs ...prometheus.Collector) {
+	Default.MustRegister(cs...)
+}
+
+// Handler serve the metrics of Default, counting its own scrapes
+func Handler() http.Handler {
+	return HandlerFor(Default)
+}
+
+// HandlerFor serve the metrics of r in the format negotiated with the scraper
+func HandlerFor(r *prometheus.Registry) http.Handler {
+	return promhttp.InstrumentMetricHandler(r, promhttp.HandlerFor(r, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"template_project/logger"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog log every request through the request logger, after RequestID.
+// Server errors are logged at error level and client errors at warn level.
+func AccessLog(c *gin.Context) {
+	start := time.Now()
+	path := c.Request.URL.Path
+	if raw := c.Request.URL.RawQuery; raw != "" {
+		path += "?" + raw
+	}
+
+	c.Next()
+
+	status := c.Writer.Status()
+	fields := logger.Fields{
+		"status":     status,
+		"path":       path,
+		"latency_ms": float64(time.Since(start)) / float64(time.Millisecond),
+		"bytes":      c.Writer.Size(),
+		"user_agent": c.Request.UserAgent(),
+	}
+	if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+		fields["errors"] = errs
+	}
+	l := logger.FromContext(c).WithFields(fields)
+	switch {
+	case status >= http.StatusInternalServerError:
+		l.Error("access")
+	case status >= http.StatusBadRequest:
+		l.Warn("access")
+	default:
+		l.Info("access")
+	}
+}
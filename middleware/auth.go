@@ -1,7 +1,114 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"template_project/auth"
+	"template_project/logger"
+	"template_project/utils/apperr"
+	"template_project/utils/render"
 
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// UserIDKey is the gin context key holding the id of the authenticated user
+	UserIDKey = "user_id"
+	// PrincipalKey is the gin context key holding the *auth.Principal of the request
+	PrincipalKey = "principal"
+)
+
+// Auth require a request authenticated by any configured method
 func Auth(c *gin.Context) {
-	c.Next()
+	authenticate(c, true, nil)
+}
+
+// RequireAuth reject with 401 the requests not authenticated by one of methods,
+// any configured method when empty, e.g. v1.Group("/admin", middleware.RequireAuth(auth.MethodJWT))
+func RequireAuth(methods ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, true, methods)
+	}
+}
+
+// OptionalAuth set the principal of the requests carrying valid credentials and let the others through,
+// invalid credentials are still rejected
+func OptionalAuth(methods ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, false, methods)
+	}
+}
+
+func authenticate(c *gin.Context, required bool, methods []string) {
+	if resolvePrincipal(c, required, methods) {
+		c.Next()
+	}
+}
+
+// resolvePrincipal set the principal of the request, it aborts the request and returns false on failure.
+// A principal set by an earlier auth middleware with one of methods is kept.
+func resolvePrincipal(c *gin.Context, required bool, methods []string) bool {
+	if p, ok := CurrentPrincipal(c); ok && (len(methods) == 0 || oneOf(p.Method, methods)) {
+		return true
+	}
+	p, err := auth.Authenticate(c, auth.Authenticators(methods...))
+	switch {
+	case err == nil:
+		c.Set(PrincipalKey, p)
+		fields := logger.Fields{"auth_method": p.Method, "subject": p.Subject}
+		if p.UserID != 0 {
+			c.Set(UserIDKey, p.UserID)
+			fields["user_id"] = p.UserID
+		}
+		addLogFields(c, fields)
+	case err == auth.ErrNoCredentials && !required:
+	case err == auth.ErrNoCredentials || err == auth.ErrInvalidCredentials:
+		unauthorized(c, err.Error())
+		return false
+	default:
+		render.Error(c, apperr.ErrService.WithCause(err))
+		return false
+	}
+	return true
+}
+
+func oneOf(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func unauthorized(c *gin.Context, msg string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	render.Error(c, apperr.ErrUnauthorized.WithMessage("%s", msg))
+}
+
+// CurrentPrincipal return the principal set by the auth middlewares
+func CurrentPrincipal(c *gin.Context) (*auth.Principal, bool) {
+	v, ok := c.Get(PrincipalKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := v.(*auth.Principal)
+	return p, ok
+}
+
+// CurrentUserID return the id of the authenticated user
+func CurrentUserID(c *gin.Context) (int64, bool) {
+	v, ok := c.Get(UserIDKey)
+	if !ok {
+		return 0, false
+	}
+	id, ok := v.(int64)
+	return id, ok
+}
+
+// MustPrincipal return the principal of a route behind RequireAuth, it panics elsewhere
+func MustPrincipal(c *gin.Context) *auth.Principal {
+	p, ok := CurrentPrincipal(c)
+	if !ok {
+		panic("middleware: no principal, the route is not behind RequireAuth")
+	}
+	return p
 }
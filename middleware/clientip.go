@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trustedProxies holds the []*net.IPNet of server.trusted_proxies, replaced on reload
+var trustedProxies atomic.Value
+
+func init() {
+	trustedProxies.Store([]*net.IPNet(nil))
+}
+
+// SetTrustedProxies set the proxies, ips or cidrs, whose X-Forwarded-For and X-Real-Ip ClientIP believes
+func SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		cidr := p
+		if !strings.Contains(p, "/") {
+			// a single address
+			cidr += "/128"
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				cidr = p + "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("trusted proxy %q should be an ip or a cidr", p)
+		}
+		nets = append(nets, n)
+	}
+	trustedProxies.Store(nets)
+	return nil
+}
+
+// ClientIP return the ip of the client. gin's Context.ClientIP believes the forwarded headers of
+// anyone, here they are only read when the direct peer is a trusted proxy, from the right so that
+// the addresses a client prepends to X-Forwarded-For are ignored.
+func ClientIP(c *gin.Context) string {
+	peer := remoteIP(c.Request.RemoteAddr)
+	if !trusted(peer) {
+		return peer
+	}
+
+	if hops := c.GetHeader("X-Forwarded-For"); hops != "" {
+		client := peer
+		list := strings.Split(hops, ",")
+		for i := len(list) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(list[i])
+			if net.ParseIP(ip) == nil {
+				break
+			}
+			client = ip
+			if !trusted(ip) {
+				break
+			}
+		}
+		return client
+	}
+	if ip := strings.TrimSpace(c.GetHeader("X-Real-Ip")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return peer
+}
+
+// remoteIP return the host of a RemoteAddr, the address itself when it has no port
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range trustedProxies.Load().([]*net.IPNet) {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
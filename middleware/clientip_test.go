@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIP(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     []string
+		want       string
+	}{
+		{"direct", "192.0.2.1:4000", nil, "192.0.2.1"},
+		{"untrusted peer", "192.0.2.1:4000", []string{"X-Forwarded-For", "198.51.100.1"}, "192.0.2.1"},
+		{"untrusted real ip", "192.0.2.1:4000", []string{"X-Real-Ip", "198.51.100.1"}, "192.0.2.1"},
+		{"trusted proxy", "10.0.0.1:4000", []string{"X-Forwarded-For", "198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", "10.0.0.1:4000", []string{"X-Forwarded-For", "198.51.100.1, 10.0.0.7"}, "198.51.100.1"},
+		{"prepended by the client", "10.0.0.1:4000", []string{"X-Forwarded-For", "203.0.113.1, 198.51.100.1"}, "198.51.100.1"},
+		{"garbage hop", "10.0.0.1:4000", []string{"X-Forwarded-For", "198.51.100.1, junk"}, "10.0.0.1"},
+		{"real ip", "10.0.0.1:4000", []string{"X-Real-Ip", "198.51.100.1"}, "198.51.100.1"},
+		{"only proxies", "10.0.0.1:4000", []string{"X-Forwarded-For", "10.0.0.2"}, "10.0.0.2"},
+		{"trusted ipv6 proxy", "[2001:db8::1]:4000", []string{"X-Forwarded-For", "2001:db8::2"}, "2001:db8::2"},
+		{"untrusted ipv6 peer", "[2001:db8::3]:4000", []string{"X-Forwarded-For", "198.51.100.1"}, "2001:db8::3"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		for i := 0; i+1 < len(tt.header); i += 2 {
+			req.Header.Set(tt.header[i], tt.header[i+1])
+		}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		if got := ClientIP(c); got != tt.want {
+			t.Errorf("%s: ClientIP = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local", ""} {
+		if err := SetTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("SetTrustedProxies(%q): want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"template_project/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute label the requests of no route, so that scanners do not make a series per path
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of http requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "http_request_duration_seconds",
+		Help: "Latency of the http requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of http requests being served.",
+	})
+)
+
+func init() {
+	metrics.MustRegister(httpRequests, httpDuration, httpInFlight)
+}
+
+// Metrics count the requests and observe their latency by route pattern, after RequestID
+func Metrics(c *gin.Context) {
+	start := time.Now()
+	httpInFlight.Inc()
+	defer httpInFlight.Dec()
+
+	c.Next()
+
+	route := CurrentRoute(c)
+	if route == "" {
+		route = unmatchedRoute
+	}
+	status := strconv.Itoa(c.Writer.Status())
+	httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+	httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+}
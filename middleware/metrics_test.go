@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"template_project/metrics"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupRoutes return a router with two probes sharing the gin.WrapH closure, like the health routes
+func setupRoutes() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(r), Metrics)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, CurrentRoute(c)) }
+	r.GET("/metrics-test/live", gin.WrapH(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	r.GET("/metrics-test/ready", gin.WrapH(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+	r.GET("/metrics-test/users/:id", ok)
+	r.GET("/metrics-test/files/*path", ok)
+	r.GET("/metrics-test/roles", ok)
+	return r
+}
+
+func serve(r http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestCurrentRoute(t *testing.T) {
+	r := setupRoutes()
+	tests := []struct {
+		path, route string
+	}{
+		{"/metrics-test/users/42", "/metrics-test/users/:id"},
+		{"/metrics-test/files/a/b.txt", "/metrics-test/files/*path"},
+		{"/metrics-test/roles", "/metrics-test/roles"},
+	}
+	for _, tt := range tests {
+		if got := serve(r, tt.path).Body.String(); got != tt.route {
+			t.Errorf("%s: route = %q, want %q", tt.path, got, tt.route)
+		}
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		match         bool
+	}{
+		{"/healthz", "/healthz", true},
+		{"/healthz", "/readyz", false},
+		{"/users/:id", "/users/42", true},
+		{"/users/:id", "/users/", false},
+		{"/users/:id", "/users/42/roles", false},
+		{"/files/*path", "/files/", true},
+		{"/files/*path", "/files/a/b", true},
+		{"/files/*path", "/files", false},
+	}
+	for _, tt := range tests {
+		if got := matchRoute(tt.pattern, tt.path); got != tt.match {
+			t.Errorf("matchRoute(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestMetricsScrape(t *testing.T) {
+	r := setupRoutes()
+	serve(r, "/metrics-test/live")
+	serve(r, "/metrics-test/ready")
+	serve(r, "/metrics-test/ready")
+	serve(r, "/metrics-test/nowhere")
+
+	w := serve(metrics.Handler(), "/metrics")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("scrape: status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	body, _ := ioutil.ReadAll(w.Body)
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/metrics-test/live",status="200"} 1`,
+		`http_requests_total{method="GET",route="/metrics-test/ready",status="503"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/metrics-test/ready",status="503"} 2`,
+		`http_requests_in_flight 0`,
+		"# TYPE go_goroutines gauge",
+		"# TYPE process_start_time_seconds gauge",
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("scrape is missing %q", line)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"template_project/auth"
+	"template_project/config"
+	"template_project/db/redis"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testAPIKeys authenticate the X-API-Key header against a fixed set of keys
+type testAPIKeys map[string]int64
+
+func (k testAPIKeys) Method() string {
+	return auth.MethodAPIKey
+}
+
+func (k testAPIKeys) Authenticate(req auth.Credentials) (*auth.Principal, error) {
+	raw := req.GetHeader("X-API-Key")
+	if raw == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	id, ok := k[raw]
+	if !ok {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return &auth.Principal{Method: auth.MethodAPIKey, Subject: "7", UserID: 7, KeyID: id}, nil
+}
+
+// setupRateLimit return a router limiting to 2 requests a minute by keyBy, behind OptionalAuth when authenticated
+func setupRateLimit(t *testing.T, keyBy string, authenticated bool) *gin.Engine {
+	redis.DB = redis.NewMemoryService()
+	config.Lock.Lock()
+	saved := config.Cfg
+	config.Cfg.RateLimit = config.RateLimitConfig{Enable: true, Rules: map[string]config.RateLimitRule{
+		"v1": {Algorithm: "sliding_window", Limit: 2, Window: config.Duration{Duration: time.Minute}, KeyBy: keyBy},
+	}}
+	config.Lock.Unlock()
+
+	err := auth.Init(config.AuthConfig{JWT: config.JWTConfig{Enable: true, Algorithms: []string{"HS256"}, Secret: "test secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Register(testAPIKeys{"key-a": 1, "key-b": 2})
+	t.Cleanup(func() {
+		redis.DB = nil
+		auth.Init(config.AuthConfig{})
+		config.Lock.Lock()
+		config.Cfg = saved
+		config.Lock.Unlock()
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if authenticated {
+		r.Use(OptionalAuth())
+	}
+	r.Use(RateLimit("v1"))
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	return r
+}
+
+// get request /ping from ip with the header set, and return the status
+func get(t *testing.T, r http.Handler, ip string, header ...string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = ip + ":40000"
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+		t.Errorf("429 without Retry-After")
+	}
+	return w.Code
+}
+
+func bearer(t *testing.T, userID int64) []string {
+	token, _, err := auth.IssueToken(strconv.FormatInt(userID, 10), time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []string{"Authorization", "Bearer " + token}
+}
+
+func expectStatus(t *testing.T, what string, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s = %d, want %d", what, got, want)
+	}
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	r := setupRateLimit(t, "ip", true)
+	expectStatus(t, "first", get(t, r, "10.0.0.1"), http.StatusOK)
+	expectStatus(t, "second", get(t, r, "10.0.0.1", bearer(t, 1)...), http.StatusOK)
+	expectStatus(t, "third", get(t, r, "10.0.0.1", "X-API-Key", "key-a"), http.StatusTooManyRequests)
+	expectStatus(t, "other ip", get(t, r, "10.0.0.2"), http.StatusOK)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	r := setupRateLimit(t, "user", true)
+	alice, bob := bearer(t, 1), bearer(t, 2)
+	expectStatus(t, "alice 1", get(t, r, "10.0.0.1", alice...), http.StatusOK)
+	expectStatus(t, "alice 2", get(t, r, "10.0.0.2", alice...), http.StatusOK)
+	expectStatus(t, "alice 3", get(t, r, "10.0.0.3", alice...), http.StatusTooManyRequests)
+	expectStatus(t, "bob", get(t, r, "10.0.0.1", bob...), http.StatusOK)
+	expectStatus(t, "anonymous", get(t, r, "10.0.0.1"), http.StatusOK)
+
+	// a forged token is refused before it reaches the limiter
+	expectStatus(t, "forged", get(t, r, "10.0.0.1", "Authorization", "Bearer forged"), http.StatusUnauthorized)
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	r := setupRateLimit(t, "api_key", true)
+	expectStatus(t, "key a 1", get(t, r, "10.0.0.1", "X-API-Key", "key-a"), http.StatusOK)
+	expectStatus(t, "key a 2", get(t, r, "10.0.0.2", "X-API-Key", "key-a"), http.StatusOK)
+	expectStatus(t, "key a 3", get(t, r, "10.0.0.3", "X-API-Key", "key-a"), http.StatusTooManyRequests)
+	expectStatus(t, "key b", get(t, r, "10.0.0.1", "X-API-Key", "key-b"), http.StatusOK)
+	expectStatus(t, "unknown key", get(t, r, "10.0.0.1", "X-API-Key", "made-up"), http.StatusUnauthorized)
+
+	// a jwt user has no api key and is limited by ip
+	expectStatus(t, "jwt 1", get(t, r, "10.0.0.4", bearer(t, 1)...), http.StatusOK)
+	expectStatus(t, "jwt 2", get(t, r, "10.0.0.4", bearer(t, 1)...), http.StatusOK)
+	expectStatus(t, "jwt 3", get(t, r, "10.0.0.4", bearer(t, 1)...), http.StatusTooManyRequests)
+}
+
+func TestRateLimitUnverifiedCredentials(t *testing.T) {
+	// without an auth middleware before the limiter, made up keys must not open new buckets
+	r := setupRateLimit(t, "api_key", false)
+	expectStatus(t, "first", get(t, r, "10.0.0.1", "X-API-Key", "made-up-1"), http.StatusOK)
+	expectStatus(t, "second", get(t, r, "10.0.0.1", "X-API-Key", "made-up-2"), http.StatusOK)
+	expectStatus(t, "third", get(t, r, "10.0.0.1", "X-API-Key", "made-up-3"), http.StatusTooManyRequests)
+}
+
+func TestRateLimitIgnoresForwardedFor(t *testing.T) {
+	// without trusted proxies the forwarded headers are the client's own, a new one per request
+	// must not open a new bucket
+	r := setupRateLimit(t, "ip", true)
+	expectStatus(t, "first", get(t, r, "10.0.0.1", "X-Forwarded-For", "192.0.2.1"), http.StatusOK)
+	expectStatus(t, "second", get(t, r, "10.0.0.1", "X-Forwarded-For", "192.0.2.2", "X-Real-Ip", "192.0.2.2"), http.StatusOK)
+	expectStatus(t, "third", get(t, r, "10.0.0.1", "X-Forwarded-For", "192.0.2.3"), http.StatusTooManyRequests)
+}
+
+func TestRateLimitBehindTrustedProxy(t *testing.T) {
+	r := setupRateLimit(t, "ip", true)
+	if err := SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	expectStatus(t, "client a 1", get(t, r, "10.0.0.1", "X-Forwarded-For", "192.0.2.1"), http.StatusOK)
+	expectStatus(t, "client b", get(t, r, "10.0.0.1", "X-Forwarded-For", "192.0.2.2"), http.StatusOK)
+	// an address the client prepends is not the one the proxy saw
+	expectStatus(t, "client a 2", get(t, r, "10.0.0.2", "X-Forwarded-For", "198.51.100.9, 192.0.2.1"), http.StatusOK)
+	expectStatus(t, "client a 3", get(t, r, "10.0.0.1", "X-Forwarded-For", "192.0.2.1"), http.StatusTooManyRequests)
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"template_project/auth"
+	"template_project/db/mysql"
+	"template_project/model"
+	"template_project/utils/apperr"
+	"template_project/utils/render"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionsKey is the gin context key caching the permissions of the principal during a request
+const PermissionsKey = "permissions"
+
+// Require let through the requests whose principal holds every one of permissions,
+// e.g. v1.GET("/users", middleware.Require("users:read"), handler.ListUsers).
+// It authenticates the request when no auth middleware ran before, unauthenticated requests get 401
+// and the ones lacking a permission get 403.
+//
+// The permissions of a user are the ones of its roles. The scopes of an api key or token narrow them,
+// and are the permissions themselves for principals which are not users.
+func Require(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := CurrentPrincipal(c)
+		if !ok {
+			if !resolvePrincipal(c, true, nil) {
+				return
+			}
+			p = MustPrincipal(c)
+		}
+
+		granted, err := principalPermissions(c, p)
+		if err != nil {
+			render.Error(c, apperr.ErrService.WithCause(fmt.Errorf("permissions of %s %s: %v", p.Method, p.Subject, err)))
+			return
+		}
+		for _, permission := range permissions {
+			if !permits(granted, permission) || (p.UserID != 0 && len(p.Scopes) > 0 && !permits(p.Scopes, permission)) {
+				render.Error(c, apperr.ErrForbidden.WithMessage("missing permission %s", permission))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// principalPermissions return the permissions of p, looked up once per request
+func principalPermissions(c *gin.Context, p *auth.Principal) ([]string, error) {
+	if v, ok := c.Get(PermissionsKey); ok {
+		return v.([]string), nil
+	}
+	granted := p.Scopes
+	if p.UserID != 0 {
+		granted = nil
+		if mysql.DB != nil {
+			var err error
+			if granted, err = model.QueryPermissionsByUserId(p.UserID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	c.Set(PermissionsKey, granted)
+	return granted, nil
+}
+
+// permits report whether granted covers permission, "*" covers everything and "users:*" every users permission
+func permits(granted []string, permission string) bool {
+	for _, g := range granted {
+		if g == permission || g == "*" {
+			return true
+		}
+		if strings.HasSuffix(g, ":*") && strings.HasPrefix(permission, g[:len(g)-1]) {
+			return true
+		}
+	}
+	return false
+}
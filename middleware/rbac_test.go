@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"template_project/auth"
+	"template_project/config"
+	"template_project/db/mysql/mysqltest"
+	"template_project/model"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scopedKeys authenticate the X-API-Key header as the principal of the key
+type scopedKeys map[string]auth.Principal
+
+func (k scopedKeys) Method() string {
+	return auth.MethodAPIKey
+}
+
+func (k scopedKeys) Authenticate(req auth.Credentials) (*auth.Principal, error) {
+	raw := req.GetHeader("X-API-Key")
+	if raw == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	p, ok := k[raw]
+	if !ok {
+		return nil, auth.ErrInvalidCredentials
+	}
+	p.Method = auth.MethodAPIKey
+	return &p, nil
+}
+
+// setupRBAC return a router with a route per permission, user 1 is admin and user 2 reads users
+func setupRBAC(t *testing.T, keys scopedKeys) *gin.Engine {
+	db := mysqltest.Use(t, &model.Role{}, &model.Permission{}, &model.RolePermission{}, &model.UserRole{})
+	create := func(v interface{}) {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	grants := map[int64][]string{1: {"*"}, 2: {"users:read"}}
+	for user, permissions := range grants {
+		role := model.Role{Name: "role of user " + strconv.FormatInt(user, 10)}
+		create(&role)
+		for _, name := range permissions {
+			permission := model.Permission{Name: name}
+			create(&permission)
+			create(&model.RolePermission{RoleId: role.Id, PermissionId: permission.Id})
+		}
+		// Grant also drop the permissions an earlier test left in the cache
+		if _, err := (&model.UserRole{}).Grant(user, role.Id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := auth.Init(config.AuthConfig{JWT: config.JWTConfig{Enable: true, Algorithms: []string{"HS256"}, Secret: "test secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Register(keys)
+	t.Cleanup(func() { auth.Init(config.AuthConfig{}) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	r.GET("/users", Require("users:read"), ok)
+	r.POST("/users", Require("users:write"), ok)
+	r.GET("/roles", Require("roles:read"), ok)
+	r.GET("/users/roles", Require("users:read", "roles:read"), ok)
+	return r
+}
+
+// request send method path with the header set and return the status
+func request(r http.Handler, method, path string, header ...string) int {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestPermits(t *testing.T) {
+	tests := []struct {
+		granted    []string
+		permission string
+		want       bool
+	}{
+		{[]string{"users:read"}, "users:read", true},
+		{[]string{"users:read"}, "users:write", false},
+		{[]string{"*"}, "logs:write", true},
+		{[]string{"users:*"}, "users:write", true},
+		{[]string{"users:*"}, "roles:read", false},
+		{[]string{"users:*"}, "usersx:read", false},
+		{[]string{"users:*"}, "users", false},
+		{[]string{"roles:read", "users:*"}, "users:read", true},
+		{nil, "users:read", false},
+	}
+	for _, tt := range tests {
+		if got := permits(tt.granted, tt.permission); got != tt.want {
+			t.Errorf("permits(%v, %s) = %v, want %v", tt.granted, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	r := setupRBAC(t, scopedKeys{
+		// the scopes of a user's key narrow the permissions of the user
+		"admin-read": {Subject: "1", UserID: 1, Scopes: []string{"users:read"}},
+		// a scope can not widen them
+		"reader-all": {Subject: "2", UserID: 2, Scopes: []string{"*"}},
+		// the scopes are the permissions of a key without user
+		"service": {Subject: "service", Scopes: []string{"users:*"}},
+	})
+	tests := []struct {
+		name         string
+		method, path string
+		header       []string
+		want         int
+	}{
+		{"anonymous", http.MethodGet, "/users", nil, http.StatusUnauthorized},
+		{"bad token", http.MethodGet, "/users", []string{"Authorization", "Bearer forged"}, http.StatusUnauthorized},
+
+		{"admin reads", http.MethodGet, "/users", bearer(t, 1), http.StatusOK},
+		{"admin writes", http.MethodPost, "/users", bearer(t, 1), http.StatusOK},
+		{"admin both", http.MethodGet, "/users/roles", bearer(t, 1), http.StatusOK},
+		{"reader reads", http.MethodGet, "/users", bearer(t, 2), http.StatusOK},
+		{"reader writes", http.MethodPost, "/users", bearer(t, 2), http.StatusForbidden},
+		{"reader both", http.MethodGet, "/users/roles", bearer(t, 2), http.StatusForbidden},
+		{"user without role", http.MethodGet, "/users", bearer(t, 3), http.StatusForbidden},
+
+		{"admin key reads", http.MethodGet, "/users", []string{"X-API-Key", "admin-read"}, http.StatusOK},
+		{"admin key writes", http.MethodPost, "/users", []string{"X-API-Key", "admin-read"}, http.StatusForbidden},
+		{"reader key writes", http.MethodPost, "/users", []string{"X-API-Key", "reader-all"}, http.StatusForbidden},
+		{"service reads", http.MethodGet, "/users", []string{"X-API-Key", "service"}, http.StatusOK},
+		{"service writes", http.MethodPost, "/users", []string{"X-API-Key", "service"}, http.StatusOK},
+		{"service roles", http.MethodGet, "/roles", []string{"X-API-Key", "service"}, http.StatusForbidden},
+		{"unknown key", http.MethodGet, "/users", []string{"X-API-Key", "made-up"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := request(r, tt.method, tt.path, tt.header...); got != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d", tt.name, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
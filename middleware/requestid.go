@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"template_project/logger"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader carry the id of a request, accepted from the client or generated
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the gin context key holding the id of the request
+	RequestIDKey = "request_id"
+	// RouteKey is the gin context key holding the route pattern of the request, e.g. /api/v1/users/:id
+	RouteKey = "route"
+
+	maxRequestIDLength = 128
+)
+
+var httpLog = logger.Named(logger.ModuleHTTP)
+
+// RequestID accept the X-Request-ID of the client or generate one, echo it in the response
+// and set the request logger returned by logger.FromContext
+func RequestID(e *gin.Engine) gin.HandlerFunc {
+	routes := &routeTable{engine: e}
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		fields := logger.Fields{
+			"request_id": id,
+			"method":     c.Request.Method,
+			"client_ip":  ClientIP(c),
+		}
+		if route := routes.lookup(c); route != "" {
+			c.Set(RouteKey, route)
+			fields["route"] = route
+		}
+		setRequestLogger(c, httpLog.WithFields(fields))
+		c.Next()
+	}
+}
+
+// CurrentRequestID return the id set by RequestID
+func CurrentRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// CurrentRoute return the route pattern set by RequestID, empty when no route matched
+func CurrentRoute(c *gin.Context) string {
+	return c.GetString(RouteKey)
+}
+
+// addLogFields add fields to the request logger, e.g. the user once authenticated
+func addLogFields(c *gin.Context, fields logger.Fields) {
+	setRequestLogger(c, logger.FromContext(c).WithFields(fields))
+}
+
+// setRequestLogger make l the logger of both the gin context and the request context
+func setRequestLogger(c *gin.Context, l logger.LoggerI) {
+	c.Set(logger.ContextKey, l)
+	c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), l))
+}
+
+// validRequestID accept the ids of printable ascii, so that clients can not inject into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// routeTable find the route pattern of a request, gin only knows the handler it matched.
+// It is built on the first request, once every route is registered.
+type routeTable struct {
+	engine *gin.Engine
+	once   sync.Once
+	// routes map the method and handler name to the paths it is registered on,
+	// several when the handler is shared, e.g. the closure of gin.WrapH
+	routes map[string][]string
+}
+
+func (t *routeTable) lookup(c *gin.Context) string {
+	t.once.Do(func() {
+		t.routes = make(map[string][]string)
+		for _, r := range t.engine.Routes() {
+			key := r.Method + " " + r.Handler
+			t.routes[key] = append(t.routes[key], r.Path)
+		}
+	})
+	paths := t.routes[c.Request.Method+" "+c.HandlerName()]
+	if len(paths) == 1 {
+		return paths[0]
+	}
+	for _, p := range paths {
+		if matchRoute(p, c.Request.URL.Path) {
+			return p
+		}
+	}
+	return ""
+}
+
+// matchRoute report whether path is served by the route pattern, with its :param and *catchall segments
+func matchRoute(pattern, path string) bool {
+	want, got := strings.Split(pattern, "/"), strings.Split(path, "/")
+	for i, seg := range want {
+		if strings.HasPrefix(seg, "*") {
+			return i < len(got)
+		}
+		if i >= len(got) {
+			return false
+		}
+		if strings.HasPrefix(seg, ":") {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if seg != got[i] {
+			return false
+		}
+	}
+	return len(want) == len(got)
+}
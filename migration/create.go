@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+const template = `package migration
+
+import "github.com/jinzhu/gorm"
+
+func init() {
+	Register(Migration{
+		Version: %s,
+		Name:    %q,
+		// the steps are not transactional, check the schema before changing it so that a rerun completes them
+		Up: func(db *gorm.DB) error {
+			return nil
+		},
+		Down: func(db *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
+
+// Create write a new migration skeleton into dir and return its path,
+// the version is the current UTC time so that files sort in creation order
+func Create(dir, name string) (string, error) {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("migration name should contain letters or digits")
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	path := filepath.Join(dir, fmt.Sprintf("v%s_%s.go", version, name))
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("migration file %s already exists", path)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf(template, version, name)), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,232 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// lockTimeout is how long a migrator waits for another one to finish
+const lockTimeout = 60 * time.Second
+
+// ErrLocked is returned when another process holds the migration lock
+var ErrLocked = errors.New("another migration is running")
+
+// Migration is a versioned schema change.
+// Down may be nil for a step that can not be rolled back.
+//
+// The steps run outside of a transaction, MySQL commits every DDL statement on its own.
+// A step failing halfway keeps the statements that ran and is not recorded, so it runs again
+// on the next up: steps should check the schema before changing it so that a rerun completes them.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(db *gorm.DB) error
+	Down    func(db *gorm.DB) error
+}
+
+// SchemaMigration is the bookkeeping row of an applied migration
+type SchemaMigration struct {
+	Version   int64  `gorm:"primary_key;auto_increment:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+func (*SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status of a registered migration
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var registry = map[int64]Migration{}
+
+// Register add a migration, it is called from the init of each migration file
+func Register(m Migration) {
+	if m.Up == nil {
+		panic(fmt.Sprintf("migration %d_%s has no Up", m.Version, m.Name))
+	}
+	if old, ok := registry[m.Version]; ok {
+		panic(fmt.Sprintf("migration version %d registered twice: %s and %s", m.Version, old.Name, m.Name))
+	}
+	registry[m.Version] = m
+}
+
+// Migrations return the registered migrations ordered by version
+func Migrations() []Migration {
+	list := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+type Migrator struct {
+	db *gorm.DB
+}
+
+func New(db *gorm.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// Status list every registered migration and whether it is applied
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var list []Status
+	for _, mg := range Migrations() {
+		row, ok := applied[mg.Version]
+		list = append(list, Status{Migration: mg, Applied: ok, AppliedAt: row.AppliedAt})
+	}
+	return list, nil
+}
+
+// Pending list the registered migrations not applied yet, all of them on a database never migrated
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var list []Migration
+	for _, mg := range Migrations() {
+		if _, ok := applied[mg.Version]; !ok {
+			list = append(list, mg)
+		}
+	}
+	return list, nil
+}
+
+// Up apply every pending migration in version order
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		if err := m.ensureTable(); err != nil {
+			return err
+		}
+		pending, err := m.Pending()
+		if err != nil {
+			return err
+		}
+		for _, mg := range pending {
+			if err := m.run(mg, true); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down roll back the n most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func() error {
+		status, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for i := len(status) - 1; i >= 0 && len(done) < n; i-- {
+			if !status[i].Applied {
+				continue
+			}
+			mg := status[i].Migration
+			if err := m.run(mg, false); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// run apply or roll back a single migration, then write or delete its bookkeeping row.
+// The row only changes once the step succeeded, see Migration for what a failed step leaves.
+func (m *Migrator) run(mg Migration, up bool) error {
+	step, direction := mg.Up, "up"
+	if !up {
+		step, direction = mg.Down, "down"
+		if step == nil {
+			return fmt.Errorf("migration %d_%s can not be rolled back", mg.Version, mg.Name)
+		}
+	}
+
+	if err := step(m.db); err != nil {
+		return fmt.Errorf("migration %d_%s %s: %v", mg.Version, mg.Name, direction, err)
+	}
+
+	var err error
+	if up {
+		err = m.db.Create(&SchemaMigration{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+	} else {
+		err = m.db.Where("version = ?", mg.Version).Delete(&SchemaMigration{}).Error
+	}
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s done but not recorded: %v", mg.Version, mg.Name, direction, err)
+	}
+	return nil
+}
+
+// applied return the applied migrations by version. It only reads, without the table of
+// the applied migrations nothing is applied: Status and Pending never change the schema.
+func (m *Migrator) applied() (map[int64]SchemaMigration, error) {
+	if !m.db.HasTable(&SchemaMigration{}) {
+		return map[int64]SchemaMigration{}, nil
+	}
+	var rows []SchemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	if m.db.HasTable(&SchemaMigration{}) {
+		return nil
+	}
+	return m.db.CreateTable(&SchemaMigration{}).Error
+}
+
+// withLock run fn while holding a mysql named lock so that two pods never migrate at once.
+// The lock belongs to a session, so it is taken on a dedicated connection.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	conn, err := m.db.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var database string
+	if err := conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		return err
+	}
+	name := "schema_migrations:" + database
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(lockTimeout.Seconds())).Scan(&got); err != nil {
+		return err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return ErrLocked
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+
+	return fn()
+}
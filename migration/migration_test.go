@@ -0,0 +1,256 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/mattn/go-sqlite3"
+)
+
+// lockResult is what GET_LOCK answers in the test database, 1 when the lock is taken, 0 on a timeout
+var lockResult int64 = 1
+
+func init() {
+	// sqlite lacks the mysql functions withLock calls, the test driver adds them
+	sql.Register("sqlite3_migration", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("database", func() string { return "test" }, true); err != nil {
+				return err
+			}
+			getLock := func(name string, timeout int64) int64 { return atomic.LoadInt64(&lockResult) }
+			if err := conn.RegisterFunc("get_lock", getLock, false); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("release_lock", func(name string) int64 { return 1 }, false)
+		},
+	})
+}
+
+// newTestDB return a gorm DB on a sqlite file of the test
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3_migration", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open("sqlite3", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// steps record the migrations run, "+1" for the up of version 1 and "-1" for its down
+type steps []string
+
+// useRegistry replace the registered migrations with versions for the test, in the order given
+func useRegistry(t *testing.T, ran *steps, versions ...int64) {
+	saved := registry
+	registry = map[int64]Migration{}
+	t.Cleanup(func() { registry = saved })
+	for _, v := range versions {
+		register(ran, v)
+	}
+}
+
+func register(ran *steps, version int64) {
+	Register(Migration{
+		Version: version,
+		Name:    "test",
+		Up: func(db *gorm.DB) error {
+			*ran = append(*ran, "+"+strconv.FormatInt(version, 10))
+			return nil
+		},
+		Down: func(db *gorm.DB) error {
+			*ran = append(*ran, "-"+strconv.FormatInt(version, 10))
+			return nil
+		},
+	})
+}
+
+func versions(list []Migration) []int64 {
+	var v []int64
+	for _, m := range list {
+		v = append(v, m.Version)
+	}
+	return v
+}
+
+func expect(t *testing.T, what string, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+	}
+}
+
+func TestUpInVersionOrder(t *testing.T) {
+	var ran steps
+	useRegistry(t, &ran, 3, 1, 2)
+	m := New(newTestDB(t))
+
+	done, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "up", versions(done), []int64{1, 2, 3})
+	expect(t, "steps", ran, steps{"+1", "+2", "+3"})
+
+	status, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt.IsZero() {
+			t.Errorf("version %d: applied %v at %v", s.Version, s.Applied, s.AppliedAt)
+		}
+	}
+}
+
+func TestPendingReadOnly(t *testing.T) {
+	var ran steps
+	useRegistry(t, &ran, 2, 1)
+	db := newTestDB(t)
+	m := New(db)
+
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "pending", versions(pending), []int64{1, 2})
+	status, err := m.Status()
+	if err != nil || len(status) != 2 || status[0].Applied || status[1].Applied {
+		t.Errorf("status = %+v, %v, want nothing applied", status, err)
+	}
+	if db.HasTable(&SchemaMigration{}) {
+		t.Error("Pending or Status created the table of the applied migrations")
+	}
+}
+
+func TestUpSkipsApplied(t *testing.T) {
+	var ran steps
+	useRegistry(t, &ran, 1, 2)
+	m := New(newTestDB(t))
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	register(&ran, 3)
+	ran = nil
+	done, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "second up", versions(done), []int64{3})
+	expect(t, "steps", ran, steps{"+3"})
+
+	ran = nil
+	if done, err := m.Up(context.Background()); err != nil || len(done) != 0 || len(ran) != 0 {
+		t.Errorf("up with nothing pending: ran %v, %v", ran, err)
+	}
+}
+
+func TestUpStopsAtFailure(t *testing.T) {
+	var ran steps
+	useRegistry(t, &ran, 1, 3)
+	Register(Migration{Version: 2, Name: "broken", Up: func(db *gorm.DB) error { return errors.New("boom") }})
+	m := New(newTestDB(t))
+
+	done, err := m.Up(context.Background())
+	if err == nil {
+		t.Fatal("want the error of version 2")
+	}
+	expect(t, "up", versions(done), []int64{1})
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the failed step is not recorded, so that the next up runs it again
+	expect(t, "pending", versions(pending), []int64{2, 3})
+}
+
+func TestDownLast(t *testing.T) {
+	var ran steps
+	useRegistry(t, &ran, 1, 2, 3)
+	m := New(newTestDB(t))
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ran = nil
+	done, err := m.Down(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "down", versions(done), []int64{3})
+	expect(t, "steps", ran, steps{"-3"})
+	pending, _ := m.Pending()
+	expect(t, "pending", versions(pending), []int64{3})
+
+	ran = nil
+	if done, err = m.Down(context.Background(), 5); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "down the rest", versions(done), []int64{2, 1})
+	expect(t, "steps", ran, steps{"-2", "-1"})
+}
+
+func TestDownIrreversible(t *testing.T) {
+	var ran steps
+	useRegistry(t, &ran, 1)
+	Register(Migration{Version: 2, Name: "irreversible", Up: func(db *gorm.DB) error { return nil }})
+	m := New(newTestDB(t))
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(context.Background(), 2); err == nil {
+		t.Fatal("down of a migration without Down: want an error")
+	}
+	pending, _ := m.Pending()
+	if len(pending) != 0 {
+		t.Errorf("pending = %v, want none rolled back", versions(pending))
+	}
+}
+
+func TestLocked(t *testing.T) {
+	var ran steps
+	useRegistry(t, &ran, 1)
+	m := New(newTestDB(t))
+
+	atomic.StoreInt64(&lockResult, 0)
+	defer atomic.StoreInt64(&lockResult, 1)
+	if _, err := m.Up(context.Background()); err != ErrLocked {
+		t.Fatalf("up error = %v, want ErrLocked", err)
+	}
+	if _, err := m.Down(context.Background(), 1); err != ErrLocked {
+		t.Fatalf("down error = %v, want ErrLocked", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("ran %v without the lock", ran)
+	}
+}
+
+func TestUserTimestampsRefuseUnnamed(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Exec("CREATE TABLE user (id INTEGER PRIMARY KEY, name VARCHAR(64), age INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("INSERT INTO user (name, age) VALUES ('ann', 1), (NULL, 2)")
+
+	err := registry[20190801000000].Up(db)
+	if err == nil || !strings.Contains(err.Error(), "1 users have no name") {
+		t.Fatalf("up error = %v, want the unnamed users refused", err)
+	}
+	if db.Dialect().HasColumn("user", "created_at") {
+		t.Error("the table was changed before the refusal")
+	}
+}
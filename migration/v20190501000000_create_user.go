@@ -0,0 +1,30 @@
+package migration
+
+import "github.com/jinzhu/gorm"
+
+type user20190501000000 struct {
+	Id   int
+	Name string
+	Age  int
+}
+
+func (*user20190501000000) TableName() string {
+	return "user"
+}
+
+func init() {
+	Register(Migration{
+		Version: 20190501000000,
+		Name:    "create_user",
+		Up: func(db *gorm.DB) error {
+			// databases set up by the former auto migrate already have the table
+			if db.HasTable(&user20190501000000{}) {
+				return nil
+			}
+			return db.CreateTable(&user20190501000000{}).Error
+		},
+		Down: func(db *gorm.DB) error {
+			return db.DropTableIfExists(&user20190501000000{}).Error
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type apiKey20190601000000 struct {
+	Id         int64  `gorm:"primary_key"`
+	UserId     int64  `gorm:"not null;index"`
+	Name       string `gorm:"type:varchar(64);not null"`
+	Prefix     string `gorm:"type:varchar(32);not null;unique_index"`
+	KeyHash    string `gorm:"type:char(64);not null"`
+	Scopes     string `gorm:"type:varchar(255);not null;default:''"`
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (*apiKey20190601000000) TableName() string {
+	return "api_key"
+}
+
+func init() {
+	Register(Migration{
+		Version: 20190601000000,
+		Name:    "create_api_key",
+		Up: func(db *gorm.DB) error {
+			if db.HasTable(&apiKey20190601000000{}) {
+				return nil
+			}
+			return db.CreateTable(&apiKey20190601000000{}).Error
+		},
+		Down: func(db *gorm.DB) error {
+			return db.DropTableIfExists(&apiKey20190601000000{}).Error
+		},
+	})
+}
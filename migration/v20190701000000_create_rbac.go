@@ -0,0 +1,87 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type role20190701000000 struct {
+	Id          int64  `gorm:"primary_key"`
+	Name        string `gorm:"type:varchar(64);not null;unique_index"`
+	Description string `gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (*role20190701000000) TableName() string {
+	return "role"
+}
+
+type permission20190701000000 struct {
+	Id          int64  `gorm:"primary_key"`
+	Name        string `gorm:"type:varchar(64);not null;unique_index"`
+	Description string `gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (*permission20190701000000) TableName() string {
+	return "permission"
+}
+
+type rolePermission20190701000000 struct {
+	RoleId       int64 `gorm:"primary_key;auto_increment:false"`
+	PermissionId int64 `gorm:"primary_key;auto_increment:false"`
+}
+
+func (*rolePermission20190701000000) TableName() string {
+	return "role_permission"
+}
+
+type userRole20190701000000 struct {
+	UserId    int64 `gorm:"primary_key;auto_increment:false"`
+	RoleId    int64 `gorm:"primary_key;auto_increment:false;index"`
+	CreatedAt time.Time
+}
+
+func (*userRole20190701000000) TableName() string {
+	return "user_role"
+}
+
+func init() {
+	Register(Migration{
+		Version: 20190701000000,
+		Name:    "create_rbac",
+		Up: func(db *gorm.DB) error {
+			for _, table := range []interface{}{&role20190701000000{}, &permission20190701000000{},
+				&rolePermission20190701000000{}, &userRole20190701000000{}} {
+				if db.HasTable(table) {
+					continue
+				}
+				if err := db.CreateTable(table).Error; err != nil {
+					return err
+				}
+			}
+
+			// the admin role holds every permission, "*" matches them all
+			var admin role20190701000000
+			if err := db.Where(role20190701000000{Name: "admin"}).Attrs(role20190701000000{Description: "full access"}).FirstOrCreate(&admin).Error; err != nil {
+				return err
+			}
+			var all permission20190701000000
+			if err := db.Where(permission20190701000000{Name: "*"}).Attrs(permission20190701000000{Description: "every permission"}).FirstOrCreate(&all).Error; err != nil {
+				return err
+			}
+			for _, name := range []string{"users:read", "users:write", "roles:read"} {
+				if err := db.Where(permission20190701000000{Name: name}).FirstOrCreate(&permission20190701000000{}).Error; err != nil {
+					return err
+				}
+			}
+			grant := rolePermission20190701000000{RoleId: admin.Id, PermissionId: all.Id}
+			return db.Where(grant).FirstOrCreate(&rolePermission20190701000000{}).Error
+		},
+		Down: func(db *gorm.DB) error {
+			return db.DropTableIfExists(&userRole20190701000000{}, &rolePermission20190701000000{},
+				&permission20190701000000{}, &role20190701000000{}).Error
+		},
+	})
+}
@@ -0,0 +1,106 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type user20190801000000 struct {
+	Id        int
+	Name      string `gorm:"type:varchar(64);not null"`
+	Age       int    `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (*user20190801000000) TableName() string {
+	return "user"
+}
+
+// duplicateUserNames return up to 10 names shared by several users, as name x count
+func duplicateUserNames(db *gorm.DB, table string) ([]string, error) {
+	var rows []struct {
+		Name string
+		N    int
+	}
+	err := db.Table(table).Select("name, COUNT(*) AS n").Group("name").Having("COUNT(*) > 1").
+		Order("name").Limit(10).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(rows))
+	for _, r := range rows {
+		names = append(names, fmt.Sprintf("%q x%d", r.Name, r.N))
+	}
+	return names, nil
+}
+
+func init() {
+	Register(Migration{
+		Version: 20190801000000,
+		Name:    "add_user_timestamps",
+		Up: func(db *gorm.DB) error {
+			table := db.NewScope(&user20190801000000{}).TableName()
+			hasIndex := db.Dialect().HasIndex(table, "uix_user_name")
+			if !hasIndex {
+				// check before any change, the unique index can not be added over duplicates
+				names, err := duplicateUserNames(db, table)
+				if err != nil {
+					return err
+				}
+				if len(names) > 0 {
+					return fmt.Errorf("user names are not unique (%s), rename or delete the duplicates and migrate again",
+						strings.Join(names, ", "))
+				}
+			}
+
+			// name becomes NOT NULL, refused over users without a name like the index over duplicates
+			var unnamed int
+			if err := db.Table(table).Where("name IS NULL").Count(&unnamed).Error; err != nil {
+				return err
+			}
+			if unnamed > 0 {
+				return fmt.Errorf("%d users have no name, name or delete them and migrate again", unnamed)
+			}
+
+			m := db.Model(&user20190801000000{})
+			if err := m.ModifyColumn("name", "varchar(64) NOT NULL").Error; err != nil {
+				return err
+			}
+			// auto migrate only adds the missing created_at and updated_at columns
+			if err := db.AutoMigrate(&user20190801000000{}).Error; err != nil {
+				return err
+			}
+			err := db.Model(&user20190801000000{}).Where("created_at IS NULL").UpdateColumns(map[string]interface{}{
+				"created_at": gorm.Expr("NOW()"),
+				"updated_at": gorm.Expr("NOW()"),
+			}).Error
+			if err != nil || hasIndex {
+				return err
+			}
+			return m.AddUniqueIndex("uix_user_name", "name").Error
+		},
+		// Down undoes what a partially applied Up did, skipping what is missing
+		Down: func(db *gorm.DB) error {
+			table := db.NewScope(&user20190801000000{}).TableName()
+			m := db.Model(&user20190801000000{})
+			if db.Dialect().HasIndex(table, "uix_user_name") {
+				if err := m.RemoveIndex("uix_user_name").Error; err != nil {
+					return err
+				}
+			}
+			for _, column := range []string{"created_at", "updated_at"} {
+				if !db.Dialect().HasColumn(table, column) {
+					continue
+				}
+				if err := m.DropColumn(column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type permission20190901000000 struct {
+	Id          int64  `gorm:"primary_key"`
+	Name        string `gorm:"type:varchar(64);not null;unique_index"`
+	Description string `gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (*permission20190901000000) TableName() string {
+	return "permission"
+}
+
+type rolePermission20190901000000 struct {
+	RoleId       int64 `gorm:"primary_key;auto_increment:false"`
+	PermissionId int64 `gorm:"primary_key;auto_increment:false"`
+}
+
+func (*rolePermission20190901000000) TableName() string {
+	return "role_permission"
+}
+
+var logPermissions20190901000000 = []string{"logs:read", "logs:write"}
+
+func init() {
+	Register(Migration{
+		Version: 20190901000000,
+		Name:    "add_log_permissions",
+		Up: func(db *gorm.DB) error {
+			for _, name := range logPermissions20190901000000 {
+				if err := db.Where(permission20190901000000{Name: name}).FirstOrCreate(&permission20190901000000{}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(db *gorm.DB) error {
+			var ids []int64
+			err := db.Model(&permission20190901000000{}).Where("name IN (?)", logPermissions20190901000000).Pluck("id", &ids).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			// the grants of the permissions go with them
+			if err := db.Where("permission_id IN (?)", ids).Delete(&rolePermission20190901000000{}).Error; err != nil {
+				return err
+			}
+			return db.Where("id IN (?)", ids).Delete(&permission20190901000000{}).Error
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"template_project/db/mysql"
+	"time"
+)
+
+// APIKey is an api key of a user, only the sha256 of the key is stored
+type APIKey struct {
+	Id         int64      `json:"id"`
+	UserId     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // public part of the key used to find it
+	KeyHash    string     `json:"-"`
+	Scopes     string     `json:"scopes"` // comma separated
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (*APIKey) TableName() string {
+	return "api_key"
+}
+
+func (this *APIKey) QueryByPrefix(prefix string) (*APIKey, error) {
+	key := APIKey{}
+	ret := mysql.DB.Model(&APIKey{}).Where("prefix = ?", prefix).First(&key)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	return &key, nil
+}
+
+func (this *APIKey) Create() error {
+	return mysql.DB.Create(this).Error
+}
+
+// Revoke mark the key id as revoked, it reports false when no active key has this id
+func (this *APIKey) Revoke(id int64) (bool, error) {
+	ret := mysql.DB.Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	return ret.RowsAffected > 0, ret.Error
+}
+
+// Touch record the last use of the key id
+func (this *APIKey) Touch(id int64, at time.Time) error {
+	return mysql.DB.Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
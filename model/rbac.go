@@ -0,0 +1,174 @@
+package model
+
+import (
+	"template_project/cache"
+	"template_project/db/mysql"
+	"template_project/db/redis"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PermissionCacheTTL is how long the servers cache the permissions of a user in redis
+const PermissionCacheTTL = 10 * time.Minute
+
+var (
+	permissionCacheOnce sync.Once
+	permissionCache     *cache.Cache
+)
+
+type Role struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (*Role) TableName() string {
+	return "role"
+}
+
+// Permission is a name like "users:read", "users:*" or "*"
+type Permission struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (*Permission) TableName() string {
+	return "permission"
+}
+
+type RolePermission struct {
+	RoleId       int64 `json:"role_id" gorm:"primary_key;auto_increment:false"`
+	PermissionId int64 `json:"permission_id" gorm:"primary_key;auto_increment:false"`
+}
+
+func (*RolePermission) TableName() string {
+	return "role_permission"
+}
+
+type UserRole struct {
+	UserId    int64     `json:"user_id" gorm:"primary_key;auto_increment:false"`
+	RoleId    int64     `json:"role_id" gorm:"primary_key;auto_increment:false"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*UserRole) TableName() string {
+	return "user_role"
+}
+
+// -----------------role operation------------------
+func (this *Role) QueryByName(name string) (*Role, error) {
+	role := Role{}
+	ret := mysql.DB.Model(&Role{}).Where("name = ?", name).First(&role)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	return &role, nil
+}
+
+func (this *Role) QueryAll() ([]Role, error) {
+	var roles []Role
+	ret := mysql.DB.Model(&Role{}).Order("name").Find(&roles)
+	return roles, ret.Error
+}
+
+// QueryByUserId return the roles granted to the user
+func (this *Role) QueryByUserId(userId int64) ([]Role, error) {
+	var roles []Role
+	ret := mysql.DB.Model(&Role{}).
+		Joins(fmt.Sprintf("JOIN %s ur ON ur.role_id = %s.id", tableName(&UserRole{}), tableName(&Role{}))).
+		Where("ur.user_id = ?", userId).Order("name").Find(&roles)
+	return roles, ret.Error
+}
+
+// -----------------user role operation------------------
+// Grant give the role to the user, it reports false when the user already had it.
+// The primary key (user_id, role_id) decides between concurrent grants.
+func (this *UserRole) Grant(userId, roleId int64) (bool, error) {
+	if err := mysql.DB.Create(&UserRole{UserId: userId, RoleId: roleId}).Error; err != nil {
+		if isDuplicateEntry(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := InvalidatePermissions(userId); err != nil {
+		return true, fmt.Errorf("role granted but the cached permissions are stale: %v", err)
+	}
+	return true, nil
+}
+
+// Revoke take the role from the user, it reports false when the user did not have it
+func (this *UserRole) Revoke(userId, roleId int64) (bool, error) {
+	ret := mysql.DB.Where("user_id = ? AND role_id = ?", userId, roleId).Delete(&UserRole{})
+	if ret.Error != nil {
+		return false, ret.Error
+	}
+	if err := InvalidatePermissions(userId); err != nil {
+		return ret.RowsAffected > 0, fmt.Errorf("role revoked but the cached permissions are stale: %v", err)
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+// -----------------permission operation------------------
+// permissionsCache is built on first use since redis is initialized after the package,
+// it is nil when redis is disabled
+func permissionsCache() *cache.Cache {
+	permissionCacheOnce.Do(func() {
+		if redis.DB != nil {
+			permissionCache = cache.New(redis.DB, cache.Options{Prefix: "perm:"})
+		}
+	})
+	return permissionCache
+}
+
+// QueryPermissionsByUserId return the names of the permissions the roles of the user hold,
+// cached in redis until the roles of the user change
+func QueryPermissionsByUserId(userId int64) ([]string, error) {
+	c := permissionsCache()
+	if c == nil {
+		return queryPermissionsByUserId(userId)
+	}
+
+	var names []string
+	err := c.GetOrLoad(strconv.FormatInt(userId, 10), PermissionCacheTTL, &names, func() (interface{}, error) {
+		return queryPermissionsByUserId(userId)
+	})
+	return names, err
+}
+
+// InvalidatePermissions drop the cached permissions of the users,
+// it must be called whenever their roles or the permissions of their roles change
+func InvalidatePermissions(userIds ...int64) error {
+	c := permissionsCache()
+	if c == nil || len(userIds) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(userIds))
+	for _, id := range userIds {
+		keys = append(keys, strconv.FormatInt(id, 10))
+	}
+	return c.Delete(keys...)
+}
+
+func queryPermissionsByUserId(userId int64) ([]string, error) {
+	names := []string{}
+	ret := mysql.DB.Table(tableName(&Permission{})).
+		Joins(fmt.Sprintf("JOIN %s rp ON rp.permission_id = %s.id", tableName(&RolePermission{}), tableName(&Permission{}))).
+		Joins(fmt.Sprintf("JOIN %s ur ON ur.role_id = rp.role_id", tableName(&UserRole{}))).
+		Where("ur.user_id = ?", userId).
+		Group(tableName(&Permission{})+".name").
+		Pluck(tableName(&Permission{})+".name", &names)
+	if ret.Error != nil {
+		return nil, ret.Error
+	}
+	return names, nil
+}
+
+// tableName return the table of model with the configured prefix
+func tableName(model interface{}) string {
+	return mysql.DB.NewScope(model).GetModelStruct().TableName(mysql.DB.DB)
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"template_project/db/mysql"
+	"template_project/db/mysql/mysqltest"
+	"template_project/db/redis"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// useRBAC set up the rbac tables and a permission cache on a MemoryService for the test
+func useRBAC(t *testing.T) {
+	t.Helper()
+	mysqltest.Use(t, &User{}, &Role{}, &Permission{}, &RolePermission{}, &UserRole{})
+
+	saved := redis.DB
+	redis.DB = redis.NewMemoryService()
+	resetCaches := func() {
+		permissionCacheOnce, permissionCache = sync.Once{}, nil
+		userCacheOnce, userCache = sync.Once{}, nil
+	}
+	resetCaches()
+	t.Cleanup(func() {
+		redis.DB = saved
+		resetCaches()
+	})
+}
+
+// seedRole create a role holding permissions and return its id
+func seedRole(t *testing.T, name string, permissions ...string) int64 {
+	t.Helper()
+	role := Role{Name: name}
+	if err := mysql.DB.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range permissions {
+		permission := Permission{}
+		if err := mysql.DB.Where(Permission{Name: p}).FirstOrCreate(&permission).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := mysql.DB.Create(&RolePermission{RoleId: role.Id, PermissionId: permission.Id}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return role.Id
+}
+
+func permissionsOf(t *testing.T, userId int64) []string {
+	t.Helper()
+	names, err := QueryPermissionsByUserId(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestGrantRevoke(t *testing.T) {
+	useRBAC(t)
+	admin := seedRole(t, "admin", "*")
+	reader := seedRole(t, "reader", "users:read", "roles:read")
+	ur := &UserRole{}
+
+	if got := permissionsOf(t, 1); len(got) != 0 {
+		t.Fatalf("permissions before any grant = %v", got)
+	}
+	for i, want := range []bool{true, false} {
+		granted, err := ur.Grant(1, reader)
+		if err != nil || granted != want {
+			t.Fatalf("grant %d = %v, %v, want %v", i, granted, err, want)
+		}
+	}
+	if got := permissionsOf(t, 1); !reflect.DeepEqual(got, []string{"roles:read", "users:read"}) {
+		t.Errorf("permissions after the grant = %v, the cache was not invalidated", got)
+	}
+	if granted, err := ur.Grant(1, admin); err != nil || !granted {
+		t.Fatalf("grant admin = %v, %v", granted, err)
+	}
+	if got := permissionsOf(t, 1); !reflect.DeepEqual(got, []string{"*", "roles:read", "users:read"}) {
+		t.Errorf("permissions with both roles = %v", got)
+	}
+
+	for i, want := range []bool{true, false} {
+		revoked, err := ur.Revoke(1, admin)
+		if err != nil || revoked != want {
+			t.Fatalf("revoke %d = %v, %v, want %v", i, revoked, err, want)
+		}
+	}
+	if got := permissionsOf(t, 1); !reflect.DeepEqual(got, []string{"roles:read", "users:read"}) {
+		t.Errorf("permissions after the revoke = %v, the cache was not invalidated", got)
+	}
+	// other users are not touched
+	if got := permissionsOf(t, 2); len(got) != 0 {
+		t.Errorf("permissions of user 2 = %v", got)
+	}
+}
@@ -1,18 +1,96 @@
 package model
 
-import "template_project/db/mysql"
+import (
+	"template_project/cache"
+	"template_project/db/mysql"
+	"template_project/db/redis"
+	"template_project/logger"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	userCacheTTL = 10 * time.Minute
+	// mysqlDuplicateEntry is the error number of a unique index violation
+	mysqlDuplicateEntry = 1062
+)
+
+// ErrDuplicateName is returned when another user has the name
+var ErrDuplicateName = errors.New("user name already exists")
+
+// UserSortColumns are the columns QueryUsers can sort by
+var UserSortColumns = []string{"id", "name", "age", "created_at"}
+
+var (
+	userCacheOnce sync.Once
+	userCache     *cache.Cache
+)
 
 type User struct {
 	Id int			`json:"id"`
 	Name string		`json:"name"`
 	Age int			`json:"age"`	
+	CreatedAt time.Time	`json:"created_at"`
+	UpdatedAt time.Time	`json:"updated_at"`
 }
 
 func (*User) TableName() string {
 	return "user"
 }
 
+// usersCache is built on first use since redis is initialized after the package,
+// it is nil when redis is disabled
+func usersCache() *cache.Cache {
+	userCacheOnce.Do(func() {
+		if redis.DB != nil {
+			userCache = cache.New(redis.DB, cache.Options{
+				Prefix:              "user:",
+				NegativeTTL:         30 * time.Second,
+				LocalSize:           1024,
+				InvalidationChannel: "cache:user",
+			})
+			// evict the local entries of the users updated on other pods
+			if err := userCache.Listen(context.Background()); err != nil {
+				logger.Named(logger.ModuleRedis).Warn("listen user cache invalidations: %v", err)
+			}
+		}
+	})
+	return userCache
+}
+
 func (this *User) QueryUserById (id int) ( *User, error) {
+	c := usersCache()
+	if c == nil {
+		return queryUserById(id)
+	}
+
+	user := User{}
+	err := c.GetOrLoad(strconv.Itoa(id), userCacheTTL, &user, func() (interface{}, error) {
+		u, err := queryUserById(id)
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, cache.ErrNotFound
+		}
+		return u, err
+	})
+	if err == cache.ErrNotFound {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func queryUserById(id int) (*User, error) {
 	user := User{}
 	ret := mysql.DB.Model(&User{}).Where(" id = ? ", id ).Find( &user )
 	if ret.Error != nil {
@@ -22,7 +100,194 @@ func (this *User) QueryUserById (id int) ( *User, error) {
 	return &user, nil
 }
 
+// -----------------user write operation------------------
+func (this *User) Create() error {
+	if err := mysql.DB.Create(this).Error; err != nil {
+		return userError(err)
+	}
+	// drop a remembered miss of the new id
+	invalidateUser(this.Id)
+	return nil
+}
+
+// Update set the columns of fields ("name", "age") of the user id and return the updated user,
+// gorm.ErrRecordNotFound is returned when there is no such user
+func (this *User) Update(id int, fields map[string]interface{}) (*User, error) {
+	if len(fields) > 0 {
+		if err := mysql.DB.Model(&User{Id: id}).Updates(fields).Error; err != nil {
+			return nil, userError(err)
+		}
+		invalidateUser(id)
+	}
+	return queryUserById(id)
+}
+
+// Delete remove the user id with its roles, it reports false when there is no such user.
+// A user created later with the same id does not inherit the roles.
+func (this *User) Delete(id int) (bool, error) {
+	tx := mysql.DB.Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	if err := tx.Where("user_id = ?", id).Delete(&UserRole{}).Error; err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	ret := tx.Where("id = ?", id).Delete(&User{})
+	if ret.Error != nil {
+		tx.Rollback()
+		return false, ret.Error
+	}
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+	invalidateUser(id)
+	if err := InvalidatePermissions(int64(id)); err != nil {
+		return ret.RowsAffected > 0, fmt.Errorf("user deleted but the cached permissions are stale: %v", err)
+	}
+	return ret.RowsAffected > 0, nil
+}
+
+func invalidateUser(id int) {
+	if c := usersCache(); c != nil {
+		if err := c.Delete(strconv.Itoa(id)); err != nil {
+			logger.Named(logger.ModuleRedis).Warn("invalidate user %d: %v", id, err)
+		}
+	}
+}
+
+// userError turn the unique name violation into ErrDuplicateName
+func userError(err error) error {
+	if isDuplicateEntry(err) {
+		return ErrDuplicateName
+	}
+	return err
+}
+
+// isDuplicateEntry report whether err is a violation of a unique index or primary key
+func isDuplicateEntry(err error) bool {
+	e, ok := err.(*mysqldriver.MySQLError)
+	return ok && e.Number == mysqlDuplicateEntry
+}
+
+// -----------------user list operation------------------
+// UserQuery filter, sort and page QueryUsers
+type UserQuery struct {
+	Name   string // users whose name contains Name
+	MinAge *int
+	MaxAge *int
+	Sort   string // one of UserSortColumns, default id
+	Desc   bool
+	Limit  int
+	Offset int
+	// After continue a keyset pagination after this user, Offset is ignored when set
+	After *UserCursor
+}
+
+// UserCursor is the position of a user in a sorted list
+type UserCursor struct {
+	Value interface{} `json:"v"` // value of the sort column
+	Id    int         `json:"id"`
+}
 
+// CursorOf return the position of u in the order of q
+func (q UserQuery) CursorOf(u User) UserCursor {
+	c := UserCursor{Id: u.Id}
+	switch q.sortColumn() {
+	case "name":
+		c.Value = u.Name
+	case "age":
+		c.Value = u.Age
+	case "created_at":
+		c.Value = u.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return c
+}
 
+func (this *User) QueryUsers(q UserQuery) ([]User, error) {
+	column := q.sortColumn()
+	direction, op := "ASC", ">"
+	if q.Desc {
+		direction, op = "DESC", "<"
+	}
 
+	db := q.filter(mysql.DB.Model(&User{}))
+	if q.After != nil {
+		if column == "id" {
+			db = db.Where("id "+op+" ?", q.After.Id)
+		} else {
+			value, err := q.cursorValue()
+			if err != nil {
+				return nil, err
+			}
+			db = db.Where(fmt.Sprintf("%s %s ? OR (%s = ? AND id %s ?)", column, op, column, op), value, value, q.After.Id)
+		}
+	} else if q.Offset > 0 {
+		db = db.Offset(q.Offset)
+	}
+	if column != "id" {
+		db = db.Order(column + " " + direction)
+	}
+	db = db.Order("id " + direction)
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
 
+	users := []User{}
+	if err := db.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CountUsers return the number of users matching the filters of q
+func (this *User) CountUsers(q UserQuery) (int64, error) {
+	var n int64
+	err := q.filter(mysql.DB.Model(&User{})).Count(&n).Error
+	return n, err
+}
+
+func (q UserQuery) filter(db *gorm.DB) *gorm.DB {
+	if q.Name != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q.Name)
+		db = db.Where("name LIKE ?", "%"+escaped+"%")
+	}
+	if q.MinAge != nil {
+		db = db.Where("age >= ?", *q.MinAge)
+	}
+	if q.MaxAge != nil {
+		db = db.Where("age <= ?", *q.MaxAge)
+	}
+	return db
+}
+
+func (q UserQuery) sortColumn() string {
+	for _, column := range UserSortColumns {
+		if q.Sort == column {
+			return column
+		}
+	}
+	return "id"
+}
+
+// cursorValue convert the decoded json value of the cursor back to the type of the sort column
+func (q UserQuery) cursorValue() (interface{}, error) {
+	switch v := q.After.Value.(type) {
+	case string:
+		if q.sortColumn() == "created_at" {
+			return time.Parse(time.RFC3339Nano, v)
+		}
+		if q.sortColumn() == "name" {
+			return v, nil
+		}
+	case float64:
+		if q.sortColumn() == "age" {
+			return int(v), nil
+		}
+	case int:
+		if q.sortColumn() == "age" {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("cursor does not match sort %s", q.sortColumn())
+}
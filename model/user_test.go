@@ -0,0 +1,180 @@
+package model
+
+import (
+	"template_project/db/mysql"
+	"errors"
+	"reflect"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+// useUsers set up useRBAC with the unique index the migrations put on the user names
+func useUsers(t *testing.T) {
+	t.Helper()
+	useRBAC(t)
+	if err := mysql.DB.Model(&User{}).AddUniqueIndex("uix_user_name", "name").Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func seedUsers(t *testing.T, users ...User) {
+	t.Helper()
+	for i := range users {
+		if err := users[i].Create(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func ids(users []User) []int {
+	var list []int
+	for _, u := range users {
+		list = append(list, u.Id)
+	}
+	return list
+}
+
+func TestUserError(t *testing.T) {
+	duplicate := &mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry 'ann' for key 'uix_user_name'"}
+	if got := userError(duplicate); got != ErrDuplicateName {
+		t.Errorf("userError(%v) = %v, want ErrDuplicateName", duplicate, got)
+	}
+	// the other errors are returned as they are
+	for _, err := range []error{
+		&mysqldriver.MySQLError{Number: 1452, Message: "foreign key constraint fails"},
+		errors.New("connection refused"),
+		gorm.ErrRecordNotFound,
+	} {
+		if got := userError(err); got != err {
+			t.Errorf("userError(%v) = %v", err, got)
+		}
+	}
+}
+
+func TestUserDuplicateName(t *testing.T) {
+	useUsers(t)
+	seedUsers(t, User{Name: "ann"}, User{Name: "bob"})
+
+	if err := (&User{Name: "ann"}).Create(); err != ErrDuplicateName {
+		t.Errorf("create a second ann error = %v, want ErrDuplicateName", err)
+	}
+	if _, err := (&User{}).Update(2, map[string]interface{}{"name": "ann"}); err != ErrDuplicateName {
+		t.Errorf("rename bob to ann error = %v, want ErrDuplicateName", err)
+	}
+	// the user keeps its own name
+	if _, err := (&User{}).Update(1, map[string]interface{}{"name": "ann", "age": 30}); err != nil {
+		t.Errorf("update ann keeping the name: %v", err)
+	}
+}
+
+func TestUserDeleteRoles(t *testing.T) {
+	useUsers(t)
+	seedUsers(t, User{Name: "ann"}, User{Name: "bob"})
+	reader := seedRole(t, "reader", "users:read")
+	ur := &UserRole{}
+	for _, id := range []int64{1, 2} {
+		if _, err := ur.Grant(id, reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// cache the permissions the delete has to drop
+	if got := permissionsOf(t, 1); !reflect.DeepEqual(got, []string{"users:read"}) {
+		t.Fatalf("permissions of ann = %v", got)
+	}
+
+	deleted, err := (&User{}).Delete(1)
+	if err != nil || !deleted {
+		t.Fatalf("delete = %v, %v", deleted, err)
+	}
+	var left []UserRole
+	mysql.DB.Find(&left)
+	if len(left) != 1 || left[0].UserId != 2 {
+		t.Errorf("user roles after the delete = %+v, want only the one of bob", left)
+	}
+	if got := permissionsOf(t, 1); len(got) != 0 {
+		t.Errorf("cached permissions of the deleted user = %v", got)
+	}
+	if _, err := (&User{}).QueryUserById(1); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("query the deleted user error = %v", err)
+	}
+
+	if deleted, err := (&User{}).Delete(1); err != nil || deleted {
+		t.Errorf("delete again = %v, %v, want false", deleted, err)
+	}
+}
+
+func TestQueryUsers(t *testing.T) {
+	useUsers(t)
+	seedUsers(t,
+		User{Name: "dan", Age: 30},
+		User{Name: "ann", Age: 20},
+		User{Name: "cid", Age: 30},
+		User{Name: "bob", Age: 40},
+		User{Name: "eve", Age: 20},
+	)
+	minAge := 25
+
+	tests := []struct {
+		name string
+		q    UserQuery
+		want []int
+	}{
+		{"by id", UserQuery{}, []int{1, 2, 3, 4, 5}},
+		{"by name", UserQuery{Sort: "name"}, []int{2, 4, 3, 1, 5}},
+		{"ties by id", UserQuery{Sort: "age"}, []int{2, 5, 1, 3, 4}},
+		{"descending", UserQuery{Sort: "age", Desc: true}, []int{4, 3, 1, 5, 2}},
+		{"offset", UserQuery{Sort: "name", Offset: 1, Limit: 2}, []int{4, 3}},
+		{"after id", UserQuery{Limit: 2, After: &UserCursor{Id: 2}}, []int{3, 4}},
+		{"after a tie", UserQuery{Sort: "age", After: &UserCursor{Value: float64(30), Id: 1}}, []int{3, 4}},
+		{"after descending", UserQuery{Sort: "name", Desc: true, After: &UserCursor{Value: "cid", Id: 3}}, []int{4, 2}},
+		{"filtered", UserQuery{Name: "e", MinAge: &minAge}, nil},
+		{"min age", UserQuery{MinAge: &minAge, Sort: "name"}, []int{4, 3, 1}},
+	}
+	for _, tt := range tests {
+		users, err := (&User{}).QueryUsers(tt.q)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := ids(users); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ids = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := (&User{}).QueryUsers(UserQuery{Sort: "age", After: &UserCursor{Value: "ann", Id: 1}}); err == nil {
+		t.Error("a cursor of another sort: want an error")
+	}
+	if n, err := (&User{}).CountUsers(UserQuery{MinAge: &minAge, Limit: 1}); err != nil || n != 3 {
+		t.Errorf("count = %d, %v, want 3", n, err)
+	}
+}
+
+// walk the keyset pages while users are added before the cursor, none is skipped or repeated
+func TestQueryUsersPages(t *testing.T) {
+	useUsers(t)
+	seedUsers(t, User{Name: "b"}, User{Name: "d"}, User{Name: "f"}, User{Name: "h"})
+
+	q := UserQuery{Sort: "name", Limit: 2}
+	var seen []string
+	for page := 0; page < 5; page++ {
+		users, err := (&User{}).QueryUsers(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range users {
+			seen = append(seen, u.Name)
+		}
+		if len(users) < q.Limit {
+			break
+		}
+		cursor := q.CursorOf(users[len(users)-1])
+		q.After = &cursor
+		// sorted before the cursor, it belongs to a page already read
+		seedUsers(t, User{Name: "a" + users[0].Name})
+	}
+	if want := []string{"b", "d", "f", "h"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("pages = %v, want %v", seen, want)
+	}
+}
@@ -3,6 +3,8 @@ package router
 import (
 	"template_project/config"
 	"template_project/handler"
+	"template_project/health"
+	"template_project/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,10 +16,31 @@ func InitRouters(e *gin.Engine, cfg config.Configuration) {
 	}
 	routerGroupAPI := e.Group(rootRouterPrefix)
 
-	v1 := routerGroupAPI.Group("/v1")
+	// probes of the orchestrator, outside the api prefix and its rate limits
+	e.GET("/healthz", gin.WrapH(health.LiveHandler()))
+	e.GET("/readyz", gin.WrapH(health.ReadyHandler(false)))
+
+	// OptionalAuth runs first so that the rate limit can key by the verified api key or user
+	v1 := routerGroupAPI.Group("/v1", middleware.OptionalAuth(), middleware.RateLimit("v1"))
 	{
 		v1.GET("/ping", handler.Ping)
 		v1.POST("/test_post", handler.TestPost)
-		v1.GET("/get_user", handler.GetUser)
+		v1.GET("/me", middleware.RequireAuth(), handler.Me)
+		v1.POST("/wallet/challenge", handler.WalletChallenge)
+		v1.POST("/wallet/login", handler.WalletLogin)
+		v1.GET("/roles", middleware.Require("roles:read"), handler.ListRoles)
+
+		users := v1.Group("/users")
+		users.GET("", middleware.Require("users:read"), handler.ListUsers)
+		users.POST("", middleware.Require("users:write"), handler.CreateUser)
+		users.GET("/:id", middleware.Require("users:read"), handler.GetUser)
+		users.PUT("/:id", middleware.Require("users:write"), handler.UpdateUser)
+		users.PATCH("/:id", middleware.Require("users:write"), handler.PatchUser)
+		users.DELETE("/:id", middleware.Require("users:write"), handler.DeleteUser)
+
+		admin := v1.Group("/admin")
+		admin.GET("/log-levels", middleware.Require("logs:read"), handler.ListLogLevels)
+		admin.PUT("/log-levels/:module", middleware.Require("logs:write"), handler.SetLogLevel)
+		admin.DELETE("/log-levels/:module", middleware.Require("logs:write"), handler.ResetLogLevel)
 	}
 }
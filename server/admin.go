@@ -0,0 +1,18 @@
+package server
+
+import (
+	"template_project/config"
+	"template_project/health"
+	"template_project/metrics"
+	"net/http"
+)
+
+// newAdminServer build the listener of the operational endpoints, apart from the api
+// so that they are not exposed with it
+func newAdminServer(serverConfig config.ServerConfig) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", health.LiveHandler())
+	mux.Handle("/readyz", health.ReadyHandler(true))
+	return newHTTPServer(serverConfig.AdminAddr, mux, serverConfig)
+}
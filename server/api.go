@@ -2,26 +2,24 @@ package server
 
 import (
 	"template_project/config"
-
-	"golang.org/x/sync/errgroup"
 )
 
-var eGroup errgroup.Group
-
 type API struct {
-	config     *config.Configuration
-	ErrorGroup *errgroup.Group
+	config *config.Configuration
 }
 
 func New(conf *config.Configuration) (*API, error) {
 	cfg := *conf
 	return &API{
-		config:     &cfg,
-		ErrorGroup: &eGroup,
+		config: &cfg,
 	}, nil
 }
 
+// Start run the api server until it is shut down
 func (api *API) Start() error {
-	server := GetServer(api)
+	server, err := GetServer(api)
+	if err != nil {
+		return err
+	}
 	return server.Run()
 }
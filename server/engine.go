@@ -1,12 +1,10 @@
 package server
 
 import (
+	"template_project/middleware"
 	"template_project/router"
 	"net/http"
-	"time"
 
-	limit "github.com/aviddiviner/gin-limit"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 )
@@ -25,40 +23,28 @@ func (config *EngineConfig) initEngineConfig(api *API) *gin.Engine {
 	conf := api.config
 	gin.SetMode(conf.Server.RunMode)
 	e := gin.New()
+	// the client ip comes from middleware.ClientIP, which only reads the forwarded headers of trusted proxies
+	e.ForwardedByClientIP = false
+	if err := middleware.SetTrustedProxies(conf.Server.TrustedProxies); err != nil {
+		panic(err)
+	}
 	e.Use(gzip.Gzip(gzip.DefaultCompression))
 
-	e.Use(gin.Logger())
+	// request id first, so that the metrics, the access log and the handlers know the route and log with it
+	e.Use(middleware.RequestID(e), middleware.Metrics, middleware.AccessLog)
 	// use recovery middleware
 	e.Use(gin.Recovery())
 
-	e.Use(cors.New(cors.Config{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{"GET", "POST", "OPTION"},
-		AllowHeaders: []string{"Origin"},
-		ExposeHeaders: []string{
-			"Content-Length",
-			"Accept-Language",
-			"DNT",
-			"X-Mx-ReqToken",
-			"Keep-Alive",
-			"User-Agent",
-			"X-Requested-With",
-			"If-Modified-Since",
-			"Cache-Control",
-			"Content-Type",
-			"Authorization"},
-		AllowCredentials: true,
-		AllowOriginFunc: func(origin string) bool {
-			return origin == "https://github.com"
-		},
-		MaxAge: 12 * time.Hour,
-	}))
+	corsHandler := newReloadableCORS(conf.Server.AllowOrigins)
+	e.Use(corsHandler.Handler)
 
 	// By default, http.ListenAndServe (which gin.Run wraps) will serve an unbounded number of requests.
 	// Limiting the number of simultaneous connections can sometimes greatly speed things up under load
-	if config.LimitConnections > 0 {
-		e.Use(limit.MaxAllowed(config.LimitConnections))
-	}
+	limiter := newConnLimiter(config.LimitConnections)
+	e.Use(limiter.Handler)
+
+	watchConfig(corsHandler, limiter)
+
 	e.NoRoute(func(ctx *gin.Context) {
 		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
 			"code": 404,
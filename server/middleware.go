@@ -0,0 +1,132 @@
+package server
+
+import (
+	"template_project/config"
+	"template_project/logger"
+	"template_project/middleware"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// connLimiter bound the number of requests served at once, the bound can be changed at runtime.
+// Requests that started under the old bound release their slot to the old semaphore.
+type connLimiter struct {
+	mu  sync.RWMutex
+	sem chan struct{}
+}
+
+func newConnLimiter(max int) *connLimiter {
+	l := &connLimiter{}
+	l.SetMax(max)
+	return l
+}
+
+// SetMax change the bound, 0 means unlimited
+func (l *connLimiter) SetMax(max int) {
+	var sem chan struct{}
+	if max > 0 {
+		sem = make(chan struct{}, max)
+	}
+	l.mu.Lock()
+	l.sem = sem
+	l.mu.Unlock()
+}
+
+func (l *connLimiter) Handler(c *gin.Context) {
+	l.mu.RLock()
+	sem := l.sem
+	l.mu.RUnlock()
+
+	if sem == nil {
+		c.Next()
+		return
+	}
+	sem <- struct{}{}
+	defer func() { <-sem }()
+	c.Next()
+}
+
+// reloadableCORS serve CORS with the allowed origins of the current config
+type reloadableCORS struct {
+	mu      sync.RWMutex
+	handler gin.HandlerFunc
+}
+
+func newReloadableCORS(origins []string) *reloadableCORS {
+	c := &reloadableCORS{}
+	c.SetOrigins(origins)
+	return c
+}
+
+// SetOrigins rebuild the CORS handler for the given origins, empty means any origin
+func (rc *reloadableCORS) SetOrigins(origins []string) {
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	handler := cors.New(cors.Config{
+		AllowOrigins: origins,
+		AllowMethods: []string{"GET", "POST", "OPTION"},
+		AllowHeaders: []string{"Origin", "Authorization", "X-API-Key", "X-Request-ID"},
+		ExposeHeaders: []string{
+			"Content-Length",
+			"Accept-Language",
+			"DNT",
+			"X-Mx-ReqToken",
+			"Keep-Alive",
+			"User-Agent",
+			"X-Requested-With",
+			"If-Modified-Since",
+			"Cache-Control",
+			"Content-Type",
+			"Authorization",
+			"X-Request-ID"},
+		AllowCredentials: true,
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "https://github.com"
+		},
+		MaxAge: 12 * time.Hour,
+	})
+
+	rc.mu.Lock()
+	rc.handler = handler
+	rc.mu.Unlock()
+}
+
+func (rc *reloadableCORS) Handler(c *gin.Context) {
+	rc.mu.RLock()
+	handler := rc.handler
+	rc.mu.RUnlock()
+	handler(c)
+}
+
+// watchConfig apply reloaded CORS origins and connection limits
+func watchConfig(corsHandler *reloadableCORS, limiter *connLimiter) {
+	config.OnChange(func(old, new config.Configuration) {
+		if !stringsEqual(old.Server.AllowOrigins, new.Server.AllowOrigins) {
+			corsHandler.SetOrigins(new.Server.AllowOrigins)
+		}
+		if old.Server.LimitConnection != new.Server.LimitConnection {
+			limiter.SetMax(new.Server.LimitConnection)
+		}
+		if !stringsEqual(old.Server.TrustedProxies, new.Server.TrustedProxies) {
+			if err := middleware.SetTrustedProxies(new.Server.TrustedProxies); err != nil {
+				logger.Log.Error("reload trusted proxies err: %v", err)
+			}
+		}
+	})
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
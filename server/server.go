@@ -1,19 +1,35 @@
 package server
 
 import (
+	"template_project/config"
+	"template_project/db/mysql"
+	"template_project/db/redis"
+	"template_project/health"
+	"template_project/logger"
+	"context"
 	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
+// defaultShutdownTimeout is used when server.shutdown_timeout is not configured
+const defaultShutdownTimeout = 15 * time.Second
+
 type Server struct {
-	Server *http.Server
-	G      *errgroup.Group
+	Handler http.Handler
 	*API
+
+	HTTP  *http.Server
+	HTTPS *http.Server
+	Admin *http.Server // serves /metrics and the probes, nil without server.admin_addr
 }
 
-func GetServer(api *API) (server *Server) {
+func GetServer(api *API) (*Server, error) {
 	serverConfig := api.config.Server
 	runMode := api.config.Server.RunMode
 
@@ -23,43 +39,160 @@ func GetServer(api *API) (server *Server) {
 		RunMode:          runMode,
 	}
 
-	return &Server{
-		G:   api.ErrorGroup,
-		API: api,
-		Server: &http.Server{
-			Handler:        currentEngineConfig.Init(api),
-			ReadTimeout:    serverConfig.ReadTimeout,
-			WriteTimeout:   serverConfig.WriteTimeout,
-			IdleTimeout:    serverConfig.IdleTimeout,
-			MaxHeaderBytes: serverConfig.MaxHeaderBytes,
-		},
+	server := &Server{
+		API:     api,
+		Handler: currentEngineConfig.Init(api),
+	}
+	server.HTTP = newHTTPServer(serverConfig.ListenAddr, server.Handler, serverConfig)
+	if serverConfig.AdminAddr != "" {
+		server.Admin = newAdminServer(serverConfig)
+	}
+
+	if serverConfig.EnableHTTPS {
+		if api.config.TLS.CertFile == "" || api.config.TLS.KeyFile == "" {
+			return nil, errors.New("use https should config the cert and key files")
+		}
+		tlsConfig, err := NewTLSConfig(api.config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		server.HTTPS = newHTTPServer(serverConfig.HTTPSAddr, server.Handler, serverConfig)
+		server.HTTPS.TLSConfig = tlsConfig
+	}
+	return server, nil
+}
+
+// newHTTPServer build a listener with the timeouts and header limits of the server config
+func newHTTPServer(addr string, handler http.Handler, serverConfig config.ServerConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       serverConfig.ReadTimeout.Duration,
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout.Duration,
+		WriteTimeout:      serverConfig.WriteTimeout.Duration,
+		IdleTimeout:       serverConfig.IdleTimeout.Duration,
+		MaxHeaderBytes:    serverConfig.MaxHeaderBytes,
 	}
 }
 
+// Run start the listeners and block until one of them fails or the process
+// receives SIGINT/SIGTERM, then drain the listeners and close the stores
 func (server *Server) Run() error {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	g, ctx := errgroup.WithContext(ctx)
 
-	server.runServer()
-
-	if server.config.Server.EnableHTTPS {
-		if server.config.TLS.CertFile == "" || server.config.TLS.KeyFile == "" {
-			return errors.New("use https should config the cert and key files")
-		}
-		server.runServerTLS()
+	server.runServer(g)
+	if server.HTTPS != nil {
+		server.runServerTLS(g)
 	}
-	if err := server.G.Wait(); err != nil {
-		return err
+	if server.Admin != nil {
+		server.runAdmin(g)
 	}
-	return nil
+
+	g.Go(func() error {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(quit)
+
+		// only a signal waits out the drain delay, a failed listener exits right away
+		drain := false
+		select {
+		case sig := <-quit:
+			logger.Log.Info("receive signal %s, shutting down", sig)
+			drain = true
+		case <-ctx.Done():
+		}
+		stop()
+		return server.shutdown(drain)
+	})
+
+	g.Go(func() error {
+		return config.Watch(ctx, reportReload)
+	})
+
+	err := g.Wait()
+	server.closeStores()
+	return err
 }
 
-func (server *Server) runServer() {
-	server.G.Go(func() error {
-		return http.ListenAndServe(server.config.Server.ListenAddr, server.Server.Handler)
+func (server *Server) runServer(g *errgroup.Group) {
+	g.Go(func() error {
+		return ignoreServerClosed(server.HTTP.ListenAndServe())
 	})
 }
 
-func (server *Server) runServerTLS() {
-	server.G.Go(func() error {
-		return http.ListenAndServeTLS(server.config.Server.HTTPSAddr, server.config.TLS.CertFile, server.config.TLS.KeyFile, server.Server.Handler)
+func (server *Server) runServerTLS(g *errgroup.Group) {
+	g.Go(func() error {
+		return ignoreServerClosed(server.HTTPS.ListenAndServeTLS(server.config.TLS.CertFile, server.config.TLS.KeyFile))
 	})
 }
+
+func (server *Server) runAdmin(g *errgroup.Group) {
+	g.Go(func() error {
+		return ignoreServerClosed(server.Admin.ListenAndServe())
+	})
+}
+
+// shutdown fail the readiness, for server.drain_delay when drain is set so that the load balancer stops sending requests,
+// then stop accepting new connections and wait for in-flight requests until the configured drain deadline expires
+func (server *Server) shutdown(drain bool) error {
+	health.SetDraining()
+	if delay := server.config.Server.DrainDelay.Duration; drain && delay > 0 {
+		logger.Log.Info("readiness failing, closing the listeners in %s", delay)
+		time.Sleep(delay)
+	}
+
+	timeout := server.config.Server.ShutdownTimeout.Duration
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var err error
+	for _, s := range []*http.Server{server.HTTP, server.HTTPS, server.Admin} {
+		if s == nil {
+			continue
+		}
+		if e := s.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// closeStores release the mysql and redis pools once no request can use them
+func (server *Server) closeStores() {
+	if mysql.DB != nil {
+		if err := mysql.DB.Close(); err != nil {
+			logger.Log.Error("close mysql err: %v", err)
+		}
+	}
+	if redis.DB != nil {
+		if err := redis.DB.Close(); err != nil {
+			logger.Log.Error("close redis err: %v", err)
+		}
+	}
+}
+
+func reportReload(result config.ReloadResult, err error) {
+	if err != nil {
+		logger.Log.Error("reload config err: %v", err)
+		return
+	}
+	if len(result.Changed) == 0 {
+		return
+	}
+	logger.Log.Info("config reloaded, changed: %v", result.Changed)
+	if len(result.Restart) > 0 {
+		logger.Log.Warn("config keys %v only take effect after a restart", result.Restart)
+	}
+}
+
+func ignoreServerClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"template_project/config"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// NewTLSConfig build the tls settings of the https listener,
+// the certificate itself is loaded by ListenAndServeTLS
+func NewTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.MinVersion != "" {
+		v, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls min_version %q", cfg.MinVersion)
+		}
+		tlsConfig.MinVersion = v
+	}
+
+	if len(cfg.CipherSuites) > 0 {
+		suites := make(map[string]uint16)
+		for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites[s.Name] = s.ID
+		}
+		for _, name := range cfg.CipherSuites {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("unsupported tls cipher suite %q", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	clientAuth, ok := clientAuthTypes[cfg.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("unsupported tls client_auth %q", cfg.ClientAuth)
+	}
+	tlsConfig.ClientAuth = clientAuth
+
+	if cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls client_ca_file err: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in tls client_ca_file %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	} else if clientAuth >= tls.VerifyClientCertIfGiven {
+		return nil, fmt.Errorf("tls client_auth %q should config the client_ca_file", cfg.ClientAuth)
+	}
+
+	return tlsConfig, nil
+}
@@ -1 +1,58 @@
 package task
+
+import (
+	"template_project/metrics"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	runs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "task_runs_total",
+		Help: "Number of task runs by task and result, success or failure.",
+	}, []string{"task", "result"})
+	duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "task_duration_seconds",
+		Help: "Duration of the task runs.",
+	}, []string{"task"})
+	running = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "task_running",
+		Help: "Number of runs of the task in progress.",
+	}, []string{"task"})
+	lastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "task_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful run of the task.",
+	}, []string{"task"})
+)
+
+func init() {
+	metrics.MustRegister(runs, duration, running, lastSuccess)
+}
+
+// Run run the task name with fn, recording its result and duration in the task metrics
+func Run(name string, fn func() error) error {
+	start := time.Now()
+	inProgress := running.WithLabelValues(name)
+	inProgress.Inc()
+	defer inProgress.Dec()
+
+	err := fn()
+	duration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		runs.WithLabelValues(name, "failure").Inc()
+		return err
+	}
+	runs.WithLabelValues(name, "success").Inc()
+	lastSuccess.WithLabelValues(name).Set(float64(time.Now().UnixNano()) / 1e9)
+	return nil
+}
+
+// Go run the task name in a goroutine, failures are passed to onError when not nil
+func Go(name string, fn func() error, onError func(error)) {
+	go func() {
+		if err := Run(name, fn); err != nil && onError != nil {
+			onError(err)
+		}
+	}()
+}
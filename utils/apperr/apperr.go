@@ -0,0 +1,92 @@
+// Package apperr is the error model of the api: a business code from utils/constant,
+// the http status it maps to, a message safe to show to clients and the internal cause.
+package apperr
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Detail explain which field of a request is invalid
+type Detail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    int
+	Status  int
+	Message string   // shown to clients
+	Details []Detail // shown to clients, optional
+	Cause   error    // logged, never shown to clients
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%d %s: %v", e.Code, e.Message, e.Cause)
+	}
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// Unwrap return the cause
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// New return an error of code with the status and message registered for it
+func New(code int) *Error {
+	entry, ok := lookup(code)
+	if !ok {
+		entry = registration{status: http.StatusInternalServerError, message: "service error"}
+	}
+	return &Error{Code: code, Status: entry.status, Message: entry.message}
+}
+
+// Wrap return an error of code caused by cause
+func Wrap(code int, cause error) *Error {
+	e := New(code)
+	e.Cause = cause
+	return e
+}
+
+// WithMessage return a copy of e with a public message
+func (e *Error) WithMessage(format string, args ...interface{}) *Error {
+	c := *e
+	c.Message = fmt.Sprintf(format, args...)
+	return &c
+}
+
+// WithCause return a copy of e caused by cause
+func (e *Error) WithCause(cause error) *Error {
+	c := *e
+	c.Cause = cause
+	return &c
+}
+
+// WithDetails return a copy of e with details appended
+func (e *Error) WithDetails(details ...Detail) *Error {
+	c := *e
+	c.Details = append(append([]Detail{}, e.Details...), details...)
+	return &c
+}
+
+// Is report whether err is an *Error of code
+func Is(err error, code int) bool {
+	e, ok := As(err)
+	return ok && e.Code == code
+}
+
+// As return err as an *Error, looking through the causes of the errors which have an Unwrap method
+func As(err error) (*Error, bool) {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e, true
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return nil, false
+		}
+		err = u.Unwrap()
+	}
+	return nil, false
+}
@@ -0,0 +1,97 @@
+package apperr
+
+import (
+	"template_project/utils/constant"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// wrapped is an error of another package keeping its cause
+type wrapped struct{ cause error }
+
+func (w wrapped) Error() string { return "wrapped: " + w.cause.Error() }
+func (w wrapped) Unwrap() error { return w.cause }
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		code, status int
+	}{
+		{constant.ParamsError, http.StatusBadRequest},
+		{constant.ChainUnSupported, http.StatusBadRequest},
+		{constant.GetRandomError, http.StatusInternalServerError},
+		{constant.SignatureError, http.StatusUnauthorized},
+		{constant.ServiceError, http.StatusInternalServerError},
+		{constant.TooManyRequests, http.StatusTooManyRequests},
+		{constant.Unauthorized, http.StatusUnauthorized},
+		{constant.Forbidden, http.StatusForbidden},
+		{constant.NotFound, http.StatusNotFound},
+		{constant.AlreadyExists, http.StatusConflict},
+		// an unregistered code is a service error
+		{4242, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if e := New(tt.code); e.Status != tt.status || e.Code != tt.code || e.Message == "" {
+			t.Errorf("New(%d) = %d %q, want status %d", tt.code, e.Status, e.Message, tt.status)
+		}
+	}
+
+	Register(4243, http.StatusTeapot, "teapot")
+	defer func() {
+		mu.Lock()
+		delete(registry, 4243)
+		mu.Unlock()
+	}()
+	if e := New(4243); e.Status != http.StatusTeapot || e.Message != "teapot" {
+		t.Errorf("registered code = %d %q", e.Status, e.Message)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a code twice should panic")
+		}
+	}()
+	Register(constant.NotFound, http.StatusGone, "gone")
+}
+
+func TestWith(t *testing.T) {
+	cause := errors.New("no rows")
+	e := ErrNotFound.WithMessage("user %d not found", 7).WithCause(cause).WithDetails(Detail{Field: "id"})
+	if e.Message != "user 7 not found" || e.Cause != cause || len(e.Details) != 1 {
+		t.Errorf("error = %+v", e)
+	}
+	// the shared errors are copied, never changed
+	if ErrNotFound.Message != "not found" || ErrNotFound.Cause != nil || ErrNotFound.Details != nil {
+		t.Errorf("ErrNotFound changed to %+v", ErrNotFound)
+	}
+	if got := ErrParams.WithMessage("%s", "100% sure").Message; got != "100% sure" {
+		t.Errorf("message = %q", got)
+	}
+	if got := e.Error(); got != fmt.Sprintf("%d user 7 not found: no rows", constant.NotFound) {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestAs(t *testing.T) {
+	e := ErrForbidden.WithMessage("no")
+	tests := []struct {
+		err  error
+		want *Error
+	}{
+		{e, e},
+		{wrapped{e}, e},
+		{fmt.Errorf("check: %w", wrapped{e}), e},
+		{errors.New("plain"), nil},
+		{wrapped{errors.New("plain")}, nil},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		got, ok := As(tt.err)
+		if got != tt.want || ok != (tt.want != nil) {
+			t.Errorf("As(%v) = %v, %v, want %v", tt.err, got, ok, tt.want)
+		}
+	}
+	if !Is(wrapped{e}, constant.Forbidden) || Is(wrapped{e}, constant.NotFound) || Is(nil, constant.Forbidden) {
+		t.Error("Is does not match the code of the wrapped error")
+	}
+}
@@ -0,0 +1,53 @@
+package apperr
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/go-playground/validator.v8"
+)
+
+// Binding turn an error of gin ShouldBind into ErrParams with a detail per invalid field
+func Binding(err error) *Error {
+	switch e := err.(type) {
+	case validator.ValidationErrors:
+		details := make([]Detail, 0, len(e))
+		for _, fe := range e {
+			details = append(details, Detail{Field: snakeCase(fe.Name), Message: validationMessage(fe)})
+		}
+		sort.Slice(details, func(i, j int) bool { return details[i].Field < details[j].Field })
+		return ErrParams.WithCause(err).WithDetails(details...)
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return ErrParams.WithMessage("invalid json body").WithCause(err)
+	}
+	return ErrParams.WithCause(err)
+}
+
+func validationMessage(fe *validator.FieldError) string {
+	switch fe.Tag {
+	case "required", "exists":
+		return "is required"
+	case "min":
+		return "should be at least " + fe.Param
+	case "max":
+		return "should be at most " + fe.Param
+	}
+	return "failed on " + fe.Tag
+}
+
+// snakeCase turn a field name like MinAge into the min_age of its json and form tags
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 && !unicode.IsUpper(rune(name[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gopkg.in/go-playground/validator.v8"
+)
+
+type listRequest struct {
+	Name   string `binding:"required"`
+	MinAge *int   `binding:"exists,min=0"`
+	Limit  int    `binding:"max=100"`
+	Email  string `binding:"omitempty,email"`
+}
+
+func TestBinding(t *testing.T) {
+	v := validator.New(&validator.Config{TagName: "binding"})
+	minAge := -1
+	err := v.Struct(&listRequest{MinAge: &minAge, Limit: 101, Email: "ann"})
+	if _, ok := err.(validator.ValidationErrors); !ok {
+		t.Fatalf("validate error = %T %v, want validator.ValidationErrors", err, err)
+	}
+
+	e := Binding(err)
+	if e.Code != ErrParams.Code || e.Status != 400 || !reflect.DeepEqual(e.Cause, err) {
+		t.Errorf("binding error = %d %d cause %v", e.Code, e.Status, e.Cause)
+	}
+	// sorted by field, named like the json and form tags
+	want := []Detail{
+		{Field: "email", Message: "failed on email"},
+		{Field: "limit", Message: "should be at most 100"},
+		{Field: "min_age", Message: "should be at least 0"},
+		{Field: "name", Message: "is required"},
+	}
+	if !reflect.DeepEqual(e.Details, want) {
+		t.Errorf("details = %+v, want %+v", e.Details, want)
+	}
+
+	err = v.Struct(&listRequest{Name: "ann"})
+	if d := Binding(err).Details; len(d) != 1 || d[0] != (Detail{Field: "min_age", Message: "is required"}) {
+		t.Errorf("details of a missing pointer = %+v", d)
+	}
+}
+
+func TestBindingJSON(t *testing.T) {
+	var v struct{ Age int }
+	syntax := json.Unmarshal([]byte(`{"age":`), &v)
+	typed := json.Unmarshal([]byte(`{"age":"ten"}`), &v)
+	for _, err := range []error{syntax, typed} {
+		e := Binding(err)
+		if e.Code != ErrParams.Code || e.Message != "invalid json body" || e.Cause != err || len(e.Details) != 0 {
+			t.Errorf("Binding(%v) = %+v", err, e)
+		}
+	}
+
+	other := errors.New("http: request body too large")
+	if e := Binding(other); e.Message != ErrParams.Message || e.Cause != other {
+		t.Errorf("Binding(%v) = %+v", other, e)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Name":      "name",
+		"MinAge":    "min_age",
+		"ChainID":   "chain_id",
+		"ID":        "id",
+		"CreatedAt": "created_at",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package apperr
+
+import (
+	"template_project/utils/constant"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+type registration struct {
+	status  int
+	message string
+}
+
+// every code of utils/constant is registered here with its http status and default message
+var (
+	registry = map[int]registration{
+		constant.Success:          {http.StatusOK, "ok"},
+		constant.ParamsError:      {http.StatusBadRequest, "invalid params"},
+		constant.ChainUnSupported: {http.StatusBadRequest, "chain not supported"},
+		constant.GetRandomError:   {http.StatusInternalServerError, "failed to generate a random value"},
+		constant.SignatureError:   {http.StatusUnauthorized, "invalid signature"},
+		constant.ServiceError:     {http.StatusInternalServerError, "service error"},
+		constant.TooManyRequests:  {http.StatusTooManyRequests, "too many requests"},
+		constant.Unauthorized:     {http.StatusUnauthorized, "unauthorized"},
+		constant.Forbidden:        {http.StatusForbidden, "forbidden"},
+		constant.NotFound:         {http.StatusNotFound, "not found"},
+		constant.AlreadyExists:    {http.StatusConflict, "already exists"},
+	}
+	mu sync.RWMutex
+)
+
+var (
+	ErrParams           = New(constant.ParamsError)
+	ErrChainUnSupported = New(constant.ChainUnSupported)
+	ErrGetRandom        = New(constant.GetRandomError)
+	ErrSignature        = New(constant.SignatureError)
+	ErrService          = New(constant.ServiceError)
+	ErrTooManyRequests  = New(constant.TooManyRequests)
+	ErrUnauthorized     = New(constant.Unauthorized)
+	ErrForbidden        = New(constant.Forbidden)
+	ErrNotFound         = New(constant.NotFound)
+	ErrAlreadyExists    = New(constant.AlreadyExists)
+)
+
+// Register map a new business code to its http status and default message, a code may only be registered once
+func Register(code, status int, message string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := registry[code]; ok {
+		panic(fmt.Sprintf("apperr: code %d registered twice", code))
+	}
+	registry[code] = registration{status: status, message: message}
+}
+
+func lookup(code int) (registration, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := registry[code]
+	return r, ok
+}
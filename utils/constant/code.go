@@ -7,4 +7,9 @@ const (
 	GetRandomError   = 1003
 	SignatureError   = 1004
 	ServiceError     = 1005
+	TooManyRequests  = 1006
+	Unauthorized     = 1007
+	Forbidden        = 1008
+	NotFound         = 1009
+	AlreadyExists    = 1010
 )
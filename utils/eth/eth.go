@@ -0,0 +1,83 @@
+// Package eth recover the signer of Ethereum personal_sign (EIP-191) and typed data (EIP-712)
+// signatures with the crypto and signer/core/apitypes packages of go-ethereum.
+package eth
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Address is a 20 bytes Ethereum account address
+type Address = common.Address
+
+var (
+	errInvalidAddress   = errors.New("eth: invalid address")
+	errInvalidSignature = errors.New("eth: invalid signature")
+)
+
+// HexToAddress parse a 0x prefixed hex address, mixed case addresses should pass the EIP-55 checksum
+func HexToAddress(s string) (Address, error) {
+	if len(s) != 42 || !has0xPrefix(s) || !common.IsHexAddress(s) {
+		return Address{}, errInvalidAddress
+	}
+	a := common.HexToAddress(s)
+	if digits := s[2:]; digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && a.Hex()[2:] != digits {
+		return a, errors.New("eth: bad address checksum")
+	}
+	return a, nil
+}
+
+func Keccak256(data ...[]byte) []byte {
+	return crypto.Keccak256(data...)
+}
+
+// TextHash is the EIP-191 hash personal_sign signs,
+// keccak256("\x19Ethereum Signed Message:\n" + len(msg) + msg)
+func TextHash(msg []byte) []byte {
+	return accounts.TextHash(msg)
+}
+
+// RecoverAddress return the address of the key that signed hash.
+// sig is r || s || v with v either 0/1 or 27/28 as returned by wallets. Signatures with
+// r or s out of range, or a high s (EIP-2), are rejected so that a signature has a single form.
+func RecoverAddress(hash, sig []byte) (Address, error) {
+	if len(hash) != 32 || len(sig) != crypto.SignatureLength {
+		return Address{}, errInvalidSignature
+	}
+	sig = append([]byte{}, sig...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	r, s, v := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), sig[crypto.RecoveryIDOffset]
+	if !crypto.ValidateSignatureValues(v, r, s, true) {
+		return Address{}, errInvalidSignature
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return Address{}, errInvalidSignature
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// RecoverPersonal return the signer of a personal_sign signature of msg
+func RecoverPersonal(msg, sig []byte) (Address, error) {
+	return RecoverAddress(TextHash(msg), sig)
+}
+
+// DecodeHex decode a hex string with or without 0x prefix
+func DecodeHex(s string) ([]byte, error) {
+	if has0xPrefix(s) {
+		s = s[2:]
+	}
+	return hex.DecodeString(s)
+}
+
+func has0xPrefix(s string) bool {
+	return len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
@@ -0,0 +1,194 @@
+package eth
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mailTypedData is the example of EIP-712, signed by the key keccak256("cow")
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": "1",
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+const (
+	mailHash      = "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"
+	mailSignature = "4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c"
+	cowAddress = "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := DecodeHex(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func mail(t *testing.T) *TypedData {
+	t.Helper()
+	var td TypedData
+	if err := json.Unmarshal([]byte(mailTypedData), &td); err != nil {
+		t.Fatal(err)
+	}
+	return &td
+}
+
+func TestTypedDataMail(t *testing.T) {
+	td := mail(t)
+	hash, err := TypedDataHash(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(hash); got != mailHash {
+		t.Fatalf("hash = %s, want %s", got, mailHash)
+	}
+
+	signer, err := RecoverTypedData(td, mustHex(t, mailSignature))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Hex() != cowAddress {
+		t.Errorf("signer = %s, want %s", signer.Hex(), cowAddress)
+	}
+
+	td.Message["contents"] = "Hello, Alice!"
+	if signer, err := RecoverTypedData(td, mustHex(t, mailSignature)); err == nil && signer.Hex() == cowAddress {
+		t.Error("a changed message still recovers the signer")
+	}
+}
+
+func TestRecoverPersonal(t *testing.T) {
+	tests := []struct {
+		msg, sig, signer string
+	}{
+		// web3.eth.accounts.sign("Some data", "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+		{"Some data",
+			"0xb91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c",
+			"0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"},
+	}
+	for _, tt := range tests {
+		signer, err := RecoverPersonal([]byte(tt.msg), mustHex(t, tt.sig))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.msg, err)
+		}
+		if signer.Hex() != tt.signer {
+			t.Errorf("%q: signer = %s, want %s", tt.msg, signer.Hex(), tt.signer)
+		}
+	}
+}
+
+func TestRecoverPersonalSigned(t *testing.T) {
+	key, err := crypto.HexToECDSA("c85ef7d79691fe79573b1a7064c19c1a9819ebdbd1faaab1a8ec92344438aaf4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("example.com wants you to sign in with your Ethereum account")
+	sig, err := crypto.Sign(TextHash(msg), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wallets answer v as 27/28, go-ethereum signs with 0/1: both are accepted
+	wallet := append([]byte{}, sig...)
+	wallet[64] += 27
+	for _, s := range [][]byte{sig, wallet} {
+		signer, err := RecoverPersonal(msg, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signer.Hex() != cowAddress {
+			t.Errorf("v %d: signer = %s, want %s", s[64], signer.Hex(), cowAddress)
+		}
+	}
+}
+
+func TestRecoverInvalid(t *testing.T) {
+	sig := mustHex(t, mailSignature)
+	hash := mustHex(t, mailHash)
+	n := crypto.S256().Params().N
+
+	with := func(fn func(sig []byte)) []byte {
+		s := append([]byte{}, sig...)
+		fn(s)
+		return s
+	}
+	setInt := func(b []byte, v *big.Int) {
+		v.FillBytes(b)
+	}
+
+	tests := []struct {
+		name string
+		sig  []byte
+	}{
+		{"short", sig[:64]},
+		{"long", append(append([]byte{}, sig...), 0)},
+		{"v 2", with(func(s []byte) { s[64] = 2 })},
+		{"v 29", with(func(s []byte) { s[64] = 29 })},
+		{"r zero", with(func(s []byte) { setInt(s[:32], big.NewInt(0)) })},
+		{"r is N", with(func(s []byte) { setInt(s[:32], n) })},
+		{"s zero", with(func(s []byte) { setInt(s[32:64], big.NewInt(0)) })},
+		{"s is N", with(func(s []byte) { setInt(s[32:64], n) })},
+		// N - s with the other v is the same signature in its malleable form
+		{"high s", with(func(s []byte) {
+			setInt(s[32:64], new(big.Int).Sub(n, new(big.Int).SetBytes(s[32:64])))
+			s[64] ^= 1
+		})},
+	}
+	for _, tt := range tests {
+		if signer, err := RecoverAddress(hash, tt.sig); err == nil {
+			t.Errorf("%s: recovered %s, want an error", tt.name, signer.Hex())
+		}
+	}
+	if _, err := RecoverAddress(hash[:31], sig); err == nil {
+		t.Error("short hash: want an error")
+	}
+}
+
+func TestHexToAddress(t *testing.T) {
+	for _, s := range []string{cowAddress, "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826", "0xCD2A3D9F938E13CD947EC05ABC7FE734DF8DD826"} {
+		a, err := HexToAddress(s)
+		if err != nil || a.Hex() != cowAddress {
+			t.Errorf("HexToAddress(%s) = %s, %v", s, a.Hex(), err)
+		}
+	}
+	for _, s := range []string{"0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD827x", "CD2a3d9F938E13CD947Ec05AbC7FE734Df8DD82600",
+		"0xcD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", "0x" + "zz" + cowAddress[4:]} {
+		if _, err := HexToAddress(s); err == nil {
+			t.Errorf("HexToAddress(%s) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// TypedData is the EIP-712 payload of eth_signTypedData_v4
+type TypedData = apitypes.TypedData
+
+// TypedField is a member of an EIP-712 struct type
+type TypedField = apitypes.Type
+
+// TypedDomain is the EIP712Domain of a TypedData
+type TypedDomain = apitypes.TypedDataDomain
+
+// TypedDataHash is the EIP-712 digest the wallet signs, keccak256(0x19 0x01 || domainSeparator || hashStruct(message))
+func TypedDataHash(td *TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(*td)
+	return hash, err
+}
+
+// RecoverTypedData return the signer of an eth_signTypedData_v4 signature of td
+func RecoverTypedData(td *TypedData, sig []byte) (Address, error) {
+	hash, err := TypedDataHash(td)
+	if err != nil {
+		return Address{}, err
+	}
+	return RecoverAddress(hash, sig)
+}
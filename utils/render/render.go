@@ -1,15 +1,19 @@
 package render
 
 import (
+	"template_project/logger"
+	"template_project/utils/apperr"
+	"template_project/utils/constant"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type RespJsonData struct {
-	Code int         `json:"code"`
-	Msg  string      `json:"message"`
-	Data interface{} `json:"data"`
+	Code    int             `json:"code"`
+	Msg     string          `json:"message"`
+	Data    interface{}     `json:"data"`
+	Details []apperr.Detail `json:"details,omitempty"`
 }
 
 func RespJson(c *gin.Context, code int, msg string, data interface{}) {
@@ -21,10 +25,32 @@ func RespJson(c *gin.Context, code int, msg string, data interface{}) {
 	c.JSON(http.StatusOK, result)
 }
 
+// RespJsonWithError answer with the http status registered for code
 func RespJsonWithError(c *gin.Context, code int, msg string) {
-	RespJson(c, code, msg, nil)
+	Error(c, apperr.New(code).WithMessage("%s", msg))
 }
 
+// RespJsonWithBindingError answer with the invalid fields of a gin binding error
 func RespJsonWithBindingError(c *gin.Context, code int, err error) {
-	RespJson(c, code, err.Error(), nil)
+	e := apperr.Binding(err)
+	e.Code = code
+	Error(c, e)
+}
+
+// Error abort the request with err. An *apperr.Error is rendered with its code, status, message and details,
+// any other error is logged and masked as a service error. The causes of 5xx errors are logged.
+func Error(c *gin.Context, err error) {
+	e, ok := apperr.As(err)
+	if !ok {
+		e = apperr.Wrap(constant.ServiceError, err)
+	}
+	if e.Status >= http.StatusInternalServerError {
+		logger.FromContext(c).Error("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	c.Abort()
+	c.JSON(e.Status, &RespJsonData{
+		Code:    e.Code,
+		Msg:     e.Message,
+		Details: e.Details,
+	})
 }
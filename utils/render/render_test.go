@@ -0,0 +1,72 @@
+package render
+
+import (
+	"template_project/utils/apperr"
+	"template_project/utils/constant"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func render(err error) (*httptest.ResponseRecorder, RespJsonData) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/users", nil)
+	Error(c, err)
+	var resp RespJsonData
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestErrorMasked(t *testing.T) {
+	w, resp := render(errors.New("dial tcp 10.0.0.3:3306: connection refused"))
+	if w.Code != http.StatusInternalServerError || resp.Code != constant.ServiceError || resp.Msg != "service error" {
+		t.Errorf("plain error rendered as %d %d %q, want a 500 service error", w.Code, resp.Code, resp.Msg)
+	}
+
+	// the cause of an apperr is logged, not shown either
+	w, resp = render(apperr.ErrService.WithCause(errors.New("secret")))
+	if w.Code != http.StatusInternalServerError || resp.Msg != "service error" {
+		t.Errorf("wrapped cause rendered as %d %q", w.Code, resp.Msg)
+	}
+}
+
+func TestError(t *testing.T) {
+	detail := apperr.Detail{Field: "name", Message: "is already taken"}
+	w, resp := render(apperr.ErrAlreadyExists.WithMessage("user name already exists").WithDetails(detail))
+	if w.Code != http.StatusConflict || resp.Code != constant.AlreadyExists || resp.Msg != "user name already exists" {
+		t.Errorf("rendered as %d %d %q", w.Code, resp.Code, resp.Msg)
+	}
+	if !reflect.DeepEqual(resp.Details, []apperr.Detail{detail}) {
+		t.Errorf("details = %+v", resp.Details)
+	}
+
+	w, resp = render(apperr.ErrUnauthorized.WithCause(errors.New("token expired")))
+	if w.Code != http.StatusUnauthorized || resp.Code != constant.Unauthorized || resp.Msg != "unauthorized" {
+		t.Errorf("rendered as %d %d %q", w.Code, resp.Code, resp.Msg)
+	}
+
+	w, _ = render(apperr.ErrForbidden)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("forbidden rendered as %d", w.Code)
+	}
+}
+
+func TestRespJsonWithError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	RespJsonWithError(c, constant.TooManyRequests, "slow down, 100%")
+	var resp RespJsonData
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusTooManyRequests || resp.Msg != "slow down, 100%" || !c.IsAborted() {
+		t.Errorf("rendered as %d %q aborted %v", w.Code, resp.Msg, c.IsAborted())
+	}
+}
@@ -1,5 +1,27 @@
 package utils
 
-func GenCharacter(len int) (string, error) {
-	return "", nil
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+const characters = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// GenCharacter return a random alphanumeric string of n characters from crypto/rand,
+// suitable for nonces and one-time tokens
+func GenCharacter(n int) (string, error) {
+	if n <= 0 {
+		return "", errors.New("utils: GenCharacter length should be positive")
+	}
+	max := big.NewInt(int64(len(characters)))
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = characters[idx.Int64()]
+	}
+	return string(b), nil
 }